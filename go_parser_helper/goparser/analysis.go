@@ -0,0 +1,398 @@
+package goparser
+
+import (
+	"bytes"
+	"go/ast"
+	"go/printer"
+	"strings"
+)
+
+// Severity levels attached to rule findings.
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Issue is a single rule violation reported by one of the post-walk detectors.
+type Issue struct {
+	RuleID   string `json:"rule_id" proto:"1"`
+	Severity string `json:"severity" proto:"2"`
+	Message  string `json:"message" proto:"3"`
+	Function string `json:"function,omitempty" proto:"4"`
+	Span     `proto:"5"`
+}
+
+// Span locates a node. Columns are 1-based and count Unicode code points,
+// not bytes, and ColEnd is one past the node's last character. Spans built
+// from a line alone leave the columns and LineEnd zero.
+type Span struct {
+	LineStart int `json:"line_start" proto:"1"`
+	ColStart  int `json:"col_start,omitempty" proto:"2"`
+	LineEnd   int `json:"line_end,omitempty" proto:"3"`
+	ColEnd    int `json:"col_end,omitempty" proto:"4"`
+}
+
+// analyze runs the rule detectors over the parsed file once the structural
+// walk has populated the result.
+func (v *GoVisitor) analyze(file *ast.File) {
+	v.funcs = newFuncIndex(file)
+	v.buildDispatch(file)
+	v.collectCalls(file)
+	v.resolveTypeReferences(file)
+	v.collectCommentMarkers(file)
+
+	v.detectFeatures(file)
+	v.detectEventInjection(file)
+	v.detectNamedErrorNotSet(file)
+	v.detectUnsafeUsage(file)
+	v.detectPermissiveSigners(file)
+	v.detectSignerGaps(file)
+	v.detectUncancellableLoops(file)
+	v.detectDuplicateLiterals(file)
+	v.detectDuplicateImports(file)
+	v.detectUnusedImports(file)
+	v.detectUnusedMessageFields(file)
+	v.detectUncheckedMapLookups(file)
+	v.detectTagConflicts(file)
+	v.detectTagIssues(file)
+	v.detectKeeperCoupling(file)
+	v.detectSensitiveLogging(file)
+	v.detectSecrets(file)
+	v.detectMissingInvariants(file)
+	v.detectUnusedFields(file)
+	v.detectInconsistentErrorReturns()
+	v.detectErrShadowing(file)
+	v.detectNilCollectionReturns(file)
+	v.detectKeyCollisionRisk(file)
+	v.detectStringStoreKeys(file)
+	v.detectShouldBeMethod(file)
+	v.detectConcurrentContextUse(file)
+	v.detectMutexGaps(file)
+	v.detectValidationOrdering(file)
+	v.detectGenesisValidation(file)
+	v.detectStubValidations(file)
+	v.detectShouldBeConst(file)
+	v.detectUnregisteredHandlers(file)
+	v.detectRedundantConditions(file)
+	v.detectMissingMigrations(file)
+	v.detectMissingAuthorization(file)
+	v.detectContextMisuse(file)
+	v.detectUnboundedLoops(file)
+	v.detectUncheckedIndexing(file)
+	v.detectMapAccessRisks(file)
+	v.detectRangeCopyMutations(file)
+	v.detectNondeterminism(file)
+	v.detectReentrancy(file)
+	v.detectOverflowRisks(file)
+	v.computePanicSurface(file)
+	v.markReachablePanics()
+
+	v.buildFindings()
+	v.computeRiskScore(file)
+}
+
+// issues returns every rule finding in the result regardless of the slice
+// that holds it.
+func (r *ParseResult) issues() []Issue {
+	var all []Issue
+	all = append(all, r.EventInjection...)
+	all = append(all, r.NamedErrorNotSet...)
+	all = append(all, r.UnsafeUsage...)
+	for _, f := range r.MessageValidation {
+		all = append(all, f.Issue)
+	}
+	all = append(all, r.UncancellableLoop...)
+	for _, f := range r.DuplicateLiterals {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.ImportFindings {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.UnusedMessageFields {
+		all = append(all, f.Issue)
+	}
+	all = append(all, r.UncheckedMapLookup...)
+	for _, f := range r.TagConflicts {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.TagIssues {
+		all = append(all, f.Issue)
+	}
+	all = append(all, r.KeeperCoupling...)
+	all = append(all, r.SensitiveLogging...)
+	for _, f := range r.SecretFindings {
+		all = append(all, f.Issue)
+	}
+	all = append(all, r.InvariantIssues...)
+	all = append(all, r.UnusedFields...)
+	all = append(all, r.InconsistentErrorReturns...)
+	for _, f := range r.ShadowFindings {
+		all = append(all, f.Issue)
+	}
+	all = append(all, r.NilCollectionReturn...)
+	all = append(all, r.KeyCollisionRisk...)
+	for _, f := range r.StoreKeyFindings {
+		all = append(all, f.Issue)
+	}
+	all = append(all, r.ShouldBeMethod...)
+	all = append(all, r.ConcurrentContextUse...)
+	for _, f := range r.ConcurrencyFindings {
+		all = append(all, f.Issue)
+	}
+	all = append(all, r.ValidationOrdering...)
+	all = append(all, r.GenesisValidationIssues...)
+	for _, f := range r.StubValidations {
+		all = append(all, f.Issue)
+	}
+	all = append(all, r.ShouldBeConst...)
+	all = append(all, r.UnregisteredHandlers...)
+	all = append(all, r.RedundantConditions...)
+	all = append(all, r.MigrationIssues...)
+	for _, f := range r.AuthorizationFindings {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.UnusedContextFindings {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.GasRisks {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.BoundsRisks {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.ReentrancyRisks {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.MapAccessRisks {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.RangeCopyFindings {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.DeterminismFindings {
+		all = append(all, f.Issue)
+	}
+	for _, f := range r.OverflowRisks {
+		all = append(all, f.Issue)
+	}
+	return all
+}
+
+func (v *GoVisitor) line(n ast.Node) int {
+	return v.fset.Position(n.Pos()).Line
+}
+
+// span locates n. A function declaration spans only its signature, so
+// a finding on it does not cover the whole body.
+func (v *GoVisitor) span(n ast.Node) Span {
+	end := n.End()
+	if fn, ok := n.(*ast.FuncDecl); ok {
+		end = fn.Type.End()
+	}
+	return Span{
+		LineStart: v.line(n),
+		ColStart:  v.column(n.Pos()),
+		LineEnd:   v.fset.Position(end).Line,
+		ColEnd:    v.column(end),
+	}
+}
+
+// nodeText renders a node back to Go source for use in finding messages.
+func (v *GoVisitor) nodeText(n ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, v.fset, n); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// importLocalNames maps each identifier an import is referenced by in the
+// file to its import path. Blank and dot imports are omitted.
+func importLocalNames(file *ast.File) map[string]string {
+	names := map[string]string{}
+	for _, imp := range file.Imports {
+		path := strings.Trim(imp.Path.Value, `"`)
+		if imp.Name != nil {
+			if imp.Name.Name != "_" && imp.Name.Name != "." {
+				names[imp.Name.Name] = path
+			}
+			continue
+		}
+		names[defaultImportName(path)] = path
+	}
+	return names
+}
+
+// defaultImportName guesses the package name of an unaliased import from its
+// path, skipping major-version suffixes like "/v2" and ".v3".
+func defaultImportName(path string) string {
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(name, "go-")
+}
+
+// funcDisplayName returns "Type.Method" for methods and the plain name for
+// functions.
+func funcDisplayName(fn *ast.FuncDecl) string {
+	if recv := receiverTypeName(fn); recv != "" {
+		return recv + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// receiverTypeName returns the receiver's base type name with any pointer and
+// type parameters stripped, or "" for plain functions.
+func receiverTypeName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	expr := fn.Recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// baseTypeName strips pointers and package qualifiers from a rendered type.
+func baseTypeName(typ string) string {
+	typ = strings.TrimLeft(typ, "*")
+	if i := strings.LastIndex(typ, "."); i >= 0 {
+		typ = typ[i+1:]
+	}
+	return typ
+}
+
+// isMessageTypeName reports whether a type name follows the Cosmos message
+// naming conventions (MsgSend, TransferMsg, sdk.Msg).
+func isMessageTypeName(typ string) bool {
+	name := baseTypeName(typ)
+	if name == "Msg" || strings.HasSuffix(name, "Msg") {
+		return true
+	}
+	return len(name) > 3 && strings.HasPrefix(name, "Msg") && ast.IsExported(name[3:])
+}
+
+// messageParams returns the names of fn's parameters that carry a message.
+func (v *GoVisitor) messageParams(fn *ast.FuncDecl) map[string]bool {
+	params := map[string]bool{}
+	if fn.Type.Params == nil {
+		return params
+	}
+	for _, field := range fn.Type.Params.List {
+		isMsg := isMessageTypeName(v.typeToString(field.Type))
+		for _, name := range field.Names {
+			if isMsg || name.Name == "msg" {
+				params[name.Name] = true
+			}
+		}
+	}
+	return params
+}
+
+// calleeName returns the called identifier of a call expression: the function
+// name for direct calls and the selector name for method or package calls.
+func calleeName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	case *ast.IndexExpr:
+		if id, ok := fun.X.(*ast.Ident); ok {
+			return id.Name
+		}
+	}
+	return ""
+}
+
+// funcIndex resolves call expressions to the functions and methods declared
+// in the file.
+type funcIndex struct {
+	funcs   map[string]*ast.FuncDecl
+	methods map[string][]*ast.FuncDecl
+	imports map[string]string
+}
+
+func newFuncIndex(file *ast.File) *funcIndex {
+	idx := &funcIndex{
+		funcs:   map[string]*ast.FuncDecl{},
+		methods: map[string][]*ast.FuncDecl{},
+		imports: importLocalNames(file),
+	}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		if fn.Recv == nil {
+			idx.funcs[fn.Name.Name] = fn
+		} else {
+			idx.methods[fn.Name.Name] = append(idx.methods[fn.Name.Name], fn)
+		}
+	}
+	return idx
+}
+
+// resolve returns the local declaration called by call from within caller.
+// Method calls on caller's receiver resolve to the same receiver type;
+// other method calls resolve only when a single local type declares the
+// method name.
+func (idx *funcIndex) resolve(call *ast.CallExpr, caller *ast.FuncDecl) *ast.FuncDecl {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return idx.funcs[fun.Name]
+	case *ast.SelectorExpr:
+		candidates := idx.methods[fun.Sel.Name]
+		id, isIdent := fun.X.(*ast.Ident)
+		if isIdent && idx.imports[id.Name] != "" {
+			return nil
+		}
+		if isIdent && caller != nil && caller.Recv != nil {
+			recv := caller.Recv.List[0]
+			if len(recv.Names) > 0 && recv.Names[0].Name == id.Name {
+				for _, m := range candidates {
+					if receiverTypeName(m) == receiverTypeName(caller) {
+						return m
+					}
+				}
+			}
+		}
+		if len(candidates) == 1 {
+			return candidates[0]
+		}
+	}
+	return nil
+}
+
+// inspectBody walks a function body without descending into nested function
+// literals, whose statements belong to a different scope.
+func inspectBody(body *ast.BlockStmt, f func(ast.Node) bool) {
+	if body == nil {
+		return
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		return f(n)
+	})
+}
@@ -0,0 +1,62 @@
+package goparser
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Version is reported as ParseResult.ToolVersion. The CLI sets it from its
+// own build version.
+var Version = "0.1.0-dev"
+
+// Parse analyzes src as the contents of filename under the default config.
+// If src is nil the file is read from disk. Like go/parser, a file that
+// does not parse still yields the declarations that did, along with the
+// parse error, which is also listed in the result's Errors.
+func Parse(filename string, src []byte) (*ParseResult, error) {
+	return ParseContext(context.Background(), filename, src, ParseOptions{}, DefaultConfig())
+}
+
+// ParseContext is Parse under opts and cfg, stopping when ctx is done or
+// opts.Timeout passes. The returned error is also set when the input was
+// over opts.MaxBytes or the parse timed out; a timeout during the walk
+// only sets the result's TimedOut.
+func ParseContext(ctx context.Context, filename string, src []byte, opts ParseOptions, cfg Config) (*ParseResult, error) {
+	if src == nil {
+		var err error
+		if src, err = os.ReadFile(filename); err != nil {
+			return nil, fmt.Errorf("failed to read file: %v", err)
+		}
+	}
+	var key [sha256.Size]byte
+	if opts.Cache != nil {
+		key = cacheKey(filename, src, opts, cfg)
+		if result, err, ok := opts.Cache.get(key); ok {
+			return result, err
+		}
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	session := NewSourceSession(ctx, filename, src, opts)
+	result := session.Run(ctx, cfg)
+	if opts.Cache != nil {
+		opts.Cache.add(key, result, session.parseErr)
+	}
+	return result, session.parseErr
+}
+
+// ParseReader analyzes the source read from r under the default config;
+// name is only used in positions.
+func ParseReader(name string, r io.Reader) (*ParseResult, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	return Parse(name, src)
+}
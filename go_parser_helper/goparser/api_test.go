@@ -0,0 +1,98 @@
+package goparser
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// parseFixture analyzes src as fixture.go under the default config.
+func parseFixture(t *testing.T, src string) *ParseResult {
+	t.Helper()
+	result, err := Parse("fixture.go", []byte(src))
+	if err != nil {
+		t.Fatalf("fixture does not parse: %v", err)
+	}
+	return result
+}
+
+// findingsFor returns result's findings for rule.
+func findingsFor(result *ParseResult, rule string) []Finding {
+	var found []Finding
+	for _, f := range result.Findings {
+		if f.RuleID == rule {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+// ruleCase is a fixture and the lines a rule should report in it; nil
+// lines is a negative case.
+type ruleCase struct {
+	name  string
+	src   string
+	lines []int
+}
+
+// checkRule runs each case as a subtest, comparing the lines rule reports
+// with the expected ones.
+func checkRule(t *testing.T, rule string, cases []ruleCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := lines(findingsFor(parseFixture(t, tc.src), rule))
+			if !reflect.DeepEqual(got, tc.lines) {
+				t.Errorf("%s: got findings on lines %v, want %v", rule, got, tc.lines)
+			}
+		})
+	}
+}
+
+// lines returns the lines findings start on.
+func lines(findings []Finding) []int {
+	var out []int
+	for _, f := range findings {
+		out = append(out, f.Line)
+	}
+	return out
+}
+
+func BenchmarkParse(b *testing.B) {
+	src, err := os.ReadFile(sampleContract)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse(sampleContract, src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseCached(b *testing.B) {
+	src, err := os.ReadFile(sampleContract)
+	if err != nil {
+		b.Fatal(err)
+	}
+	opts := ParseOptions{Cache: NewResultCache(16)}
+	cfg := DefaultConfig()
+	if _, err := ParseContext(context.Background(), sampleContract, src, opts, cfg); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseContext(context.Background(), sampleContract, src, opts, cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	if stats := opts.Cache.Stats(); stats.Misses != 1 {
+		b.Fatalf("got %d cache misses, want 1", stats.Misses)
+	}
+}
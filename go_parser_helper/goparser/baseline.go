@@ -0,0 +1,122 @@
+package goparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Baseline lists accepted findings that -baseline drops from the output.
+type Baseline struct {
+	Suppressions []Suppression `json:"suppressions"`
+}
+
+// Suppression matches findings of one rule in one file, by fingerprint or,
+// in hand-written entries without one, by line. An empty File matches
+// every file.
+type Suppression struct {
+	RuleID      string `json:"rule_id"`
+	File        string `json:"file,omitempty"`
+	Line        int    `json:"line,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// fingerprint hashes the rule, the enclosing function and the snippet with
+// whitespace collapsed, so moving or reindenting the code keeps it. Findings
+// without a snippet hash their message instead.
+func (f Finding) fingerprint() string {
+	text := f.Snippet
+	if text == "" {
+		text = f.Message
+	}
+	sum := sha256.Sum256([]byte(f.RuleID + "\x00" + f.Function + "\x00" + strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s Suppression) matches(file string, f Finding) bool {
+	if s.RuleID != f.RuleID || (s.File != "" && s.File != file) {
+		return false
+	}
+	if s.Fingerprint != "" {
+		return s.Fingerprint == f.Fingerprint
+	}
+	return s.Line == f.Line
+}
+
+// LoadBaseline reads a baseline file, checking that every entry names a
+// rule and a fingerprint or line.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %v", err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %v", path, err)
+	}
+	for i, s := range b.Suppressions {
+		if s.RuleID == "" || (s.Fingerprint == "" && s.Line == 0) {
+			return nil, fmt.Errorf("invalid baseline %s: entry %d needs a rule_id and a fingerprint or line", path, i)
+		}
+	}
+	return &b, nil
+}
+
+// NewBaseline suppresses every finding in files, keyed as the results are
+// reported.
+func NewBaseline(files map[string]*ParseResult) *Baseline {
+	b := &Baseline{Suppressions: []Suppression{}}
+	for file, result := range files {
+		for _, f := range result.Findings {
+			b.Suppressions = append(b.Suppressions, Suppression{RuleID: f.RuleID, File: file, Line: f.Line, Fingerprint: f.Fingerprint})
+		}
+	}
+	sort.Slice(b.Suppressions, func(i, j int) bool {
+		a, c := b.Suppressions[i], b.Suppressions[j]
+		if a.File != c.File {
+			return a.File < c.File
+		}
+		if a.Line != c.Line {
+			return a.Line < c.Line
+		}
+		return a.RuleID < c.RuleID
+	})
+	return b
+}
+
+// Write saves the baseline as indented JSON.
+func (b *Baseline) Write(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Apply removes the suppressed findings from each result's Findings and
+// counts them in Suppressed. The per-detector slices and RiskScore are
+// left as parsed.
+func (b *Baseline) Apply(files map[string]*ParseResult) {
+	for file, result := range files {
+		kept := []Finding{}
+		for _, f := range result.Findings {
+			suppressed := false
+			for _, s := range b.Suppressions {
+				if s.matches(file, f) {
+					suppressed = true
+					break
+				}
+			}
+			if suppressed {
+				result.Suppressed++
+			} else {
+				kept = append(kept, f)
+			}
+		}
+		result.Findings = kept
+	}
+}
@@ -0,0 +1,107 @@
+package goparser
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+)
+
+// ResultCache is an in-memory LRU cache of parse results keyed by a
+// SHA-256 of the file name, source, options and config, set as
+// ParseOptions.Cache to skip re-parsing identical content. One cache can
+// serve calls with different options and configs. It is safe for
+// concurrent use.
+//
+// Hits return the cached *ParseResult itself, shared with every other
+// caller: treat it as read-only. Timed-out results are never cached.
+type ResultCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is most recently used
+	entries  map[[sha256.Size]byte]*list.Element
+	hits     uint64
+	misses   uint64
+}
+
+type cacheEntry struct {
+	key    [sha256.Size]byte
+	result *ParseResult
+	err    error
+}
+
+// CacheStats reports a ResultCache's use since it was created.
+type CacheStats struct {
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+	Entries  int    `json:"entries"`
+	Capacity int    `json:"capacity"`
+}
+
+// NewResultCache returns a cache holding up to capacity results, evicting
+// the least recently used. A capacity below one holds nothing.
+func NewResultCache(capacity int) *ResultCache {
+	return &ResultCache{capacity: capacity, order: list.New(), entries: map[[sha256.Size]byte]*list.Element{}}
+}
+
+// cacheKey hashes the name with the source, since positions and errors
+// carry the name, and with every option and config setting that changes
+// the result. Timeout only decides whether a result is cached, and
+// Concurrency and Cache do not affect a single file.
+func cacheKey(filename string, src []byte, opts ParseOptions, cfg Config) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(filename))
+	h.Write([]byte{0})
+	// Config encodes its maps in sorted key order, and the encoding of
+	// these types cannot fail.
+	json.NewEncoder(h).Encode(struct {
+		Wrap           bool
+		DeclsOnly      bool
+		MaxBytes       int64
+		Config         Config
+		SecretPatterns []SecretPattern
+	}{opts.Wrap, opts.DeclsOnly, opts.MaxBytes, cfg, cfg.SecretPatterns})
+	h.Write(src)
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+func (c *ResultCache) get(key [sha256.Size]byte) (*ParseResult, error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	entry := elem.Value.(*cacheEntry)
+	return entry.result, entry.err, true
+}
+
+func (c *ResultCache) add(key [sha256.Size]byte, result *ParseResult, err error) {
+	if c.capacity < 1 || result.TimedOut {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: result, err: err})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Stats returns the hit and miss counts and current size.
+func (c *ResultCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Entries: c.order.Len(), Capacity: c.capacity}
+}
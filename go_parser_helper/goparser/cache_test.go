@@ -0,0 +1,59 @@
+package goparser
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestResultCacheKeysOnOptionsAndConfig(t *testing.T) {
+	src, err := os.ReadFile(sampleContract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := NewResultCache(16)
+	parse := func(opts ParseOptions, cfg Config) *ParseResult {
+		t.Helper()
+		opts.Cache = cache
+		result, err := ParseContext(context.Background(), sampleContract, src, opts, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	base := parse(ParseOptions{}, DefaultConfig())
+	if again := parse(ParseOptions{}, DefaultConfig()); again != base {
+		t.Error("identical options and config missed the cache")
+	}
+	if declsOnly := parse(ParseOptions{DeclsOnly: true}, DefaultConfig()); declsOnly == base || len(declsOnly.Findings) != 0 {
+		t.Error("DeclsOnly was served the full result")
+	}
+	disabled := DefaultConfig()
+	off := false
+	disabled.Rules = map[string]RuleSetting{base.Findings[0].RuleID: {Enabled: &off}}
+	if result := parse(ParseOptions{}, disabled); result == base || len(result.Findings) >= len(base.Findings) {
+		t.Error("a config disabling a rule was served the default result")
+	}
+	// Timeout and Concurrency do not change a finished result.
+	if result := parse(ParseOptions{Concurrency: 4, Timeout: 1 << 40}, DefaultConfig()); result != base {
+		t.Error("Timeout or Concurrency split the cache")
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 3 {
+		t.Errorf("got %d hits and %d misses, want 2 and 3", stats.Hits, stats.Misses)
+	}
+}
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewResultCache(2)
+	opts := ParseOptions{Cache: cache}
+	for _, name := range []string{"a.go", "b.go", "a.go", "c.go", "a.go", "b.go"} {
+		if _, err := ParseContext(context.Background(), name, []byte("package p\n"), opts, DefaultConfig()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// b.go was evicted by c.go; a.go stayed because it was used again.
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 4 || stats.Entries != 2 {
+		t.Errorf("got %+v, want 2 hits, 4 misses and 2 entries", stats)
+	}
+}
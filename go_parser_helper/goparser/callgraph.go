@@ -0,0 +1,91 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// CallEdge is one call from a function declared in the file to another
+// declared in the same file. Methods are named Type.Method.
+type CallEdge struct {
+	Caller    string `json:"caller" proto:"1"`
+	Callee    string `json:"callee" proto:"2"`
+	LineStart int    `json:"line_start" proto:"3"` // first call site
+}
+
+// collectCalls records each caller→callee pair once, at its first call
+// site. Calls made inside function literals belong to the enclosing
+// declaration; calls that resolve outside the file are dropped.
+func (v *GoVisitor) collectCalls(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		caller := funcDisplayName(fn)
+		seen := map[string]bool{}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			target := v.funcs.resolve(call, fn)
+			if target == nil {
+				return true
+			}
+			callee := funcDisplayName(target)
+			if !seen[callee] {
+				seen[callee] = true
+				v.result.Calls = append(v.result.Calls, CallEdge{Caller: caller, Callee: callee, LineStart: v.line(call)})
+			}
+			return true
+		})
+	}
+}
+
+// WriteDOT renders the call graphs of results as a Graphviz digraph, one
+// cluster per file when there is more than one. Every declared function is
+// a node, so functions that neither call nor are called still appear;
+// exported ones are drawn bold as entry points.
+func WriteDOT(w io.Writer, results map[string]*ParseResult) error {
+	files := make([]string, 0, len(results))
+	for file := range results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	if _, err := fmt.Fprintln(w, "digraph calls {\n\tnode [shape=box];"); err != nil {
+		return err
+	}
+	for i, file := range files {
+		res := results[file]
+		indent := "\t"
+		// Node IDs are prefixed per file so clusters do not share nodes.
+		id := func(name string) string { return strconv.Quote(name) }
+		if len(files) > 1 {
+			indent = "\t\t"
+			prefix := file + ":"
+			id = func(name string) string { return strconv.Quote(prefix + name) }
+			fmt.Fprintf(w, "\tsubgraph cluster_%d {\n\t\tlabel=%s;\n", i, strconv.Quote(file))
+		}
+		for _, fn := range res.Functions {
+			name := fn.displayName()
+			attrs := "label=" + strconv.Quote(name)
+			if fn.IsExported {
+				attrs += ", style=bold"
+			}
+			fmt.Fprintf(w, "%s%s [%s];\n", indent, id(name), attrs)
+		}
+		for _, edge := range res.Calls {
+			fmt.Fprintf(w, "%s%s -> %s;\n", indent, id(edge.Caller), id(edge.Callee))
+		}
+		if len(files) > 1 {
+			fmt.Fprintln(w, "\t}")
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
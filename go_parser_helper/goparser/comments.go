@@ -0,0 +1,156 @@
+package goparser
+
+import (
+	"go/ast"
+	"regexp"
+	"strings"
+)
+
+// CommentMarker is a developer note left in a comment: a TODO-style
+// keyword, or an admission such as "should validate ... but doesn't" just
+// above a stubbed-out return.
+type CommentMarker struct {
+	// Marker is the keyword found, or "should-but" for an admission.
+	Marker string `json:"marker" proto:"1"`
+	Text   string `json:"text" proto:"2"`
+	// Declaration is the function, type, variable or constant the comment
+	// is in or documents, "" between declarations.
+	Declaration string `json:"declaration" proto:"3"`
+	LineStart   int    `json:"line_start" proto:"4"`
+}
+
+// defaultCommentMarkers are the keywords reported when the config lists
+// none.
+var defaultCommentMarkers = []string{"TODO", "FIXME", "XXX", "HACK", "BUG"}
+
+var (
+	shouldWord = regexp.MustCompile(`(?i)\bshould\b`)
+	butWord    = regexp.MustCompile(`(?i)\bbut\b`)
+)
+
+// commentText strips the comment delimiters from c.
+func commentText(c *ast.Comment) string {
+	text := strings.TrimPrefix(c.Text, "//")
+	if strings.HasPrefix(text, "/*") {
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+	}
+	return strings.TrimSpace(text)
+}
+
+// isStubReturn reports whether ret returns nothing or only nil, literals
+// and empty composite literals.
+func isStubReturn(ret *ast.ReturnStmt) bool {
+	for _, result := range ret.Results {
+		switch r := result.(type) {
+		case *ast.BasicLit:
+		case *ast.Ident:
+			if r.Name != "nil" && r.Name != "true" && r.Name != "false" {
+				return false
+			}
+		case *ast.CompositeLit:
+			if len(r.Elts) > 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// declarationName names a top-level declaration: Type.Method for methods,
+// the first spec's name for a type, var or const group.
+func declarationName(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		return funcDisplayName(d)
+	case *ast.GenDecl:
+		if len(d.Specs) == 0 {
+			return ""
+		}
+		switch s := d.Specs[0].(type) {
+		case *ast.TypeSpec:
+			return s.Name.Name
+		case *ast.ValueSpec:
+			return s.Names[0].Name
+		}
+	}
+	return ""
+}
+
+// collectCommentMarkers records every comment line carrying one of the
+// configured keywords, matched case-sensitively as whole words, and every
+// comment group that says "should" and "but" right before a stub return.
+func (v *GoVisitor) collectCommentMarkers(file *ast.File) {
+	keywords := v.config.CommentMarkers
+	if len(keywords) == 0 {
+		keywords = defaultCommentMarkers
+	}
+	var quoted []string
+	for _, keyword := range keywords {
+		quoted = append(quoted, regexp.QuoteMeta(keyword))
+	}
+	keywordPattern := regexp.MustCompile(`\b(` + strings.Join(quoted, "|") + `)\b`)
+
+	// enclosing returns the declaration containing group, counting a doc
+	// comment as part of the declaration it documents.
+	enclosing := func(group *ast.CommentGroup) ast.Decl {
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Doc == group {
+					return decl
+				}
+			case *ast.GenDecl:
+				if d.Doc == group {
+					return decl
+				}
+			}
+			if group.Pos() >= decl.Pos() && group.End() <= decl.End() {
+				return decl
+			}
+		}
+		return nil
+	}
+
+	for _, group := range file.Comments {
+		decl := enclosing(group)
+		name := ""
+		if decl != nil {
+			name = declarationName(decl)
+		}
+		for _, c := range group.List {
+			text := commentText(c)
+			if match := keywordPattern.FindString(text); match != "" {
+				v.result.CommentMarkers = append(v.result.CommentMarkers, CommentMarker{
+					Marker: match, Text: text, Declaration: name, LineStart: v.line(c),
+				})
+			}
+		}
+
+		fn, ok := decl.(*ast.FuncDecl)
+		groupText := group.Text()
+		if !ok || fn.Body == nil || !shouldWord.MatchString(groupText) || !butWord.MatchString(groupText) {
+			continue
+		}
+		// next is the first statement after the comment.
+		var next ast.Stmt
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			stmt, ok := n.(ast.Stmt)
+			if ok && stmt.Pos() > group.End() && (next == nil || stmt.Pos() < next.Pos()) {
+				next = stmt
+			}
+			return true
+		})
+		ret, ok := next.(*ast.ReturnStmt)
+		if !ok || v.line(ret) > v.fset.Position(group.End()).Line+1 || !isStubReturn(ret) {
+			continue
+		}
+		v.result.CommentMarkers = append(v.result.CommentMarkers, CommentMarker{
+			Marker:      "should-but",
+			Text:        strings.Join(strings.Fields(groupText), " "),
+			Declaration: name,
+			LineStart:   v.line(group),
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package goparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Config tunes the analysis applied to a parsed file.
+type Config struct {
+	RiskWeights RiskWeights `json:"risk_weights"`
+	// Severities overrides the severity of findings by rule ID, as in
+	// {"QLK-PANIC": "high"}.
+	Severities map[string]string `json:"severities"`
+	// Rules turns rules off and reweights them by rule ID. A rule it does
+	// not list keeps its default severity and stays enabled.
+	Rules map[string]RuleSetting `json:"rules"`
+	// SecretPatterns extend the built-in secret patterns; they are loaded
+	// with -secret-patterns.
+	SecretPatterns []SecretPattern `json:"-"`
+	// NestingThreshold is the block-nesting depth above which a function is
+	// reported as QLK-DEEP-NESTING.
+	NestingThreshold int `json:"nesting_threshold"`
+	// ParamThreshold is the parameter count above which a function is
+	// reported as QLK-TOO-MANY-PARAMS.
+	ParamThreshold int `json:"param_threshold"`
+	// CommentMarkers are the keywords, such as TODO and FIXME, reported in
+	// CommentMarkers; empty means the built-in set.
+	CommentMarkers []string `json:"comment_markers"`
+	// Nondeterministic lists the calls reported in contract logic, as
+	// "time.Now" or a whole import path such as "math/rand", plus
+	// "map-iteration" for output built while ranging over a map; empty
+	// means the built-in set. -nondeterministic replaces it.
+	Nondeterministic []string `json:"nondeterministic"`
+	// ShadowNames are the variables QLK-ERR-SHADOW tracks; empty means
+	// just err.
+	ShadowNames []string `json:"shadow_names"`
+}
+
+// RuleSetting configures one rule, as {enabled: false} or
+// {severity: high} in a rules file.
+type RuleSetting struct {
+	// Enabled is nil when the setting leaves the rule on.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Severity replaces the rule's default severity, and takes precedence
+	// over Severities.
+	Severity string `json:"severity,omitempty"`
+}
+
+// ruleEnabled reports whether findings of rule are reported.
+func (c Config) ruleEnabled(rule string) bool {
+	setting, ok := c.Rules[rule]
+	return !ok || setting.Enabled == nil || *setting.Enabled
+}
+
+// ruleWarnings describes the Rules entries that name no known rule or an
+// unknown severity. They are reported in Errors rather than failing the
+// load, so a rules file shared across versions of the tool keeps working.
+func (c Config) ruleWarnings() []string {
+	var warnings []string
+	for rule, setting := range c.Rules {
+		if _, ok := ruleCategories[rule]; !ok {
+			warnings = append(warnings, fmt.Sprintf("Config: unknown rule %s in rules", rule))
+		}
+		if setting.Severity != "" && SeverityRank(setting.Severity) < 0 {
+			warnings = append(warnings, fmt.Sprintf("Config: rule %s has unknown severity %q; want one of %s", rule, setting.Severity, strings.Join(SeverityLevels, ", ")))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// RiskWeights are the points each signal adds to a file's RiskScore, which is
+// capped at 100.
+type RiskWeights struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Info     int `json:"info"`
+
+	// Complexity is added once per function whose cyclomatic complexity
+	// exceeds ComplexityThreshold.
+	Complexity          int `json:"complexity"`
+	ComplexityThreshold int `json:"complexity_threshold"`
+
+	Panic       int `json:"panic"`
+	MissingAuth int `json:"missing_auth"`
+	Unsafe      int `json:"unsafe"`
+	Reflection  int `json:"reflection"`
+}
+
+// DefaultConfig returns the weights used when no -config file is given.
+func DefaultConfig() Config {
+	return Config{
+		RiskWeights: RiskWeights{
+			Critical:            25,
+			High:                10,
+			Medium:              5,
+			Low:                 1,
+			Complexity:          3,
+			ComplexityThreshold: 10,
+			Panic:               5,
+			MissingAuth:         10,
+			Unsafe:              15,
+			Reflection:          5,
+		},
+		NestingThreshold: 4,
+		ParamThreshold:   6,
+	}
+}
+
+// LoadConfig reads a config file over the defaults, so a file only needs
+// to list the values it changes. Files ending in .yaml or .yml are read as
+// YAML with the same keys as the JSON form.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config: %v", err)
+	}
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		tree, err := parseYAML(data)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+		}
+		if tree == nil {
+			return cfg, nil
+		}
+		if data, err = json.Marshal(tree); err != nil {
+			return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+		}
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return cfg, nil
+}
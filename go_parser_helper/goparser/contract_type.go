@@ -0,0 +1,185 @@
+package goparser
+
+import (
+	"go/ast"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ContractTypeScore is the confidence, from 0 to 1, that a file targets a
+// framework.
+type ContractTypeScore struct {
+	Framework  string  `json:"framework" proto:"1"`
+	Confidence float64 `json:"confidence" proto:"2"`
+}
+
+// frameworkSignals are the marks a framework leaves on a file: import path
+// fragments, the package names it is conventionally imported as, type names
+// used in signatures and fields, and the names of the methods it requires
+// contracts to implement.
+type frameworkSignals struct {
+	name       string
+	imports    []string
+	qualifiers []string
+	types      []string
+	methods    []string
+}
+
+// Signal weights: an import is close to conclusive; a conventional package
+// qualifier without the import, as in a pasted snippet, is nearly so. Type
+// and method names only corroborate them.
+const (
+	importSignalWeight    = 0.6
+	qualifierSignalWeight = 0.4
+	typeSignalWeight      = 0.25
+	methodSignalWeight    = 0.15
+)
+
+var frameworks = []frameworkSignals{
+	{
+		name:       "cosmos_sdk",
+		imports:    []string{"github.com/cosmos/cosmos-sdk", "cosmossdk.io/"},
+		qualifiers: []string{"sdk", "sdkerrors", "storetypes", "authtypes", "banktypes"},
+		types:      []string{"Msg", "AccAddress", "Coins", "KVStore", "StoreKey", "KVStoreKey"},
+		methods:    []string{"ValidateBasic", "GetSigners", "RegisterServices", "InitGenesis", "ExportGenesis", "BeginBlock", "EndBlock"},
+	},
+	{
+		name:       "ethereum",
+		imports:    []string{"github.com/ethereum/go-ethereum"},
+		qualifiers: []string{"ethclient", "bind", "ethtypes"},
+		types:      []string{"TransactOpts", "CallOpts", "BoundContract", "Transaction", "ethclient.Client"},
+		methods:    []string{"Transact", "FilterLogs", "WatchLogs"},
+	},
+	{
+		name:       "cosmwasm",
+		imports:    []string{"github.com/CosmWasm/", "cosmwasm-std"},
+		qualifiers: []string{"wasmvm", "wasmvmtypes", "wasmtypes"},
+		types:      []string{"Env", "MessageInfo", "Deps", "DepsMut"},
+		// Go contract bindings export the entry points in lower case, as
+		// the Wasm host looks them up.
+		methods: []string{"Instantiate", "Execute", "Query", "Migrate", "Sudo", "instantiate", "execute", "query", "migrate", "sudo"},
+	},
+	{
+		name:       "solana",
+		imports:    []string{"github.com/gagliardetto/solana-go", "github.com/portto/solana-go-sdk"},
+		qualifiers: []string{"solana"},
+		types:      []string{"PublicKey", "Instruction", "AccountMeta"},
+		methods:    []string{"ProcessInstruction"},
+	},
+	{
+		name:       "substrate",
+		imports:    []string{"github.com/centrifuge/go-substrate-rpc-client", "github.com/vedhavyas/go-subkey"},
+		qualifiers: []string{"gsrpc", "subkey"},
+		types:      []string{"Extrinsic", "StorageKey", "Metadata"},
+		methods:    []string{"SubmitExtrinsic"},
+	},
+	{
+		name:       "near",
+		imports:    []string{"github.com/near/", "github.com/eteu-technologies/near-api-go"},
+		qualifiers: []string{"near"},
+		types:      []string{"AccountID", "AccountId"},
+		methods:    []string{"FunctionCall"},
+	},
+}
+
+// detectContractType scores every known framework from the parsed imports,
+// package qualifiers, type names and method names, and labels the file with
+// the best scoring one. Files matching none fall back to a keyword check of
+// the source.
+func (v *GoVisitor) detectContractType(file *ast.File) {
+	// Qualifiers only count when they are not a local import name, which
+	// the import signal already covers.
+	imported := importLocalNames(file)
+	qualifiers := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && imported[id.Name] == "" {
+				qualifiers[id.Name] = true
+			}
+		}
+		return true
+	})
+	types := map[string]bool{}
+	addType := func(typ string) {
+		typ = strings.TrimLeft(typ, "[]*.")
+		types[typ] = true
+		types[baseTypeName(typ)] = true
+	}
+	methods := map[string]bool{}
+	for _, fn := range v.result.Functions {
+		methods[fn.Name] = true
+		for _, p := range fn.Parameters {
+			addType(p.Type)
+		}
+		for _, r := range fn.ReturnTypes {
+			addType(r)
+		}
+	}
+	for _, st := range v.result.Structs {
+		types[st.Name] = true
+		for _, f := range st.Fields {
+			addType(f.Type)
+		}
+	}
+
+	v.result.ContractTypes = []ContractTypeScore{}
+	for _, fw := range frameworks {
+		typed, hasMethods := anyOf(types, fw.types), anyOf(methods, fw.methods)
+		score := 0.0
+		if anyImport(v.result.Imports, fw.imports) {
+			score += importSignalWeight
+		} else if anyOf(qualifiers, fw.qualifiers) {
+			score += qualifierSignalWeight
+		} else if !typed || !hasMethods {
+			// A type or method name alone is too common to count.
+			continue
+		}
+		if typed {
+			score += typeSignalWeight
+		}
+		if hasMethods {
+			score += methodSignalWeight
+		}
+		v.result.ContractTypes = append(v.result.ContractTypes, ContractTypeScore{
+			Framework:  fw.name,
+			Confidence: math.Round(score*100) / 100,
+		})
+	}
+	// Ties keep the order of frameworks, so a wasmd module importing the
+	// SDK stays cosmos_sdk unless CosmWasm signals outweigh it.
+	sort.SliceStable(v.result.ContractTypes, func(i, j int) bool {
+		return v.result.ContractTypes[i].Confidence > v.result.ContractTypes[j].Confidence
+	})
+
+	if len(v.result.ContractTypes) > 0 {
+		v.result.ContractType = v.result.ContractTypes[0].Framework
+		return
+	}
+	source := strings.ToLower(v.source)
+	if strings.Contains(source, "blockchain") || strings.Contains(source, "smart contract") {
+		v.result.ContractType = "blockchain"
+	} else {
+		v.result.ContractType = "generic"
+	}
+}
+
+func anyImport(imports []ParsedImport, fragments []string) bool {
+	for _, imp := range imports {
+		for _, fragment := range fragments {
+			if strings.Contains(imp.Path, fragment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyOf(set map[string]bool, names []string) bool {
+	for _, name := range names {
+		if set[name] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package goparser
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DirResult is the aggregate produced in -dir mode: one ParseResult per file,
+// keyed by slash-separated path relative to the scanned root.
+type DirResult struct {
+	Files map[string]*ParseResult `json:"files" proto:"1"`
+	// RiskScore is the module's score: that of its riskiest file.
+	RiskScore   int        `json:"risk_score" proto:"2"`
+	RiskRanking []FileRisk `json:"risk_ranking" proto:"3"`
+	// Merged is set with -merge.
+	Merged *MergedResult `json:"merged,omitempty" proto:"4"`
+	// Errors lists the directories ParseDir could not read and skipped.
+	Errors []string `json:"errors" proto:"5"`
+}
+
+// FileRisk is one entry of the ranked list of files by RiskScore.
+type FileRisk struct {
+	File      string `json:"file" proto:"1"`
+	RiskScore int    `json:"risk_score" proto:"2"`
+}
+
+// ParseDir parses every .go file under root, skipping vendor and testdata
+// directories and, unless includeTests is set, _test.go files. Files are
+// parsed by opts.Concurrency workers. A file that cannot be read, or whose
+// analysis panics, is reported in its own Errors, and a directory that
+// cannot be read is skipped and reported in the result's Errors, rather
+// than failing the scan. Only an unreadable root is an error.
+func ParseDir(root string, opts ParseOptions, cfg Config, includeTests bool) (*DirResult, error) {
+	type job struct{ path, rel string }
+	var jobs []job
+	var walkErrors []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			walkErrors = append(walkErrors, err.Error())
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && (d.Name() == "vendor" || d.Name() == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || (!includeTests && strings.HasSuffix(path, "_test.go")) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, job{path, filepath.ToSlash(rel)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	files := map[string]*ParseResult{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan job)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				result := parseFileRecovered(j.path, opts, cfg)
+				mu.Lock()
+				files[j.rel] = result
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+	wg.Wait()
+	result := newDirResult(files)
+	result.Errors = append(result.Errors, walkErrors...)
+	return result, nil
+}
+
+// parseFileRecovered parses one file of a multi-file run, turning read
+// errors and panics in the walker or detectors into an error result.
+func parseFileRecovered(path string, opts ParseOptions, cfg Config) *ParseResult {
+	return recovered(func() *ParseResult {
+		result, err := ParseFile(path, opts, cfg)
+		if err != nil {
+			return failedRead(err)
+		}
+		return result
+	})
+}
+
+// parseSourceRecovered is parseFileRecovered for a file already read.
+func parseSourceRecovered(path string, source []byte, opts ParseOptions, cfg Config) *ParseResult {
+	return recovered(func() *ParseResult {
+		return ParseSource(path, source, opts, cfg)
+	})
+}
+
+// recovered runs parse, turning a panic into an error result.
+func recovered(parse func() *ParseResult) (result *ParseResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = &ParseResult{PackageName: "unknown", Errors: []string{fmt.Sprintf("Internal error: %v", r)}, ToolVersion: Version}
+		}
+	}()
+	return parse()
+}
+
+// failedRead is the result reported for a file that could not be read.
+func failedRead(err error) *ParseResult {
+	return &ParseResult{PackageName: "unknown", Errors: []string{err.Error()}, ToolVersion: Version}
+}
+
+func newDirResult(files map[string]*ParseResult) *DirResult {
+	aggregate := &DirResult{Files: files, RiskRanking: []FileRisk{}, Errors: []string{}}
+	for path, result := range files {
+		aggregate.RiskRanking = append(aggregate.RiskRanking, FileRisk{File: path, RiskScore: result.RiskScore})
+		if result.RiskScore > aggregate.RiskScore {
+			aggregate.RiskScore = result.RiskScore
+		}
+	}
+	sort.Slice(aggregate.RiskRanking, func(i, j int) bool {
+		a, b := aggregate.RiskRanking[i], aggregate.RiskRanking[j]
+		if a.RiskScore != b.RiskScore {
+			return a.RiskScore > b.RiskScore
+		}
+		return a.File < b.File
+	})
+	return aggregate
+}
+
+// TimedOut reports whether -timeout stopped any of the files.
+func (d *DirResult) TimedOut() bool {
+	for _, result := range d.Files {
+		if result.TimedOut {
+			return true
+		}
+	}
+	return false
+}
+
+// FindingsAtOrAbove counts the findings at least as severe as severity
+// across all files.
+func (d *DirResult) FindingsAtOrAbove(severity string) int {
+	count := 0
+	for _, result := range d.Files {
+		count += result.FindingsAtOrAbove(severity)
+	}
+	return count
+}
+
+// ParseFiles parses each named file, keyed by its path as given. As in
+// ParseDir, a file that cannot be read is reported in its own Errors.
+func ParseFiles(names []string, opts ParseOptions, cfg Config) (*DirResult, error) {
+	files := map[string]*ParseResult{}
+	for _, name := range names {
+		files[filepath.ToSlash(name)] = parseFileRecovered(name, opts, cfg)
+	}
+	return newDirResult(files), nil
+}
+
+// MergedResult is the union of the imports and functions of every file.
+type MergedResult struct {
+	Imports   []ParsedImport   `json:"imports" proto:"1"`
+	Functions []ParsedFunction `json:"functions" proto:"2"`
+}
+
+// Merge fills in Merged. Files are taken in sorted order, so the union is
+// the same from run to run; an import is listed once however many files
+// share it.
+func (d *DirResult) Merge() {
+	paths := make([]string, 0, len(d.Files))
+	for path := range d.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	d.Merged = &MergedResult{Imports: []ParsedImport{}, Functions: []ParsedFunction{}}
+	seen := map[ParsedImport]bool{}
+	for _, path := range paths {
+		result := d.Files[path]
+		for _, imp := range result.Imports {
+			if !seen[imp] {
+				seen[imp] = true
+				d.Merged.Imports = append(d.Merged.Imports, imp)
+			}
+		}
+		d.Merged.Functions = append(d.Merged.Functions, result.Functions...)
+	}
+	sort.SliceStable(d.Merged.Imports, func(i, j int) bool {
+		return d.Merged.Imports[i].Path < d.Merged.Imports[j].Path
+	})
+}
@@ -0,0 +1,139 @@
+package goparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// syntheticTree writes files copies of the sample contract under a fresh
+// directory, ten to a package, and returns its root.
+func syntheticTree(tb testing.TB, files int) string {
+	tb.Helper()
+	src, err := os.ReadFile(sampleContract)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	root := tb.TempDir()
+	for i := 0; i < files; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("pkg%02d", i/10))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.go", i)), src, 0o644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestParseDirConcurrencyIsDeterministic(t *testing.T) {
+	root := syntheticTree(t, 40)
+	var outputs [][]byte
+	for _, workers := range []int{1, 8} {
+		result, err := ParseDir(root, ParseOptions{Concurrency: workers}, DefaultConfig(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Files) != 40 {
+			t.Fatalf("concurrency %d: got %d files, want 40", workers, len(result.Files))
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, data)
+	}
+	if string(outputs[0]) != string(outputs[1]) {
+		t.Error("output with 8 workers differs from output with 1")
+	}
+}
+
+func TestParseDirSkipsUnreadableDirectories(t *testing.T) {
+	root := syntheticTree(t, 20)
+	locked := filepath.Join(root, "pkg01")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0o755)
+	if _, err := os.ReadDir(locked); err == nil {
+		t.Skip("permissions are not enforced for this user")
+	}
+
+	result, err := ParseDir(root, ParseOptions{}, DefaultConfig(), false)
+	if err != nil {
+		t.Fatalf("scan failed on an unreadable subdirectory: %v", err)
+	}
+	if len(result.Files) != 10 {
+		t.Errorf("got %d files, want the 10 outside %s", len(result.Files), locked)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "pkg01") {
+		t.Errorf("got errors %q, want one naming pkg01", result.Errors)
+	}
+}
+
+func TestParseDirUnreadableRoot(t *testing.T) {
+	if _, err := ParseDir(filepath.Join(t.TempDir(), "missing"), ParseOptions{}, DefaultConfig(), false); err == nil {
+		t.Error("want an error for a missing root")
+	}
+}
+
+func TestParseFilesRecordsReadErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.go")
+	result, err := ParseFiles([]string{missing, sampleContract}, ParseOptions{}, DefaultConfig())
+	if err != nil {
+		t.Fatalf("ParseFiles failed on an unreadable file: %v", err)
+	}
+	failed := result.Files[filepath.ToSlash(missing)]
+	if failed == nil || len(failed.Errors) != 1 || !strings.Contains(failed.Errors[0], "failed to read file") {
+		t.Errorf("got %+v for the missing file, want a read error", failed)
+	}
+	if parsed := result.Files[sampleContract]; parsed == nil || len(parsed.Findings) == 0 {
+		t.Error("the readable file was not parsed")
+	}
+}
+
+func BenchmarkParseDir(b *testing.B) {
+	root := syntheticTree(b, 300)
+	// Concurrency 0 is one worker per CPU.
+	for _, bc := range []struct {
+		name    string
+		workers int
+	}{{"sequential", 1}, {"parallel", 0}} {
+		b.Run(bc.name, func(b *testing.B) {
+			opts := ParseOptions{Concurrency: bc.workers}
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseDir(root, opts, DefaultConfig(), false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDirResultTotals(t *testing.T) {
+	result, err := ParseFiles([]string{sampleContract, "api.go"}, ParseOptions{}, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 0
+	for _, file := range result.Files {
+		want += file.FindingsAtOrAbove(SeverityHigh)
+	}
+	if want == 0 {
+		t.Fatal("sample has no high findings")
+	}
+	if got := result.FindingsAtOrAbove(SeverityHigh); got != want {
+		t.Errorf("got %d findings at or above high, want %d", got, want)
+	}
+	if result.TimedOut() {
+		t.Error("TimedOut is set without a timeout")
+	}
+	result.Files["api.go"].TimedOut = true
+	if !result.TimedOut() {
+		t.Error("TimedOut is not set when a file timed out")
+	}
+}
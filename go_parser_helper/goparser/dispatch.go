@@ -0,0 +1,290 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// DispatchRoute maps a message type to the function that handles it, either
+// through a type-switch router or a msgServer method.
+type DispatchRoute struct {
+	MessageType string `json:"message_type" proto:"1"`
+	Handler     string `json:"handler" proto:"2"`
+	Kind        string `json:"kind" proto:"3"` // "type_switch" or "msg_server"
+	LineStart   int    `json:"line_start" proto:"4"`
+}
+
+// isMsgServerType reports whether a receiver type implements a module's
+// generated MsgServer (msgServer, MsgServerImpl, ...).
+func isMsgServerType(name string) bool {
+	return strings.Contains(strings.ToLower(name), "msgserver")
+}
+
+// buildDispatch records the file's message routing: cases of type switches
+// over messages and methods on msgServer types.
+func (v *GoVisitor) buildDispatch(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		if isMsgServerType(receiverTypeName(fn)) && fn.Type.Params != nil {
+			for _, field := range fn.Type.Params.List {
+				if typ := v.typeToString(field.Type); isMessageTypeName(typ) {
+					v.result.Dispatch = append(v.result.Dispatch, DispatchRoute{
+						MessageType: baseTypeName(typ),
+						Handler:     funcDisplayName(fn),
+						Kind:        "msg_server",
+						LineStart:   v.line(fn),
+					})
+					break
+				}
+			}
+		}
+
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			sw, ok := n.(*ast.TypeSwitchStmt)
+			if !ok {
+				return true
+			}
+			for _, stmt := range sw.Body.List {
+				clause := stmt.(*ast.CaseClause)
+				handler := v.firstCallTarget(clause.Body, fn)
+				if handler == "" {
+					continue
+				}
+				for _, expr := range clause.List {
+					typ := v.typeToString(expr)
+					if !isMessageTypeName(typ) {
+						continue
+					}
+					v.result.Dispatch = append(v.result.Dispatch, DispatchRoute{
+						MessageType: baseTypeName(typ),
+						Handler:     handler,
+						Kind:        "type_switch",
+						LineStart:   v.line(clause),
+					})
+				}
+			}
+			return true
+		})
+	}
+}
+
+// firstCallTarget returns the display name of the first call in stmts,
+// preferring local declarations so routes line up with the function list.
+func (v *GoVisitor) firstCallTarget(stmts []ast.Stmt, caller *ast.FuncDecl) string {
+	target := ""
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || target != "" {
+				return target == ""
+			}
+			if decl := v.funcs.resolve(call, caller); decl != nil {
+				target = funcDisplayName(decl)
+			} else {
+				target = v.nodeText(call.Fun)
+			}
+			return false
+		})
+		if target != "" {
+			break
+		}
+	}
+	return target
+}
+
+// UnusedMessageField is a message field that none of its handlers read.
+type UnusedMessageField struct {
+	Issue       `proto:"1"`
+	MessageType string `json:"message_type" proto:"9"`
+	Field       string `json:"field" proto:"10"`
+}
+
+// messageHandlers returns the functions handling messageType: dispatch
+// targets plus any function taking the message as a parameter, excluding
+// the message's own methods.
+func (v *GoVisitor) messageHandlers(file *ast.File, messageType string) []*ast.FuncDecl {
+	targets := map[string]bool{}
+	for _, route := range v.result.Dispatch {
+		if route.MessageType == messageType {
+			targets[route.Handler] = true
+		}
+	}
+	var handlers []*ast.FuncDecl
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || receiverTypeName(fn) == messageType {
+			continue
+		}
+		if targets[funcDisplayName(fn)] || len(v.paramsOfType(fn, messageType)) > 0 {
+			handlers = append(handlers, fn)
+		}
+	}
+	return handlers
+}
+
+// paramsOfType returns the names of fn's parameters whose base type is
+// typeName.
+func (v *GoVisitor) paramsOfType(fn *ast.FuncDecl, typeName string) []string {
+	var names []string
+	if fn.Type.Params == nil {
+		return names
+	}
+	for _, field := range fn.Type.Params.List {
+		if baseTypeName(v.typeToString(field.Type)) != typeName {
+			continue
+		}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+	}
+	return names
+}
+
+// fieldReads collects the fields read through param within body. A Get<Field>
+// getter counts as reading Field. whole is true when the value escapes as a
+// whole (passed on, dereferenced, copied), in which case any field may be read.
+func fieldReads(body *ast.BlockStmt, param string) (fields map[string]bool, whole bool) {
+	fields = map[string]bool{}
+	selectorX := map[*ast.Ident]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			if id, ok := node.X.(*ast.Ident); ok && id.Name == param {
+				selectorX[id] = true
+				fields[node.Sel.Name] = true
+				fields[strings.TrimPrefix(node.Sel.Name, "Get")] = true
+			}
+		case *ast.Ident:
+			if node.Name == param && !selectorX[node] {
+				whole = true
+			}
+		}
+		return true
+	})
+	return fields, whole
+}
+
+// detectUnusedMessageFields flags message fields that no handler of that
+// message ever reads.
+func (v *GoVisitor) detectUnusedMessageFields(file *ast.File) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok || !isMessageTypeName(ts.Name.Name) {
+				continue
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			v.checkMessageFields(file, ts.Name.Name, st)
+		}
+	}
+}
+
+func (v *GoVisitor) checkMessageFields(file *ast.File, messageType string, st *ast.StructType) {
+	handlers := v.messageHandlers(file, messageType)
+	if len(handlers) == 0 {
+		return
+	}
+	read := map[string]bool{}
+	var names []string
+	for _, fn := range handlers {
+		names = append(names, funcDisplayName(fn))
+		for _, param := range v.paramsOfType(fn, messageType) {
+			fields, whole := fieldReads(fn.Body, param)
+			if whole {
+				return
+			}
+			for field := range fields {
+				read[field] = true
+			}
+		}
+	}
+	for _, field := range st.Fields.List {
+		for _, name := range field.Names {
+			if name.Name == "_" || read[name.Name] {
+				continue
+			}
+			v.result.UnusedMessageFields = append(v.result.UnusedMessageFields, UnusedMessageField{
+				Issue: Issue{
+					RuleID:   "QLK-UNUSED-MSG-FIELD",
+					Severity: SeverityLow,
+					Message:  fmt.Sprintf("field %s of %s is never read by its handler %s", name.Name, messageType, strings.Join(names, ", ")),
+					Function: names[0],
+					Span:     v.span(name),
+				},
+				MessageType: messageType,
+				Field:       name.Name,
+			})
+		}
+	}
+}
+
+// isHandlerShaped reports whether fn looks like a message handler: it takes
+// a context and a concrete message type and returns an error last.
+func (v *GoVisitor) isHandlerShaped(fn *ast.FuncDecl) bool {
+	if len(v.contextParams(fn.Type)) == 0 || fn.Type.Results == nil {
+		return false
+	}
+	results := fn.Type.Results.List
+	if id, ok := results[len(results)-1].Type.(*ast.Ident); !ok || id.Name != "error" {
+		return false
+	}
+	for _, field := range fn.Type.Params.List {
+		if typ := v.typeToString(field.Type); isMessageTypeName(typ) && baseTypeName(typ) != "Msg" {
+			return true
+		}
+	}
+	return false
+}
+
+// detectUnregisteredHandlers flags handler-shaped functions that no dispatch
+// route targets and nothing in the file calls. Files without any routing
+// are skipped, since their handlers are registered elsewhere.
+func (v *GoVisitor) detectUnregisteredHandlers(file *ast.File) {
+	if len(v.result.Dispatch) == 0 {
+		return
+	}
+	wired := map[string]bool{}
+	for _, route := range v.result.Dispatch {
+		wired[route.Handler] = true
+	}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if callee := v.funcs.resolve(call, fn); callee != nil && callee != fn {
+					wired[funcDisplayName(callee)] = true
+				}
+			}
+			return true
+		})
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || isMsgServerType(receiverTypeName(fn)) || wired[funcDisplayName(fn)] || !v.isHandlerShaped(fn) {
+			continue
+		}
+		v.result.UnregisteredHandlers = append(v.result.UnregisteredHandlers, Issue{
+			RuleID:   "QLK-UNREGISTERED-HANDLER",
+			Severity: SeverityMedium,
+			Message:  fmt.Sprintf("handler %s is not routed by any dispatch case or called in this file", funcDisplayName(fn)),
+			Function: funcDisplayName(fn),
+			Span:     v.span(fn),
+		})
+	}
+}
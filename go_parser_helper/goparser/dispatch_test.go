@@ -0,0 +1,92 @@
+package goparser
+
+import "testing"
+
+// router is a handler file whose type switch routes MsgSend to
+// handleSend.
+const router = `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type MsgSend struct {
+	From   string
+	Amount uint64
+	Memo   string
+}
+
+type MsgBurn struct{ Amount uint64 }
+
+func NewHandler() func(ctx sdk.Context, msg sdk.Msg) error {
+	return func(ctx sdk.Context, msg sdk.Msg) error {
+		switch m := msg.(type) {
+		case *MsgSend:
+			return handleSend(ctx, m)
+		}
+		return nil
+	}
+}
+
+`
+
+func TestDispatchRoutes(t *testing.T) {
+	result := parseFixture(t, router+`func handleSend(ctx sdk.Context, msg *MsgSend) error {
+	_, _, _ = msg.From, msg.Amount, msg.Memo
+	return nil
+}
+`)
+	if len(result.Dispatch) != 1 {
+		t.Fatalf("got routes %+v, want one", result.Dispatch)
+	}
+	if route := result.Dispatch[0]; route.MessageType != "MsgSend" || route.Handler != "handleSend" || route.Kind != "type_switch" {
+		t.Errorf("got route %+v", route)
+	}
+}
+
+func TestUnusedMessageField(t *testing.T) {
+	checkRule(t, "QLK-UNUSED-MSG-FIELD", []ruleCase{
+		{"field never read", router + `func handleSend(ctx sdk.Context, msg *MsgSend) error {
+	_, _ = msg.From, msg.GetAmount()
+	return nil
+}
+`, []int{8}},
+		{"every field read", router + `func handleSend(ctx sdk.Context, msg *MsgSend) error {
+	_, _, _ = msg.From, msg.Amount, msg.Memo
+	return nil
+}
+`, nil},
+		{"message passed on whole", router + `func handleSend(ctx sdk.Context, msg *MsgSend) error {
+	return store(msg)
+}
+
+func store(msg *MsgSend) error { return nil }
+`, nil},
+	})
+}
+
+func TestUnregisteredHandler(t *testing.T) {
+	const handleSend = `func handleSend(ctx sdk.Context, msg *MsgSend) error {
+	_, _, _ = msg.From, msg.Amount, msg.Memo
+	return nil
+}
+`
+	checkRule(t, "QLK-UNREGISTERED-HANDLER", []ruleCase{
+		{"handler no route reaches", router + handleSend + `
+func handleBurn(ctx sdk.Context, msg *MsgBurn) error {
+	_ = msg.Amount
+	return nil
+}
+`, []int{28}},
+		{"every handler routed", router + handleSend, nil},
+		{"no routing in the file", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type MsgBurn struct{ Amount uint64 }
+
+func handleBurn(ctx sdk.Context, msg *MsgBurn) error {
+	_ = msg.Amount
+	return nil
+}
+`, nil},
+	})
+}
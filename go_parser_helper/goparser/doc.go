@@ -0,0 +1,9 @@
+// Package goparser parses Go smart-contract source and runs the
+// ContractQuard rule detectors over it. Parse and ParseReader cover the
+// common case; AnalysisSession runs several configs over one parse, and
+// ParseDir, ParseFiles and ParseManifest analyze many files at once.
+// NewServer exposes ParseContext over HTTP.
+//
+// Results marshal to the JSON consumed by the Python analyzer, and to the
+// SARIF and protobuf forms the go_parser_helper CLI offers.
+package goparser
@@ -0,0 +1,63 @@
+package goparser
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// FeaturesUsed is a capability fingerprint of the language features a file
+// relies on, used for reviewer routing and minimum Go version checks.
+type FeaturesUsed struct {
+	Generics   bool `json:"generics" proto:"1"`
+	Goroutines bool `json:"goroutines" proto:"2"`
+	Channels   bool `json:"channels" proto:"3"`
+	Reflection bool `json:"reflection" proto:"4"`
+	Cgo        bool `json:"cgo" proto:"5"`
+	Unsafe     bool `json:"unsafe" proto:"6"`
+	Defer      bool `json:"defer" proto:"7"`
+	Recover    bool `json:"recover" proto:"8"`
+}
+
+func (v *GoVisitor) detectFeatures(file *ast.File) {
+	features := &v.result.FeaturesUsed
+	features.Goroutines = len(v.result.Goroutines) > 0
+	features.Channels = len(v.result.Channels) > 0
+
+	for _, imp := range file.Imports {
+		switch strings.Trim(imp.Path.Value, `"`) {
+		case "reflect":
+			features.Reflection = true
+		case "C":
+			features.Cgo = true
+		case "unsafe":
+			features.Unsafe = true
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.FuncType:
+			if t.TypeParams != nil && len(t.TypeParams.List) > 0 {
+				features.Generics = true
+			}
+		case *ast.TypeSpec:
+			if t.TypeParams != nil && len(t.TypeParams.List) > 0 {
+				features.Generics = true
+			}
+		case *ast.ChanType, *ast.SendStmt:
+			features.Channels = true
+		case *ast.UnaryExpr:
+			if t.Op == token.ARROW {
+				features.Channels = true
+			}
+		case *ast.DeferStmt:
+			features.Defer = true
+		case *ast.CallExpr:
+			if id, ok := t.Fun.(*ast.Ident); ok && id.Name == "recover" {
+				features.Recover = true
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,241 @@
+package goparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Finding is one entry of the flat findings stream: every rule issue and
+// every recorded panic, leak-suspect goroutine or discarded error, normalized
+// to the same shape.
+type Finding struct {
+	RuleID   string `json:"rule_id" proto:"1"`
+	Severity string `json:"severity" proto:"2"`
+	Message  string `json:"message" proto:"3"`
+	Line     int    `json:"line" proto:"4"`
+	// Column, EndLine and EndColumn are zero when the rule reports a line
+	// only; columns count code points, as in Span.
+	Column    int    `json:"column,omitempty" proto:"5"`
+	EndLine   int    `json:"end_line,omitempty" proto:"6"`
+	EndColumn int    `json:"end_column,omitempty" proto:"7"`
+	Function  string `json:"function,omitempty" proto:"8"`
+	Category  string `json:"category" proto:"9"`
+	Snippet   string `json:"snippet,omitempty" proto:"10"`
+	// Fingerprint identifies the finding across runs for -baseline; it does
+	// not depend on the line number.
+	Fingerprint string `json:"fingerprint" proto:"11"`
+}
+
+// SeverityLevels lists the finding severities from least to most severe.
+var SeverityLevels = []string{SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// SeverityRank returns the position of severity in SeverityLevels, or -1
+// when it is not one of them.
+func SeverityRank(severity string) int {
+	for i, level := range SeverityLevels {
+		if level == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// FindingsAtOrAbove counts the findings at least as severe as severity.
+func (r *ParseResult) FindingsAtOrAbove(severity string) int {
+	threshold := SeverityRank(severity)
+	count := 0
+	for _, f := range r.Findings {
+		if SeverityRank(f.Severity) >= threshold {
+			count++
+		}
+	}
+	return count
+}
+
+// canSignalFailure reports whether any of a function's results can carry a
+// failure: an error, an ok bool, a nilable pointer, or a Result or Response
+// value.
+func canSignalFailure(returnTypes []string) bool {
+	for _, typ := range returnTypes {
+		name := baseTypeName(typ)
+		if typ == "error" || typ == "bool" || strings.HasPrefix(typ, "*") ||
+			strings.HasSuffix(name, "Result") || strings.HasSuffix(name, "Response") {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleCategories groups rule IDs for the report layer. Rules missing here
+// are reported as "general".
+var ruleCategories = map[string]string{
+	"QLK-PANIC":                   "reliability",
+	"QLK-IGNORED-ERR":             "reliability",
+	"QLK-NAMED-ERR-NOT-SET":       "reliability",
+	"QLK-INCONSISTENT-ERR-RETURN": "reliability",
+	"QLK-ERR-SHADOW":              "reliability",
+	"QLK-NIL-COLLECTION-RETURN":   "reliability",
+	"QLK-NO-ERROR-RETURN":         "reliability",
+	"QLK-UNCHECKED-INDEX":         "reliability",
+	"QLK-UNCHECKED-MAP-LOOKUP":    "reliability",
+	"QLK-RANGE-COPY-MUTATION":     "reliability",
+	"QLK-UNBOUNDED-LOOP":          "gas",
+	"QLK-NONDETERMINISM":          "determinism",
+	"QLK-INTEGER-OVERFLOW":        "arithmetic",
+	"QLK-MAP-ZERO-INSERT":         "state",
+	"QLK-UNCANCELLABLE-LOOP":      "concurrency",
+	"QLK-CONCURRENT-CTX":          "concurrency",
+	"QLK-GOROUTINE-LEAK":          "concurrency",
+	"QLK-UNLOCKED-ACCESS":         "concurrency",
+	"QLK-UNGUARDED-SHARED-MAP":    "concurrency",
+	"QLK-LOOP-VAR-CAPTURE":        "concurrency",
+	"QLK-MISSING-AUTH":            "access_control",
+	"QLK-PERMISSIVE-SIGNERS":      "access_control",
+	"QLK-EMPTY-SIGNERS":           "access_control",
+	"QLK-MISSING-GETSIGNERS":      "access_control",
+	"QLK-VALIDATION-ORDER":        "validation",
+	"QLK-STUB-VALIDATION":         "validation",
+	"QLK-GENESIS-VALIDATION":      "validation",
+	"QLK-UNUSED-MSG-FIELD":        "validation",
+	"QLK-EVENT-INJECTION":         "security",
+	"QLK-REENTRANCY":              "security",
+	"QLK-SENSITIVE-LOGGING":       "security",
+	"QLK-HARDCODED-SECRET":        "security",
+	"QLK-UNSAFE":                  "security",
+	"QLK-KEY-COLLISION":           "state",
+	"QLK-STRING-STORE-KEY":        "state",
+	"QLK-MISSING-INVARIANT":       "state",
+	"QLK-MISSING-MIGRATION":       "state",
+	"QLK-UNUSED-CONTEXT":          "state",
+	"QLK-DIRECT-STATE-WRITE":      "state",
+	"QLK-UNREGISTERED-HANDLER":    "cosmos",
+	"QLK-KEEPER-COUPLING":         "design",
+	"QLK-SHOULD-BE-METHOD":        "design",
+	"QLK-TAG-CONFLICT":            "hygiene",
+	"QLK-EMPTY-JSON-TAG":          "hygiene",
+	"QLK-JSON-INT64-PRECISION":    "serialization",
+	"QLK-UNUSED-FIELD":            "hygiene",
+	"QLK-DUPLICATE-LITERAL":       "hygiene",
+	"QLK-DUPLICATE-IMPORT":        "hygiene",
+	"QLK-UNUSED-IMPORT":           "hygiene",
+	"QLK-DEEP-NESTING":            "design",
+	"QLK-TOO-MANY-PARAMS":         "design",
+	"QLK-SHOULD-BE-CONST":         "hygiene",
+	"QLK-REDUNDANT-CONDITION":     "hygiene",
+}
+
+// severityOf returns the severity configured for an issue's rule, falling
+// back to the one its detector assigned.
+func (v *GoVisitor) severityOf(issue Issue) string {
+	if setting := v.config.Rules[issue.RuleID]; SeverityRank(setting.Severity) >= 0 {
+		return setting.Severity
+	}
+	if severity, ok := v.config.Severities[issue.RuleID]; ok {
+		return severity
+	}
+	return issue.Severity
+}
+
+// buildFindings flattens the rule issues and the recorded panics,
+// leak-suspect goroutines and discarded errors into Findings.
+func (v *GoVisitor) buildFindings() {
+	issues := v.result.issues()
+	for _, p := range v.result.Panics {
+		where := p.EnclosingFunction
+		if where == "" {
+			where = "package scope"
+		}
+		issues = append(issues, Issue{
+			RuleID:   "QLK-PANIC",
+			Severity: SeverityMedium,
+			Message:  fmt.Sprintf("panic(%s) in %s", p.Argument, where),
+			Function: p.EnclosingFunction,
+			Span:     p.Span,
+		})
+	}
+	for _, g := range v.result.Goroutines {
+		if !g.LeakSuspect {
+			continue
+		}
+		issues = append(issues, Issue{
+			RuleID:   "QLK-GOROUTINE-LEAK",
+			Severity: SeverityMedium,
+			Message:  "goroutine loops forever with no context, stop channel or WaitGroup and cannot be stopped",
+			Function: g.EnclosingFunction,
+			Span:     g.Span,
+		})
+	}
+	for _, fn := range v.result.Functions {
+		if fn.MaxNestingDepth > v.config.NestingThreshold {
+			issues = append(issues, Issue{
+				RuleID:   "QLK-DEEP-NESTING",
+				Severity: SeverityLow,
+				Message:  fmt.Sprintf("%s nests blocks %d deep (threshold %d); deep nesting hides missing guards", fn.displayName(), fn.MaxNestingDepth, v.config.NestingThreshold),
+				Function: fn.displayName(),
+				Span:     Span{LineStart: fn.LineStart, ColStart: fn.ColStart},
+			})
+		}
+		// Parameters lists a, b int as two entries and a variadic one as
+		// one, which is how callers count positional arguments.
+		if len(fn.Parameters) > v.config.ParamThreshold {
+			issues = append(issues, Issue{
+				RuleID:   "QLK-TOO-MANY-PARAMS",
+				Severity: SeverityLow,
+				Message:  fmt.Sprintf("%s takes %d parameters (threshold %d); long positional lists invite swapped arguments, consider an options struct", fn.displayName(), len(fn.Parameters), v.config.ParamThreshold),
+				Function: fn.displayName(),
+				Span:     Span{LineStart: fn.LineStart, ColStart: fn.ColStart},
+			})
+		}
+		if fn.IsExported && fn.MutatesState && !canSignalFailure(fn.ReturnTypes) {
+			issues = append(issues, Issue{
+				RuleID:   "QLK-NO-ERROR-RETURN",
+				Severity: SeverityMedium,
+				Message:  fmt.Sprintf("%s changes %s but returns no error, so callers cannot tell when it fails", fn.displayName(), strings.Join(fn.MutatedFields, ", ")),
+				Function: fn.displayName(),
+				Span:     Span{LineStart: fn.LineStart, ColStart: fn.ColStart},
+			})
+		}
+	}
+	for _, e := range v.result.IgnoredErrors {
+		issues = append(issues, Issue{
+			RuleID:   "QLK-IGNORED-ERR",
+			Severity: SeverityMedium,
+			Message:  fmt.Sprintf("result %d of %s is discarded", e.DiscardedIndex, e.Call),
+			Function: e.EnclosingFunction,
+			Span:     e.Span,
+		})
+	}
+
+	v.result.Errors = append(v.result.Errors, v.config.ruleWarnings()...)
+	for _, issue := range issues {
+		if !v.config.ruleEnabled(issue.RuleID) {
+			continue
+		}
+		category, ok := ruleCategories[issue.RuleID]
+		if !ok {
+			category = "general"
+		}
+		finding := Finding{
+			RuleID:    issue.RuleID,
+			Severity:  v.severityOf(issue),
+			Message:   issue.Message,
+			Line:      issue.LineStart,
+			Column:    issue.ColStart,
+			EndLine:   issue.LineEnd,
+			EndColumn: issue.ColEnd,
+			Function:  issue.Function,
+			Category:  category,
+			Snippet:   v.redacted(v.snippet(issue.LineStart, issue.LineStart)),
+		}
+		finding.Fingerprint = finding.fingerprint()
+		v.result.Findings = append(v.result.Findings, finding)
+	}
+}
+
+// redacted replaces the literals reported as secrets in a snippet.
+func (v *GoVisitor) redacted(snippet string) string {
+	for literal, preview := range v.redactions {
+		snippet = strings.ReplaceAll(snippet, literal, preview)
+	}
+	return snippet
+}
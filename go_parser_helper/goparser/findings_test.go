@@ -0,0 +1,187 @@
+package goparser
+
+import "testing"
+
+func TestPanicRule(t *testing.T) {
+	checkRule(t, "QLK-PANIC", []ruleCase{
+		{"panic in a function and at package scope", `package p
+
+var boot = func() int { panic("boot") }()
+
+func Must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+`, []int{3, 7}},
+		{"no panic", `package p
+
+import "errors"
+
+func Must(err error) error {
+	if err != nil {
+		return errors.New("failed")
+	}
+	return nil
+}
+`, nil},
+	})
+}
+
+func TestIgnoredErr(t *testing.T) {
+	checkRule(t, "QLK-IGNORED-ERR", []ruleCase{
+		{"discarded error", `package p
+
+import "strconv"
+
+func Parse(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+`, []int{6}},
+		{"local callee's error position", `package p
+
+func load() (error, int) { return nil, 0 }
+
+func Run() int {
+	_, n := load()
+	return n
+}
+`, []int{6}},
+		{"error handled", `package p
+
+import "strconv"
+
+func Parse(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	return n, err
+}
+`, nil},
+		{"local callee's non-error result", `package p
+
+func split() (int, int) { return 0, 1 }
+
+func Run() int {
+	a, _ := split()
+	return a
+}
+`, nil},
+	})
+}
+
+func TestDeepNesting(t *testing.T) {
+	checkRule(t, "QLK-DEEP-NESTING", []ruleCase{
+		{"five levels", `package p
+
+func Walk(grid [][]int) int {
+	n := 0
+	for _, row := range grid {
+		for _, cell := range row {
+			if cell > 0 {
+				switch cell {
+				case 1:
+					if n < 10 {
+						n++
+					}
+				}
+			}
+		}
+	}
+	return n
+}
+`, []int{3}},
+		{"at the threshold", `package p
+
+func Walk(grid [][]int) int {
+	n := 0
+	for _, row := range grid {
+		for _, cell := range row {
+			if cell > 0 {
+				if n < 10 {
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
+`, nil},
+		{"else-if chain is one level", `package p
+
+func Kind(n int) string {
+	if n > 2 {
+		return "big"
+	} else if n > 1 {
+		return "two"
+	} else if n > 0 {
+		return "one"
+	} else if n == 0 {
+		return "zero"
+	} else if n < -1 {
+		return "negative"
+	}
+	return "minus one"
+}
+`, nil},
+	})
+}
+
+func TestNoErrorReturn(t *testing.T) {
+	checkRule(t, "QLK-NO-ERROR-RETURN", []ruleCase{
+		{"exported mutator without an error", `package p
+
+type Pool struct{ reserve int }
+
+func (p *Pool) Drain() {
+	p.reserve = 0
+}
+`, []int{5}},
+		{"returns an error", `package p
+
+type Pool struct{ reserve int }
+
+func (p *Pool) Drain() error {
+	p.reserve = 0
+	return nil
+}
+`, nil},
+		{"returns a bool", `package p
+
+type Pool struct{ reserve int }
+
+func (p *Pool) Drain() bool {
+	p.reserve = 0
+	return true
+}
+`, nil},
+		{"unexported or read-only", `package p
+
+type Pool struct{ reserve int }
+
+func (p *Pool) drain() {
+	p.reserve = 0
+}
+
+func (p *Pool) Reserve() int {
+	return p.reserve
+}
+`, nil},
+	})
+}
+
+func TestTooManyParams(t *testing.T) {
+	checkRule(t, "QLK-TOO-MANY-PARAMS", []ruleCase{
+		{"seven parameters", `package p
+
+func Open(a, b, c, d string, e, f int, g bool) {}
+`, []int{3}},
+		{"six parameters", `package p
+
+func Open(a, b, c, d string, e, f int) {}
+`, nil},
+		{"variadic counts once", `package p
+
+func Open(a, b, c, d, e string, rest ...int) {}
+`, nil},
+	})
+}
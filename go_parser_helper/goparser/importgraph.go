@@ -0,0 +1,169 @@
+package goparser
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ImportGraph is the -format import-graph output: an adjacency list of the
+// local packages under the scanned root, keyed by slash-separated directory
+// relative to it.
+type ImportGraph struct {
+	Module   string                `json:"module,omitempty"`
+	Packages map[string]*GraphNode `json:"packages"`
+	// Cycles lists each strongly connected group of local packages, members
+	// sorted by directory.
+	Cycles [][]string `json:"cycles"`
+}
+
+// GraphNode is one local package and the local packages it imports.
+type GraphNode struct {
+	Name    string   `json:"name"`
+	Imports []string `json:"imports"`
+}
+
+// modulePath reads the module directive from root/go.mod, or returns "" when
+// root is not a module root.
+func modulePath(root string) string {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`)
+		}
+	}
+	return ""
+}
+
+// BuildImportGraph links the packages of a -dir result by their import
+// paths. An import is local when it is module/dir, or, without a go.mod,
+// when it ends in /dir.
+func BuildImportGraph(root string, res *DirResult) *ImportGraph {
+	graph := &ImportGraph{Module: modulePath(root), Packages: map[string]*GraphNode{}, Cycles: [][]string{}}
+	for file, result := range res.Files {
+		if result.PackageName == "unknown" {
+			continue
+		}
+		dir := path.Dir(file)
+		if graph.Packages[dir] == nil {
+			graph.Packages[dir] = &GraphNode{Name: result.PackageName, Imports: []string{}}
+		}
+	}
+
+	resolve := func(importPath string) string {
+		if graph.Module != "" {
+			if importPath == graph.Module {
+				return "."
+			}
+			if dir := strings.TrimPrefix(importPath, graph.Module+"/"); dir != importPath {
+				if graph.Packages[dir] != nil {
+					return dir
+				}
+				return ""
+			}
+		}
+		for dir := range graph.Packages {
+			if dir != "." && (importPath == dir || strings.HasSuffix(importPath, "/"+dir)) {
+				return dir
+			}
+		}
+		return ""
+	}
+
+	for file, result := range res.Files {
+		node := graph.Packages[path.Dir(file)]
+		if node == nil {
+			continue
+		}
+		for _, imp := range result.Imports {
+			target := resolve(imp.Path)
+			if target == "" || target == path.Dir(file) {
+				continue
+			}
+			if !containsString(node.Imports, target) {
+				node.Imports = append(node.Imports, target)
+			}
+		}
+	}
+	for _, node := range graph.Packages {
+		sort.Strings(node.Imports)
+	}
+	graph.Cycles = importCycles(graph.Packages)
+	return graph
+}
+
+// importCycles returns the strongly connected components with more than one
+// package, found with Tarjan's algorithm.
+func importCycles(packages map[string]*GraphNode) [][]string {
+	var dirs []string
+	for dir := range packages {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	index := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	cycles := [][]string{}
+
+	var connect func(dir string)
+	connect = func(dir string) {
+		index[dir] = len(index)
+		low[dir] = index[dir]
+		stack = append(stack, dir)
+		onStack[dir] = true
+		for _, next := range packages[dir].Imports {
+			if _, seen := index[next]; !seen {
+				connect(next)
+				if low[next] < low[dir] {
+					low[dir] = low[next]
+				}
+			} else if onStack[next] && index[next] < low[dir] {
+				low[dir] = index[next]
+			}
+		}
+		if low[dir] != index[dir] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == dir {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+	for _, dir := range dirs {
+		if _, seen := index[dir]; !seen {
+			connect(dir)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package goparser
+
+// InterfaceSatisfaction lists the declared structs whose method sets cover an
+// interface declared in the same file.
+type InterfaceSatisfaction struct {
+	Interface    string     `json:"interface" proto:"1"`
+	Implementers []string   `json:"implementers" proto:"2"`
+	NearMisses   []NearMiss `json:"near_misses" proto:"3"`
+	Note         string     `json:"note" proto:"4"`
+}
+
+// NearMiss is a struct implementing more than half of an interface's methods
+// but not all of them.
+type NearMiss struct {
+	Struct  string   `json:"struct" proto:"1"`
+	Missing []string `json:"missing" proto:"2"`
+}
+
+// checkSatisfactions matches every interface with methods against the
+// method set of every struct. Only names are compared, not signatures, and
+// methods of embedded interfaces are not expanded.
+func (v *GoVisitor) checkSatisfactions() {
+	for _, iface := range v.result.Interfaces {
+		if len(iface.Methods) == 0 {
+			continue
+		}
+		sat := InterfaceSatisfaction{
+			Interface:    iface.Name,
+			Implementers: []string{},
+			NearMisses:   []NearMiss{},
+			Note:         "matched by method name only; signatures were not compared",
+		}
+		for _, st := range v.result.Structs {
+			has := map[string]bool{}
+			for _, m := range st.Methods {
+				has[m] = true
+			}
+			var missing []string
+			for _, m := range iface.Methods {
+				if !has[m] {
+					missing = append(missing, m)
+				}
+			}
+			switch {
+			case len(missing) == 0:
+				sat.Implementers = append(sat.Implementers, st.Name)
+			case len(iface.Methods)-len(missing) > len(iface.Methods)/2:
+				sat.NearMisses = append(sat.NearMisses, NearMiss{Struct: st.Name, Missing: missing})
+			}
+		}
+		v.result.Satisfactions = append(v.result.Satisfactions, sat)
+	}
+}
@@ -0,0 +1,140 @@
+package goparser
+
+//go:generate sh -c "go run .. -schema > ../parse_result.schema.json"
+
+import (
+	"reflect"
+	"strings"
+)
+
+// The JSON Schema is derived from the result types by reflection, like the
+// protobuf encoding, so it describes exactly what encoding/json emits:
+// embedded structs are flattened, fields without omitempty are required,
+// and nil slices, maps and pointers may be null unless omitempty drops
+// them. Regenerate parse_result.schema.json with go generate whenever a
+// result type changes.
+
+// schemaEnums constrains string fields, keyed by "Type.Field", to the
+// values the parser emits.
+var schemaEnums = map[string][]string{
+	"ParsedChannel.Direction":    {"send", "receive", "bidirectional"},
+	"ParsedSelectCase.Direction": {"send", "receive"},
+	"ParseResult.Wrapped":        {"package", "function"},
+	"Issue.Severity":             SeverityLevels,
+	"Finding.Severity":           SeverityLevels,
+}
+
+// schemaDescriptions tell apart the output shapes, which depend on the
+// flags a run was given.
+var schemaDescriptions = map[string]string{
+	"ParseResult":    "Output for a single -file.",
+	"DirResult":      "Output for -dir, -manifest or several -file inputs.",
+	"QuietResult":    "Output for a single -file with -quiet: findings and errors only.",
+	"QuietDirResult": "Output for -dir, -manifest or several -file inputs with -quiet.",
+}
+
+func init() {
+	var names []string
+	for _, fw := range frameworks {
+		names = append(names, fw.name)
+	}
+	schemaEnums["ContractTypeScore.Framework"] = names
+	// Results for input that never parsed leave the contract type empty.
+	schemaEnums["ParseResult.ContractType"] = append(append([]string{}, names...), "blockchain", "generic", "")
+}
+
+// JSONSchema returns a draft 2020-12 JSON Schema whose root is root and
+// whose $defs also hold every struct reachable from extra.
+func JSONSchema(root reflect.Type, extra ...reflect.Type) map[string]interface{} {
+	defs := map[string]interface{}{}
+	var define func(t reflect.Type)
+	var typeSchema func(t reflect.Type, enum []string) map[string]interface{}
+	typeSchema = func(t reflect.Type, enum []string) map[string]interface{} {
+		switch t.Kind() {
+		case reflect.Ptr:
+			return typeSchema(t.Elem(), enum)
+		case reflect.Slice:
+			return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), nil)}
+		case reflect.Map:
+			return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), nil)}
+		case reflect.Struct:
+			define(t)
+			return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+		case reflect.String:
+			s := map[string]interface{}{"type": "string"}
+			if enum != nil {
+				s["enum"] = enum
+			}
+			return s
+		case reflect.Bool:
+			return map[string]interface{}{"type": "boolean"}
+		case reflect.Float32, reflect.Float64:
+			return map[string]interface{}{"type": "number"}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return map[string]interface{}{"type": "integer"}
+		}
+		return map[string]interface{}{}
+	}
+	define = func(t reflect.Type) {
+		if _, ok := defs[t.Name()]; ok {
+			return
+		}
+		properties := map[string]interface{}{}
+		required := []string{}
+		s := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if description, ok := schemaDescriptions[t.Name()]; ok {
+			s["description"] = description
+		}
+		defs[t.Name()] = s
+
+		var walk func(t reflect.Type)
+		walk = func(t reflect.Type) {
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				tag := strings.Split(f.Tag.Get("json"), ",")
+				if f.Anonymous && f.Type.Kind() == reflect.Struct && tag[0] == "" {
+					walk(f.Type)
+					continue
+				}
+				if !f.IsExported() || tag[0] == "-" {
+					continue
+				}
+				name := tag[0]
+				if name == "" {
+					name = f.Name
+				}
+				omitempty := false
+				for _, opt := range tag[1:] {
+					omitempty = omitempty || opt == "omitempty"
+				}
+				field := typeSchema(f.Type, schemaEnums[t.Name()+"."+f.Name])
+				switch f.Type.Kind() {
+				case reflect.Slice, reflect.Map, reflect.Ptr:
+					if !omitempty {
+						field = map[string]interface{}{"anyOf": []interface{}{field, map[string]interface{}{"type": "null"}}}
+					}
+				}
+				properties[name] = field
+				if !omitempty {
+					required = append(required, name)
+				}
+			}
+		}
+		walk(t)
+		s["required"] = required
+	}
+
+	schema := typeSchema(root, nil)
+	for _, t := range extra {
+		define(t)
+	}
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = root.Name()
+	schema["$defs"] = defs
+	return schema
+}
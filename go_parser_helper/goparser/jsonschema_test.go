@@ -0,0 +1,242 @@
+package goparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// validate checks doc against schema, resolving $ref against defs, and
+// returns one message per violation. It covers the keywords JSONSchema
+// emits: type, enum, required, properties, additionalProperties, items
+// and anyOf.
+func validate(defs map[string]interface{}, schema map[string]interface{}, doc interface{}, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return validate(defs, defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{}), doc, path)
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		var first []string
+		for i, alt := range anyOf {
+			errs := validate(defs, alt.(map[string]interface{}), doc, path)
+			if len(errs) == 0 {
+				return nil
+			}
+			if i == 0 {
+				first = errs
+			}
+		}
+		return first
+	}
+	if typ, ok := schema["type"].(string); ok && !hasJSONType(doc, typ) {
+		return []string{fmt.Sprintf("%s: expected %s, got %T", path, typ, doc)}
+	}
+	var errs []string
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, v := range enum {
+			found = found || v == doc
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %v is not one of %v", path, doc, enum))
+		}
+	}
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := doc[name.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required %s", path, name))
+			}
+		}
+		keys := make([]string, 0, len(doc))
+		for k := range doc {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if property, ok := properties[k]; ok {
+				errs = append(errs, validate(defs, property.(map[string]interface{}), doc[k], path+"."+k)...)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					errs = append(errs, fmt.Sprintf("%s: unexpected property %s", path, k))
+				}
+			case map[string]interface{}:
+				errs = append(errs, validate(defs, extra, doc[k], path+"."+k)...)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range doc {
+				errs = append(errs, validate(defs, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+func hasJSONType(doc interface{}, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := doc.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := doc.([]interface{})
+		return ok
+	case "string":
+		_, ok := doc.(string)
+		return ok
+	case "boolean":
+		_, ok := doc.(bool)
+		return ok
+	case "null":
+		return doc == nil
+	case "number":
+		_, ok := doc.(json.Number)
+		return ok
+	case "integer":
+		n, ok := doc.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	}
+	return false
+}
+
+// decodeJSON round-trips v through encoding/json into generic values,
+// keeping numbers exact so integers can be told from floats.
+func decodeJSON(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// checkedInSchema loads parse_result.schema.json and returns it with its
+// $defs.
+func checkedInSchema(t *testing.T) (map[string]interface{}, map[string]interface{}) {
+	t.Helper()
+	data, err := os.ReadFile("../parse_result.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema, schema["$defs"].(map[string]interface{})
+}
+
+func TestOutputMatchesSchema(t *testing.T) {
+	schema, defs := checkedInSchema(t)
+	single, err := Parse(sampleContract, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(single.Findings) == 0 {
+		t.Fatal("sample produced no findings to validate")
+	}
+	multi, err := ParseFiles([]string{sampleContract}, ParseOptions{}, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		def string
+		v   interface{}
+	}{
+		{"ParseResult", single},
+		{"QuietResult", single.Quiet()},
+		{"DirResult", multi},
+		{"QuietDirResult", multi.Quiet()},
+	} {
+		root := defs[tc.def].(map[string]interface{})
+		if tc.def == "ParseResult" {
+			root = schema
+		}
+		for _, msg := range validate(defs, root, decodeJSON(t, tc.v), "$") {
+			t.Errorf("%s: %s", tc.def, msg)
+		}
+	}
+}
+
+func TestSchemaRejectsInvalidOutput(t *testing.T) {
+	schema, defs := checkedInSchema(t)
+	result, err := Parse(sampleContract, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		mutate func(doc map[string]interface{})
+		want   string
+	}{
+		{"missing required key", func(doc map[string]interface{}) {
+			delete(doc, "package_name")
+		}, "$: missing required package_name"},
+		{"value outside enum", func(doc map[string]interface{}) {
+			doc["findings"].([]interface{})[0].(map[string]interface{})["severity"] = "severe"
+		}, "$.findings[0].severity: severe is not one of"},
+		{"unknown property", func(doc map[string]interface{}) {
+			doc["findings"].([]interface{})[0].(map[string]interface{})["extra"] = true
+		}, "$.findings[0]: unexpected property extra"},
+		{"wrong type", func(doc map[string]interface{}) {
+			doc["functions"].([]interface{})[0].(map[string]interface{})["line_start"] = json.Number("1.5")
+		}, "$.functions[0].line_start: expected integer"},
+	} {
+		doc := decodeJSON(t, result).(map[string]interface{})
+		tc.mutate(doc)
+		errs := validate(defs, schema, doc, "$")
+		if len(errs) != 1 || !strings.HasPrefix(errs[0], tc.want) {
+			t.Errorf("%s: got %q, want one error starting %q", tc.name, errs, tc.want)
+		}
+	}
+}
+
+func TestSchemaMatchesCheckedIn(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the command")
+	}
+	want, err := os.ReadFile("../parse_result.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "run", "..", "-schema")
+	cmd.Stderr = os.Stderr
+	got, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("go run .. -schema: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("parse_result.schema.json is stale; run go generate ./...")
+	}
+
+	// The command must describe the same types the library does.
+	schema, err := json.MarshalIndent(JSONSchema(reflect.TypeOf(ParseResult{}), reflect.TypeOf(DirResult{}), reflect.TypeOf(QuietResult{}), reflect.TypeOf(QuietDirResult{})), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(schema)+"\n" != string(got) {
+		t.Error("go run .. -schema differs from JSONSchema of the result types")
+	}
+}
@@ -0,0 +1,125 @@
+package goparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"go/build/constraint"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ManifestEntry is one source file listed in a -manifest file. Entries may be
+// given as a bare path or as {"file": ..., "tags": [...]}.
+type ManifestEntry struct {
+	File string   `json:"file"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+func (e *ManifestEntry) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		e.Tags = nil
+		return json.Unmarshal(data, &e.File)
+	}
+	type entry ManifestEntry
+	return json.Unmarshal(data, (*entry)(e))
+}
+
+// LoadManifest reads a JSON array of manifest entries.
+func LoadManifest(path string) ([]ManifestEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []ManifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %v", path, err)
+	}
+	for i, entry := range entries {
+		if entry.File == "" {
+			return nil, fmt.Errorf("invalid manifest %s: entry %d has no file", path, i)
+		}
+	}
+	return entries, nil
+}
+
+// ParseManifest parses exactly the files listed, keyed by their manifest
+// path. Files whose build constraints are not satisfied by their entry's
+// tags are left out, as the build would leave them out. A file that cannot
+// be read, or whose build constraints do not parse, is reported in its own
+// Errors. Each file is read once, after its size is checked against
+// opts.MaxBytes; an oversized file is reported without its constraints
+// being evaluated.
+func ParseManifest(entries []ManifestEntry, opts ParseOptions, cfg Config) (*DirResult, error) {
+	files := map[string]*ParseResult{}
+	for _, entry := range entries {
+		path := filepath.ToSlash(entry.File)
+		if opts.MaxBytes > 0 {
+			if info, err := os.Stat(entry.File); err == nil && info.Size() > opts.MaxBytes {
+				files[path] = failedResult(&sizeLimitError{limit: opts.MaxBytes})
+				continue
+			}
+		}
+		source, err := os.ReadFile(entry.File)
+		if err != nil {
+			files[path] = failedRead(fmt.Errorf("failed to read file: %v", err))
+			continue
+		}
+		ok, err := buildConstraintsSatisfied(source, entry.Tags)
+		if err != nil {
+			files[path] = failedRead(fmt.Errorf("invalid build constraint: %v", err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		files[path] = parseSourceRecovered(entry.File, source, opts, cfg)
+	}
+	return newDirResult(files), nil
+}
+
+// buildConstraintsSatisfied evaluates the //go:build (or legacy // +build)
+// lines of a file's header with tags set, in addition to the host GOOS,
+// GOARCH, compiler and release tags.
+func buildConstraintsSatisfied(source []byte, tags []string) (bool, error) {
+	enabled := map[string]bool{runtime.GOOS: true, runtime.GOARCH: true, runtime.Compiler: true}
+	if runtime.GOOS != "windows" && runtime.GOOS != "plan9" && runtime.GOOS != "js" && runtime.GOOS != "wasip1" {
+		enabled["unix"] = true
+	}
+	for _, tag := range build.Default.ReleaseTags {
+		enabled[tag] = true
+	}
+	for _, tag := range tags {
+		enabled[tag] = true
+	}
+	hasTag := func(tag string) bool { return enabled[tag] }
+
+	var plusBuild []constraint.Expr
+	for _, line := range bytes.Split(source, []byte("\n")) {
+		text := strings.TrimSpace(string(line))
+		if text == "" || (strings.HasPrefix(text, "//") && !constraint.IsGoBuild(text) && !constraint.IsPlusBuild(text)) {
+			continue
+		}
+		if !strings.HasPrefix(text, "//") {
+			break
+		}
+		expr, err := constraint.Parse(text)
+		if err != nil {
+			return false, err
+		}
+		if constraint.IsGoBuild(text) {
+			// //go:build takes precedence over any // +build lines.
+			return expr.Eval(hasTag), nil
+		}
+		plusBuild = append(plusBuild, expr)
+	}
+	for _, expr := range plusBuild {
+		if !expr.Eval(hasTag) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,61 @@
+package goparser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// manifestTree writes each named source into a temporary directory and
+// returns manifest entries for them in the order given.
+func manifestTree(t *testing.T, sources ...string) []ManifestEntry {
+	t.Helper()
+	dir := t.TempDir()
+	var entries []ManifestEntry
+	for i := 0; i+1 < len(sources); i += 2 {
+		path := filepath.Join(dir, sources[i])
+		if err := os.WriteFile(path, []byte(sources[i+1]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, ManifestEntry{File: path})
+	}
+	return entries
+}
+
+func TestParseManifestBadConstraint(t *testing.T) {
+	entries := manifestTree(t,
+		"good.go", "package p\n\nfunc Good() {}\n",
+		"bad.go", "//go:build linux &&\n\npackage p\n",
+	)
+	result, err := ParseManifest(entries, ParseOptions{}, DefaultConfig())
+	if err != nil {
+		t.Fatalf("a malformed constraint failed the whole manifest: %v", err)
+	}
+	good := result.Files[filepath.ToSlash(entries[0].File)]
+	if good == nil || len(good.Functions) != 1 {
+		t.Errorf("good.go was not parsed: %+v", good)
+	}
+	bad := result.Files[filepath.ToSlash(entries[1].File)]
+	if bad == nil || len(bad.Errors) != 1 || !strings.HasPrefix(bad.Errors[0], "invalid build constraint") {
+		t.Errorf("bad.go: got %+v, want an invalid build constraint error", bad)
+	}
+}
+
+func TestParseManifestMaxBytes(t *testing.T) {
+	entries := manifestTree(t,
+		"small.go", "package p\n",
+		"large.go", "package p\n\n"+strings.Repeat("// padding\n", 20),
+	)
+	result, err := ParseManifest(entries, ParseOptions{MaxBytes: 64}, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if small := result.Files[filepath.ToSlash(entries[0].File)]; small == nil || len(small.Errors) != 0 {
+		t.Errorf("small.go: got %+v, want a clean result", small)
+	}
+	large := result.Files[filepath.ToSlash(entries[1].File)]
+	if large == nil || len(large.Errors) != 1 || !strings.HasPrefix(large.Errors[0], "Input too large") {
+		t.Errorf("large.go: got %+v, want an input too large error", large)
+	}
+}
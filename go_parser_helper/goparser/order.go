@@ -0,0 +1,68 @@
+package goparser
+
+import (
+	"reflect"
+	"sort"
+)
+
+// orderKeys are the fields entries are ordered by, in priority order: the
+// line, then the name, then the column. The first field an element type
+// has of each group is used; Issue-based findings are named by rule.
+var orderKeys = [][]string{
+	{"LineStart", "Line"},
+	{"Name", "RuleID"},
+	{"ColStart", "Column"},
+}
+
+// SortByPosition reorders every slice of r whose entries carry a line (the
+// declarations, imports and each findings slice) by line, then name, then
+// column. The sort is stable, so entries equal on all three keep their
+// relative order and identical input always sorts the same way. Without it
+// each slice is in the order its detector found entries, which is source
+// order within a slice. Nested lists such as struct fields are left alone.
+func (r *ParseResult) SortByPosition() {
+	rv := reflect.ValueOf(r).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Field(i)
+		if field.Kind() != reflect.Slice || field.Type().Elem().Kind() != reflect.Struct {
+			continue
+		}
+		var keys [][]int
+		for _, group := range orderKeys {
+			for _, name := range group {
+				if f, ok := field.Type().Elem().FieldByName(name); ok {
+					keys = append(keys, f.Index)
+					break
+				}
+			}
+		}
+		if len(keys) == 0 || field.Type().Elem().FieldByIndex(keys[0]).Type.Kind() != reflect.Int {
+			continue
+		}
+		sort.SliceStable(field.Interface(), func(a, b int) bool {
+			ea, eb := field.Index(a), field.Index(b)
+			for _, key := range keys {
+				ka, kb := ea.FieldByIndex(key), eb.FieldByIndex(key)
+				switch ka.Kind() {
+				case reflect.Int:
+					if ka.Int() != kb.Int() {
+						return ka.Int() < kb.Int()
+					}
+				case reflect.String:
+					if ka.String() != kb.String() {
+						return ka.String() < kb.String()
+					}
+				}
+			}
+			return false
+		})
+	}
+}
+
+// SortByPosition sorts each file's result as ParseResult.SortByPosition
+// does.
+func (d *DirResult) SortByPosition() {
+	for _, result := range d.Files {
+		result.SortByPosition()
+	}
+}
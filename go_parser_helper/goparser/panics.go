@@ -0,0 +1,302 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// PanicSource is one statement that can panic at run time.
+type PanicSource struct {
+	// Kind is "type_assertion", "index", "nil_map_write", "panic" or
+	// "divide".
+	Kind      string `json:"kind" proto:"1"`
+	Expr      string `json:"expr" proto:"2"`
+	Function  string `json:"function" proto:"3"`
+	LineStart int    `json:"line_start" proto:"4"`
+}
+
+// panicSources lists the statements in fn's own body that can panic:
+// single-value type assertions, slice or array indexing, writes to a nil
+// local map, explicit panic calls and division by a non-constant.
+func (v *GoVisitor) panicSources(file *ast.File, fn *ast.FuncDecl) []PanicSource {
+	var sources []PanicSource
+	if fn.Body == nil {
+		return sources
+	}
+	name := funcDisplayName(fn)
+	add := func(kind string, n ast.Node) {
+		sources = append(sources, PanicSource{Kind: kind, Expr: v.nodeText(n), Function: name, LineStart: v.line(n)})
+	}
+	scope := v.newMapScope(file, fn)
+
+	// Comma-ok assertions, maps declared without a value and generic
+	// instantiations are found first so the main walk can tell them apart.
+	checked := map[*ast.TypeAssertExpr]bool{}
+	nilMaps := map[string]bool{}
+	instantiations := map[*ast.IndexExpr]bool{}
+	inspectBody(fn.Body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.CallExpr:
+			// f[T](x) instantiates a generic local function; it does not index.
+			if index, ok := s.Fun.(*ast.IndexExpr); ok {
+				if id, ok := index.X.(*ast.Ident); ok {
+					if generic := v.funcs.funcs[id.Name]; generic != nil && generic.Type.TypeParams != nil {
+						instantiations[index] = true
+					}
+				}
+			}
+		case *ast.AssignStmt:
+			if len(s.Lhs) == 2 && len(s.Rhs) == 1 {
+				if ta, ok := s.Rhs[0].(*ast.TypeAssertExpr); ok {
+					checked[ta] = true
+				}
+			}
+		case *ast.ValueSpec:
+			if len(s.Names) == 2 && len(s.Values) == 1 {
+				if ta, ok := s.Values[0].(*ast.TypeAssertExpr); ok {
+					checked[ta] = true
+				}
+			}
+			if _, ok := s.Type.(*ast.MapType); ok && len(s.Values) == 0 {
+				for _, id := range s.Names {
+					nilMaps[id.Name] = true
+				}
+			}
+		}
+		return true
+	})
+
+	inspectBody(fn.Body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.TypeAssertExpr:
+			if s.Type != nil && !checked[s] {
+				add("type_assertion", s)
+			}
+		case *ast.AssignStmt:
+			for _, lhs := range s.Lhs {
+				index, ok := lhs.(*ast.IndexExpr)
+				if !ok {
+					continue
+				}
+				if id, ok := index.X.(*ast.Ident); ok && nilMaps[id.Name] {
+					add("nil_map_write", s)
+				}
+			}
+			for i, lhs := range s.Lhs {
+				// A map is no longer nil once something is assigned to it.
+				if id, ok := lhs.(*ast.Ident); ok && i < len(s.Rhs) {
+					delete(nilMaps, id.Name)
+				}
+			}
+			if (s.Tok == token.QUO_ASSIGN || s.Tok == token.REM_ASSIGN) && !isNonZeroConst(s.Rhs[0]) {
+				add("divide", s)
+			}
+		case *ast.IndexExpr:
+			if !scope.isMap(s.X) && !instantiations[s] {
+				add("index", s)
+			}
+		case *ast.CallExpr:
+			if id, ok := s.Fun.(*ast.Ident); ok && id.Name == "panic" {
+				add("panic", s)
+			}
+		case *ast.BinaryExpr:
+			if (s.Op == token.QUO || s.Op == token.REM) && !isNonZeroConst(s.Y) {
+				add("divide", s)
+			}
+		}
+		return true
+	})
+	return sources
+}
+
+// isNonZeroConst reports whether expr is a numeric literal other than zero.
+func isNonZeroConst(expr ast.Expr) bool {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || (lit.Kind != token.INT && lit.Kind != token.FLOAT) {
+		return false
+	}
+	for _, c := range lit.Value {
+		if c >= '1' && c <= '9' {
+			return true
+		}
+	}
+	return false
+}
+
+// computePanicSurface attaches to every exported function the panic sources
+// reachable from it through calls to functions declared in the file, the
+// function's own first and then its callees breadth-first.
+func (v *GoVisitor) computePanicSurface(file *ast.File) {
+	own := map[*ast.FuncDecl][]PanicSource{}
+	var decls []*ast.FuncDecl
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			own[fn] = v.panicSources(file, fn)
+			decls = append(decls, fn)
+		}
+	}
+
+	parsed := map[string]*ParsedFunction{}
+	for i := range v.result.Functions {
+		f := &v.result.Functions[i]
+		parsed[fmt.Sprintf("%s:%d", f.Name, f.LineStart)] = f
+	}
+
+	for _, fn := range decls {
+		if !fn.Name.IsExported() {
+			continue
+		}
+		target := parsed[fmt.Sprintf("%s:%d", fn.Name.Name, v.line(fn))]
+		if target == nil {
+			continue
+		}
+		surface := []PanicSource{}
+		visited := map[*ast.FuncDecl]bool{fn: true}
+		queue := []*ast.FuncDecl{fn}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			surface = append(surface, own[current]...)
+			inspectBody(current.Body, func(n ast.Node) bool {
+				if call, ok := n.(*ast.CallExpr); ok {
+					if callee := v.funcs.resolve(call, current); callee != nil && !visited[callee] {
+						visited[callee] = true
+						queue = append(queue, callee)
+					}
+				}
+				return true
+			})
+		}
+		target.PanicSurface = surface
+	}
+}
+
+// closureSuffix matches the part of a closure's name that follows its
+// declaring function: .func1, .func1.2.
+var closureSuffix = regexp.MustCompile(`\.func\d+(\.\d+)*$`)
+
+// markReachablePanics runs a breadth-first search over Calls from every
+// exported function at once and marks each panic whose declaring function
+// it reaches, recording the shortest chain.
+func (v *GoVisitor) markReachablePanics() {
+	callees := map[string][]string{}
+	for _, edge := range v.result.Calls {
+		callees[edge.Caller] = append(callees[edge.Caller], edge.Callee)
+	}
+	// from maps each reached function to its predecessor on a shortest
+	// chain, "" for the exported entry points themselves.
+	from := map[string]string{}
+	var queue []string
+	for _, fn := range v.result.Functions {
+		if name := fn.displayName(); fn.IsExported {
+			if _, ok := from[name]; !ok {
+				from[name] = ""
+				queue = append(queue, name)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, callee := range callees[current] {
+			if _, ok := from[callee]; !ok {
+				from[callee] = current
+				queue = append(queue, callee)
+			}
+		}
+	}
+
+	for i := range v.result.Panics {
+		p := &v.result.Panics[i]
+		name := closureSuffix.ReplaceAllString(p.EnclosingFunction, "")
+		if _, ok := from[name]; !ok || name == "" {
+			continue
+		}
+		p.ReachableFromExported = true
+		for ; name != ""; name = from[name] {
+			p.CallChain = append([]string{name}, p.CallChain...)
+		}
+	}
+}
+
+// BoundsRisk is an index into a caller-supplied slice at a caller-supplied
+// position with no length check before it.
+type BoundsRisk struct {
+	Issue   `proto:"1"`
+	Indexed string `json:"indexed" proto:"9"`
+}
+
+// detectUncheckedIndexing flags x[i] where x is a slice or array parameter
+// and i is a parameter or arithmetic on one, unless an earlier if in the
+// function compares i against len(x).
+func (v *GoVisitor) detectUncheckedIndexing(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || fn.Type.Params == nil {
+			continue
+		}
+		slices, params := map[string]bool{}, map[string]bool{}
+		for _, field := range fn.Type.Params.List {
+			typ := v.typeToString(field.Type)
+			for _, name := range field.Names {
+				params[name.Name] = true
+				if strings.HasPrefix(typ, "[") || strings.HasPrefix(typ, "...") {
+					slices[name.Name] = true
+				}
+			}
+		}
+		if len(slices) == 0 {
+			continue
+		}
+		// refsParam returns a parameter other than slice that expr uses.
+		refsParam := func(expr ast.Expr, slice string) string {
+			ref := ""
+			ast.Inspect(expr, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && params[id.Name] && id.Name != slice && ref == "" {
+					ref = id.Name
+				}
+				return ref == ""
+			})
+			return ref
+		}
+		// guarded holds "slice/index" pairs compared by an if seen so far.
+		guarded := map[string]bool{}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.IfStmt:
+				for _, cmp := range comparisons(s.Cond) {
+					for _, pair := range [][2]ast.Expr{{cmp.X, cmp.Y}, {cmp.Y, cmp.X}} {
+						if name := lenOf(pair[1]); name != "" {
+							if index := refsParam(pair[0], name); index != "" {
+								guarded[name+"/"+index] = true
+							}
+						}
+					}
+				}
+			case *ast.IndexExpr:
+				id, ok := s.X.(*ast.Ident)
+				if !ok || !slices[id.Name] {
+					return true
+				}
+				index := refsParam(s.Index, id.Name)
+				if index == "" || guarded[id.Name+"/"+index] {
+					return true
+				}
+				v.result.BoundsRisks = append(v.result.BoundsRisks, BoundsRisk{
+					Issue: Issue{
+						RuleID:   "QLK-UNCHECKED-INDEX",
+						Severity: SeverityMedium,
+						Message:  fmt.Sprintf("%s is indexed by caller-supplied %s without a length check and can panic", id.Name, index),
+						Function: funcDisplayName(fn),
+						Span:     v.span(s),
+					},
+					Indexed: id.Name,
+				})
+			}
+			return true
+		})
+	}
+}
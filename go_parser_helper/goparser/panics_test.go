@@ -0,0 +1,42 @@
+package goparser
+
+import "testing"
+
+func TestUncheckedIndex(t *testing.T) {
+	checkRule(t, "QLK-UNCHECKED-INDEX", []ruleCase{
+		{"index from a parameter", `package p
+
+func Pick(items []string, i int) string {
+	return items[i]
+}
+`, []int{4}},
+		{"arithmetic on a parameter", `package p
+
+func Next(items []string, i int) string {
+	return items[i+1]
+}
+`, []int{4}},
+		{"length checked first", `package p
+
+func Pick(items []string, i int) string {
+	if i >= len(items) {
+		return ""
+	}
+	return items[i]
+}
+`, nil},
+		{"constant or local index", `package p
+
+func First(items []string) string {
+	last := len(items) - 1
+	return items[0] + items[last]
+}
+`, nil},
+		{"map parameter", `package p
+
+func Pick(items map[int]string, i int) string {
+	return items[i]
+}
+`, nil},
+	})
+}
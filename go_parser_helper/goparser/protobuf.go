@@ -0,0 +1,266 @@
+package goparser
+
+//go:generate sh -c "go run .. -proto-schema > ../parse_result.proto"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The protobuf encoding is derived from the Go result types by reflection so
+// it cannot drift from the JSON output: each message's fields are the
+// json-tagged fields, numbered by their proto tag, with embedded structs such
+// as Issue flattened into the enclosing message. An embedded struct's tag is
+// the number of its first field, and its own fields are numbered from 1
+// relative to it. Numbers are part of the wire format: a new field takes the
+// next unused number, and a removed field's number goes into protoReserved
+// rather than being reused. ProtoSchema renders the matching .proto;
+// regenerate parse_result.proto with go generate whenever a result type
+// changes.
+
+// protoReserved lists, by message, the numbers of removed fields, which are
+// never assigned again.
+var protoReserved = map[string][]int{}
+
+// protoField is one field of a generated message.
+type protoField struct {
+	name   string
+	number int
+	index  []int
+	typ    reflect.Type
+}
+
+// protoFields lists the wire fields of struct type t. It fails when a field
+// has no proto tag or two fields, or a field and a reserved number, share a
+// number.
+func protoFields(t reflect.Type) ([]protoField, error) {
+	var fields []protoField
+	numbered := map[int]string{}
+	for _, number := range protoReserved[t.Name()] {
+		numbered[number] = "reserved"
+	}
+	var walk func(t reflect.Type, index []int, base int) error
+	walk = func(t reflect.Type, index []int, base int) error {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			path := append(append([]int{}, index...), i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			embedded := f.Anonymous && f.Type.Kind() == reflect.Struct
+			if !embedded && (!f.IsExported() || name == "-") {
+				continue
+			}
+			number, err := strconv.Atoi(f.Tag.Get("proto"))
+			if err != nil || number <= 0 {
+				return fmt.Errorf("%s.%s has no valid proto tag", t.Name(), f.Name)
+			}
+			number += base
+			if embedded {
+				if err := walk(f.Type, path, number-1); err != nil {
+					return err
+				}
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if other, ok := numbered[number]; ok {
+				return fmt.Errorf("%s.%s and %s both use proto number %d", t.Name(), name, other, number)
+			}
+			numbered[number] = name
+			fields = append(fields, protoField{name: name, number: number, index: path, typ: f.Type})
+		}
+		return nil
+	}
+	if err := walk(t, nil, 0); err != nil {
+		return nil, err
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].number < fields[j].number })
+	return fields, nil
+}
+
+// protoMessages returns the message types reachable from roots, in the
+// order the .proto lists them, after checking that each is numbered
+// correctly.
+func protoMessages(roots ...reflect.Type) ([]reflect.Type, error) {
+	var messages []reflect.Type
+	seen := map[reflect.Type]bool{}
+	queue := append([]reflect.Type{}, roots...)
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		fields, err := protoFields(t)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, t)
+		for _, f := range fields {
+			_, nested := protoTypeName(f.typ)
+			queue = append(queue, nested...)
+		}
+	}
+	return messages, nil
+}
+
+// MarshalProto encodes a result (a pointer to one of the result structs) in
+// the protobuf binary wire format.
+func MarshalProto(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot encode %s as protobuf", rv.Type())
+	}
+	if _, err := protoMessages(rv.Type()); err != nil {
+		return nil, err
+	}
+	return appendMessage(nil, rv), nil
+}
+
+// appendMessage encodes a struct whose type protoMessages has checked.
+func appendMessage(buf []byte, v reflect.Value) []byte {
+	fields, _ := protoFields(v.Type())
+	for _, f := range fields {
+		buf = appendField(buf, f.number, v.FieldByIndex(f.index))
+	}
+	return buf
+}
+
+func appendTag(buf []byte, number int, wireType uint64) []byte {
+	return binary.AppendUvarint(buf, uint64(number)<<3|wireType)
+}
+
+func appendBytes(buf []byte, number int, data []byte) []byte {
+	buf = appendTag(buf, number, 2)
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+// appendField encodes one field. Zero scalars are omitted as proto3 does;
+// repeated scalars are packed; map entries are sorted by key so the output
+// is deterministic.
+func appendField(buf []byte, number int, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() > 0 {
+			buf = appendBytes(buf, number, []byte(v.String()))
+		}
+	case reflect.Bool:
+		if v.Bool() {
+			buf = appendTag(buf, number, 0)
+			buf = append(buf, 1)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() != 0 {
+			buf = appendTag(buf, number, 0)
+			buf = binary.AppendUvarint(buf, uint64(v.Int()))
+		}
+	case reflect.Float32, reflect.Float64:
+		if v.Float() != 0 {
+			buf = appendTag(buf, number, 1)
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.Float()))
+		}
+	case reflect.Struct:
+		buf = appendBytes(buf, number, appendMessage(nil, v))
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			buf = appendField(buf, number, v.Elem())
+		}
+	case reflect.Slice:
+		switch v.Type().Elem().Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.Len() == 0 {
+				break
+			}
+			var packed []byte
+			for i := 0; i < v.Len(); i++ {
+				packed = binary.AppendUvarint(packed, uint64(v.Index(i).Int()))
+			}
+			buf = appendBytes(buf, number, packed)
+		case reflect.String:
+			// Repeated strings keep empty elements, unlike singular ones.
+			for i := 0; i < v.Len(); i++ {
+				buf = appendBytes(buf, number, []byte(v.Index(i).String()))
+			}
+		default:
+			for i := 0; i < v.Len(); i++ {
+				buf = appendField(buf, number, v.Index(i))
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			entry := appendField(nil, 1, key)
+			entry = appendField(entry, 2, v.MapIndex(key))
+			buf = appendBytes(buf, number, entry)
+		}
+	}
+	return buf
+}
+
+// ProtoSchema renders the .proto definitions for the given root result
+// types and every message they reference. It fails, rather than renumber
+// anything, when a field is missing its proto tag or reuses a number.
+func ProtoSchema(roots ...reflect.Type) (string, error) {
+	messages, err := protoMessages(roots...)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("// Code generated by go_parser_helper -proto-schema. DO NOT EDIT.\n\n")
+	b.WriteString("syntax = \"proto3\";\n\npackage qlk.goparser;\n")
+	for _, t := range messages {
+		fmt.Fprintf(&b, "\nmessage %s {\n", t.Name())
+		if reserved := protoReserved[t.Name()]; len(reserved) > 0 {
+			numbers := make([]string, len(reserved))
+			for i, number := range reserved {
+				numbers[i] = strconv.Itoa(number)
+			}
+			fmt.Fprintf(&b, "  reserved %s;\n", strings.Join(numbers, ", "))
+		}
+		fields, _ := protoFields(t)
+		for _, f := range fields {
+			typ, _ := protoTypeName(f.typ)
+			fmt.Fprintf(&b, "  %s %s = %d;\n", typ, f.name, f.number)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String(), nil
+}
+
+// protoTypeName returns the .proto type of a Go field type, together with
+// any message types it references.
+func protoTypeName(t reflect.Type) (string, []reflect.Type) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return protoTypeName(t.Elem())
+	case reflect.Slice:
+		elem, nested := protoTypeName(t.Elem())
+		return "repeated " + elem, nested
+	case reflect.Map:
+		key, _ := protoTypeName(t.Key())
+		value, nested := protoTypeName(t.Elem())
+		return fmt.Sprintf("map<%s, %s>", key, value), nested
+	case reflect.Struct:
+		return t.Name(), []reflect.Type{t}
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "bool", nil
+	case reflect.Float32, reflect.Float64:
+		return "double", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int64", nil
+	}
+	return "bytes", nil
+}
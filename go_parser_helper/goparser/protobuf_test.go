@@ -0,0 +1,248 @@
+package goparser
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleContract is the repository's vulnerable Cosmos contract, which
+// exercises most detectors.
+const sampleContract = "../../test_vulnerable_contract.go"
+
+// wireValue is one decoded field occurrence: a varint, a fixed64 or a
+// length-delimited payload.
+type wireValue struct {
+	varint uint64
+	bytes  []byte
+}
+
+// decodeWire splits a message into its field occurrences by number.
+func decodeWire(t *testing.T, buf []byte) map[int][]wireValue {
+	t.Helper()
+	fields := map[int][]wireValue{}
+	for len(buf) > 0 {
+		tag, n := binary.Uvarint(buf)
+		if n <= 0 {
+			t.Fatalf("bad tag")
+		}
+		buf = buf[n:]
+		number, wireType := int(tag>>3), tag&7
+		switch wireType {
+		case 0:
+			v, n := binary.Uvarint(buf)
+			if n <= 0 {
+				t.Fatalf("field %d: bad varint", number)
+			}
+			buf = buf[n:]
+			fields[number] = append(fields[number], wireValue{varint: v})
+		case 1:
+			fields[number] = append(fields[number], wireValue{varint: binary.LittleEndian.Uint64(buf)})
+			buf = buf[8:]
+		case 2:
+			size, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < size {
+				t.Fatalf("field %d: bad length", number)
+			}
+			fields[number] = append(fields[number], wireValue{bytes: buf[n : n+int(size)]})
+			buf = buf[n+int(size):]
+		default:
+			t.Fatalf("field %d: unexpected wire type %d", number, wireType)
+		}
+	}
+	return fields
+}
+
+// checkDecoded compares the encoding of struct v, decoded from buf, with v
+// field by field.
+func checkDecoded(t *testing.T, path string, v reflect.Value, buf []byte) {
+	t.Helper()
+	fields, err := protoFields(v.Type())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := decodeWire(t, buf)
+	known := map[int]bool{}
+	for _, f := range fields {
+		known[f.number] = true
+		checkValue(t, path+"."+f.name, v.FieldByIndex(f.index), decoded[f.number])
+	}
+	for number := range decoded {
+		if !known[number] {
+			t.Errorf("%s: unexpected field number %d", path, number)
+		}
+	}
+}
+
+func checkValue(t *testing.T, path string, v reflect.Value, got []wireValue) {
+	t.Helper()
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			if len(got) != 0 {
+				t.Errorf("%s: nil encoded %d times", path, len(got))
+			}
+			return
+		}
+		checkValue(t, path, v.Elem(), got)
+	case reflect.String:
+		want := 0
+		if v.Len() > 0 {
+			want = 1
+		}
+		if len(got) != want || want == 1 && string(got[0].bytes) != v.String() {
+			t.Errorf("%s: got %v, want %q", path, got, v.String())
+		}
+	case reflect.Bool:
+		if v.Bool() != (len(got) == 1 && got[0].varint == 1) {
+			t.Errorf("%s: got %v, want %v", path, got, v.Bool())
+		}
+	case reflect.Int, reflect.Int64:
+		if v.Int() == 0 && len(got) != 0 || v.Int() != 0 && (len(got) != 1 || int64(got[0].varint) != v.Int()) {
+			t.Errorf("%s: got %v, want %d", path, got, v.Int())
+		}
+	case reflect.Float64:
+		if v.Float() == 0 && len(got) != 0 || v.Float() != 0 && (len(got) != 1 || math.Float64frombits(got[0].varint) != v.Float()) {
+			t.Errorf("%s: got %v, want %v", path, got, v.Float())
+		}
+	case reflect.Struct:
+		if len(got) != 1 {
+			t.Errorf("%s: message encoded %d times", path, len(got))
+			return
+		}
+		checkDecoded(t, path, v, got[0].bytes)
+	case reflect.Slice:
+		switch v.Type().Elem().Kind() {
+		case reflect.Int, reflect.Int64:
+			var ints []int64
+			for _, packed := range got {
+				for buf := packed.bytes; len(buf) > 0; {
+					n, size := binary.Uvarint(buf)
+					ints = append(ints, int64(n))
+					buf = buf[size:]
+				}
+			}
+			if len(ints) != v.Len() {
+				t.Errorf("%s: got %d ints, want %d", path, len(ints), v.Len())
+				return
+			}
+			for i, n := range ints {
+				if n != v.Index(i).Int() {
+					t.Errorf("%s[%d]: got %d, want %d", path, i, n, v.Index(i).Int())
+				}
+			}
+		case reflect.String:
+			if len(got) != v.Len() {
+				t.Errorf("%s: got %d strings, want %d", path, len(got), v.Len())
+				return
+			}
+			for i := range got {
+				if string(got[i].bytes) != v.Index(i).String() {
+					t.Errorf("%s[%d]: got %q, want %q", path, i, got[i].bytes, v.Index(i).String())
+				}
+			}
+		default:
+			if len(got) != v.Len() {
+				t.Errorf("%s: got %d elements, want %d", path, len(got), v.Len())
+				return
+			}
+			for i := range got {
+				elem := v.Index(i)
+				for elem.Kind() == reflect.Ptr {
+					elem = elem.Elem()
+				}
+				checkDecoded(t, path, elem, got[i].bytes)
+			}
+		}
+	case reflect.Map:
+		if len(got) != v.Len() {
+			t.Errorf("%s: got %d entries, want %d", path, len(got), v.Len())
+		}
+	}
+}
+
+func TestMarshalProtoRoundTrip(t *testing.T) {
+	result, err := Parse(sampleContract, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Findings) == 0 {
+		t.Fatal("sample produced no findings to encode")
+	}
+	buf, err := MarshalProto(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, "ParseResult", reflect.ValueOf(result).Elem(), buf)
+}
+
+func TestProtoSchemaMatchesCheckedIn(t *testing.T) {
+	want, err := os.ReadFile("../parse_result.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ProtoSchema(reflect.TypeOf(ParseResult{}), reflect.TypeOf(DirResult{}), reflect.TypeOf(QuietResult{}), reflect.TypeOf(QuietDirResult{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Error("parse_result.proto is stale; run go generate ./...")
+	}
+}
+
+func TestProtoNumbersAreChecked(t *testing.T) {
+	type untagged struct {
+		A string `json:"a" proto:"1"`
+		B string `json:"b"`
+	}
+	type duplicate struct {
+		A string `json:"a" proto:"1"`
+		B string `json:"b" proto:"1"`
+	}
+	type embedded struct {
+		Span `proto:"1"`
+		Name string `json:"name" proto:"2"`
+	}
+	type reserved struct {
+		A string `json:"a" proto:"2"`
+	}
+	protoReserved["reserved"] = []int{2}
+	defer delete(protoReserved, "reserved")
+
+	for _, tc := range []struct {
+		typ  reflect.Type
+		want string
+	}{
+		{reflect.TypeOf(untagged{}), "untagged.B has no valid proto tag"},
+		{reflect.TypeOf(duplicate{}), "both use proto number 1"},
+		{reflect.TypeOf(embedded{}), "both use proto number 2"},
+		{reflect.TypeOf(reserved{}), "reserved both use proto number 2"},
+	} {
+		_, err := ProtoSchema(tc.typ)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got error %v, want %q", tc.typ.Name(), err, tc.want)
+		}
+		if _, err := MarshalProto(reflect.New(tc.typ).Interface()); err == nil {
+			t.Errorf("%s: MarshalProto accepted a badly numbered type", tc.typ.Name())
+		}
+	}
+}
+
+func TestProtoReservedNumbersAreRendered(t *testing.T) {
+	type message struct {
+		A string `json:"a" proto:"1"`
+		C string `json:"c" proto:"3"`
+	}
+	protoReserved["message"] = []int{2}
+	defer delete(protoReserved, "message")
+	schema, err := ProtoSchema(reflect.TypeOf(message{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(schema, "message message {\n  reserved 2;\n  string a = 1;\n  string c = 3;\n}") {
+		t.Errorf("unexpected schema:\n%s", schema)
+	}
+}
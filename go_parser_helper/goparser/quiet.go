@@ -0,0 +1,42 @@
+package goparser
+
+// QuietResult is the -quiet form of a ParseResult: the findings and errors
+// without the structural dump.
+type QuietResult struct {
+	PackageName        string       `json:"package_name" proto:"1"`
+	Findings           []Finding    `json:"findings" proto:"2"`
+	TimedOut           bool         `json:"timed_out,omitempty" proto:"3"`
+	Suppressed         int          `json:"suppressed,omitempty" proto:"4"`
+	ParseErrors        []ParseError `json:"parse_errors,omitempty" proto:"5"`
+	ParseErrorsOmitted int          `json:"parse_errors_omitted,omitempty" proto:"6"`
+	Errors             []string     `json:"errors" proto:"7"`
+}
+
+// QuietDirResult is the -quiet form of a DirResult.
+type QuietDirResult struct {
+	Files  map[string]*QuietResult `json:"files" proto:"1"`
+	Errors []string                `json:"errors" proto:"2"`
+}
+
+// Quiet trims r to its findings and errors.
+func (r *ParseResult) Quiet() *QuietResult {
+	return &QuietResult{
+		PackageName:        r.PackageName,
+		Findings:           r.Findings,
+		TimedOut:           r.TimedOut,
+		Suppressed:         r.Suppressed,
+		ParseErrors:        r.ParseErrors,
+		ParseErrorsOmitted: r.ParseErrorsOmitted,
+		Errors:             r.Errors,
+	}
+}
+
+// Quiet trims every file of d to its findings and errors, keeping d's own
+// Errors and dropping the module-level risk summary and merged view.
+func (d *DirResult) Quiet() *QuietDirResult {
+	files := make(map[string]*QuietResult, len(d.Files))
+	for path, result := range d.Files {
+		files[path] = result.Quiet()
+	}
+	return &QuietDirResult{Files: files, Errors: d.Errors}
+}
@@ -0,0 +1,160 @@
+package goparser
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// cyclomaticComplexity counts the decision points of a function body: if,
+// for and range statements, non-default case clauses, && and ||, and every
+// return nested inside a branch, starting from a base of 1. Functions
+// without a body score 0.
+func cyclomaticComplexity(body *ast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+	topLevel := map[ast.Stmt]bool{}
+	for _, stmt := range body.List {
+		topLevel[stmt] = true
+	}
+	complexity := 1
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if s.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if s.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if s.Op == token.LAND || s.Op == token.LOR {
+				complexity++
+			}
+		case *ast.ReturnStmt:
+			if !topLevel[s] {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// nestingDepth returns the deepest chain of nested if, for, range, switch,
+// select and bare block statements in body. An else branch sits at the
+// depth of its if, and function literals are not entered.
+func nestingDepth(body *ast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+	var stmtsDepth func(stmts []ast.Stmt) int
+	var stmtDepth func(stmt ast.Stmt) int
+	stmtsDepth = func(stmts []ast.Stmt) int {
+		deepest := 0
+		for _, stmt := range stmts {
+			deepest = max(deepest, stmtDepth(stmt))
+		}
+		return deepest
+	}
+	stmtDepth = func(stmt ast.Stmt) int {
+		switch s := stmt.(type) {
+		case *ast.BlockStmt:
+			return 1 + stmtsDepth(s.List)
+		case *ast.LabeledStmt:
+			return stmtDepth(s.Stmt)
+		case *ast.IfStmt:
+			depth := 1 + stmtsDepth(s.Body.List)
+			switch e := s.Else.(type) {
+			case *ast.IfStmt:
+				depth = max(depth, stmtDepth(e))
+			case *ast.BlockStmt:
+				depth = max(depth, 1+stmtsDepth(e.List))
+			}
+			return depth
+		case *ast.ForStmt:
+			return 1 + stmtsDepth(s.Body.List)
+		case *ast.RangeStmt:
+			return 1 + stmtsDepth(s.Body.List)
+		case *ast.SwitchStmt:
+			return 1 + stmtsDepth(s.Body.List)
+		case *ast.TypeSwitchStmt:
+			return 1 + stmtsDepth(s.Body.List)
+		case *ast.SelectStmt:
+			return 1 + stmtsDepth(s.Body.List)
+		case *ast.CaseClause:
+			return stmtsDepth(s.Body)
+		case *ast.CommClause:
+			return stmtsDepth(s.Body)
+		}
+		return 0
+	}
+	return stmtsDepth(body.List)
+}
+
+// severityWeight returns the configured points for a finding severity.
+func (w RiskWeights) severityWeight(severity string) int {
+	switch severity {
+	case SeverityCritical:
+		return w.Critical
+	case SeverityHigh:
+		return w.High
+	case SeverityMedium:
+		return w.Medium
+	case SeverityLow:
+		return w.Low
+	default:
+		return w.Info
+	}
+}
+
+// computeRiskScore combines weighted finding counts, complex functions and
+// high-risk patterns into a single 0-100 triage score.
+func (v *GoVisitor) computeRiskScore(file *ast.File) {
+	weights := v.config.RiskWeights
+	points := 0
+	for _, issue := range v.result.issues() {
+		if !v.config.ruleEnabled(issue.RuleID) {
+			continue
+		}
+		points += weights.severityWeight(v.severityOf(issue))
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		if cyclomaticComplexity(fn.Body) > weights.ComplexityThreshold {
+			points += weights.Complexity
+		}
+		ast.Inspect(fn, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if id, ok := call.Fun.(*ast.Ident); ok && id.Name == "panic" {
+					points += weights.Panic
+				}
+			}
+			return true
+		})
+	}
+
+	for _, finding := range v.result.MessageValidation {
+		if finding.Kind == "permissive_signers" {
+			points += weights.MissingAuth
+		}
+	}
+	if v.result.FeaturesUsed.Unsafe {
+		points += weights.Unsafe
+	}
+	if v.result.FeaturesUsed.Reflection {
+		points += weights.Reflection
+	}
+
+	if points > 100 {
+		points = 100
+	}
+	v.result.RiskScore = points
+}
@@ -0,0 +1,676 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// contextParams returns the names of parameters typed as a context
+// (context.Context, sdk.Context or any other *.Context).
+func (v *GoVisitor) contextParams(ft *ast.FuncType) []string {
+	var names []string
+	if ft.Params == nil {
+		return names
+	}
+	for _, field := range ft.Params.List {
+		if baseTypeName(v.typeToString(field.Type)) != "Context" {
+			continue
+		}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+	}
+	return names
+}
+
+// isStopSignal reports whether a received-from channel expression is a
+// cancellation signal: ctx.Done() or a channel named like done/quit/stop.
+func isStopSignal(ch ast.Expr) bool {
+	if call, ok := ch.(*ast.CallExpr); ok {
+		return calleeName(call) == "Done"
+	}
+	name := ""
+	switch c := ch.(type) {
+	case *ast.Ident:
+		name = c.Name
+	case *ast.SelectorExpr:
+		name = c.Sel.Name
+	}
+	name = strings.ToLower(name)
+	for _, marker := range []string{"done", "quit", "stop", "exit", "clos", "shutdown", "cancel"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// commChannel returns the channel a select case receives from, or nil for
+// send cases and default.
+func commChannel(comm ast.Stmt) ast.Expr {
+	var expr ast.Expr
+	switch c := comm.(type) {
+	case *ast.ExprStmt:
+		expr = c.X
+	case *ast.AssignStmt:
+		if len(c.Rhs) == 1 {
+			expr = c.Rhs[0]
+		}
+	}
+	if recv, ok := expr.(*ast.UnaryExpr); ok && recv.Op == token.ARROW {
+		return recv.X
+	}
+	return nil
+}
+
+// isContextName reports whether an identifier conventionally holds a
+// context: ctx, sdkCtx, goCtx and the like.
+func isContextName(name string) bool {
+	return name == "ctx" || strings.HasSuffix(name, "Ctx")
+}
+
+// classifyGoroutine records the cancellation signals a go statement's call
+// passes or, for a func literal, refers to, and marks func literals that
+// loop forever without any of them as leak suspects. A for {} loop only
+// counts as forever when nothing in its body can leave it.
+func (v *GoVisitor) classifyGoroutine(call *ast.CallExpr, g *ParsedGoroutine) {
+	nodes := make([]ast.Node, 0, len(call.Args)+1)
+	for _, arg := range call.Args {
+		if isStopSignal(arg) {
+			g.HasStopChannel = true
+		}
+		nodes = append(nodes, arg)
+	}
+	lit, isLit := call.Fun.(*ast.FuncLit)
+	if isLit {
+		nodes = append(nodes, lit.Body)
+		if len(v.contextParams(lit.Type)) > 0 {
+			g.HasContext = true
+		}
+		if lit.Type.Params != nil {
+			for _, field := range lit.Type.Params.List {
+				if baseTypeName(v.typeToString(field.Type)) == "WaitGroup" {
+					g.HasWaitGroup = true
+				}
+			}
+		}
+	}
+
+	loops := false
+	labels := map[*ast.ForStmt]string{}
+	for _, node := range nodes {
+		ast.Inspect(node, func(n ast.Node) bool {
+			switch e := n.(type) {
+			case *ast.FuncLit:
+				// A nested goroutine's signals are its own.
+				return false
+			case *ast.LabeledStmt:
+				if loop, ok := e.Stmt.(*ast.ForStmt); ok {
+					labels[loop] = e.Label.Name
+				}
+			case *ast.Ident:
+				if isContextName(e.Name) {
+					g.HasContext = true
+				}
+			case *ast.UnaryExpr:
+				if e.Op == token.ARROW && isStopSignal(e.X) {
+					g.HasStopChannel = true
+				}
+			case *ast.CallExpr:
+				// wg.Done() is a call statement; ctx.Done() is received from.
+				if sel, ok := e.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Done" && len(e.Args) == 0 {
+					if id, ok := sel.X.(*ast.Ident); ok && !isContextName(id.Name) {
+						g.HasWaitGroup = true
+					}
+				}
+			case *ast.ForStmt:
+				if e.Cond == nil && !hasLoopExit(e, labels[e]) {
+					loops = true
+				}
+			}
+			return true
+		})
+	}
+	g.LeakSuspect = isLit && loops && !g.HasContext && !g.HasStopChannel && !g.HasWaitGroup
+}
+
+// hasLoopExit reports whether the body of an infinite loop, labelled label
+// or "", can leave it: by a return, a goto, or a break that targets it. An
+// unlabelled break inside a nested for, range, switch or select leaves only
+// that statement.
+func hasLoopExit(loop *ast.ForStmt, label string) bool {
+	exits := false
+	var walk func(node ast.Node, breaksLoop bool)
+	walk = func(node ast.Node, breaksLoop bool) {
+		ast.Inspect(node, func(n ast.Node) bool {
+			if exits {
+				return false
+			}
+			switch s := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.ReturnStmt:
+				exits = true
+			case *ast.BranchStmt:
+				switch {
+				case s.Tok == token.GOTO:
+					exits = true
+				case s.Tok == token.BREAK && s.Label == nil:
+					exits = breaksLoop
+				case s.Tok == token.BREAK:
+					exits = s.Label.Name == label
+				}
+			case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+				if n != node {
+					walk(n, false)
+					return false
+				}
+			}
+			return true
+		})
+	}
+	walk(loop.Body, true)
+	return exits
+}
+
+// loopScope is a for or range loop and the iteration variables it declares.
+type loopScope struct {
+	loop ast.Stmt
+	vars []*ast.Ident
+}
+
+// enterLoop returns a copy of the visitor for walking loop, which declares
+// vars. The loop stack is copied so sibling loops do not see each other.
+func (v *GoVisitor) enterLoop(loop ast.Stmt, vars []*ast.Ident) *GoVisitor {
+	child := *v
+	child.loops = append(v.loops[:len(v.loops):len(v.loops)], loopScope{loop: loop, vars: vars})
+	return &child
+}
+
+// checkLoopCapture flags a go statement whose func literal refers to an
+// iteration variable of an enclosing loop instead of receiving it as an
+// argument. Identifiers are matched by resolved object, so a parameter or
+// local that shadows the variable is not a capture. Go 1.22 made loop
+// variables per-iteration, so the finding only holds for modules built
+// with an earlier language version.
+func (v *GoVisitor) checkLoopCapture(gs *ast.GoStmt) {
+	lit, ok := gs.Call.Fun.(*ast.FuncLit)
+	if !ok || len(v.loops) == 0 {
+		return
+	}
+	loopOf := map[*ast.Object]ast.Stmt{}
+	for _, scope := range v.loops {
+		for _, id := range scope.vars {
+			if id.Obj != nil {
+				loopOf[id.Obj] = scope.loop
+			}
+		}
+	}
+	reported := map[*ast.Object]bool{}
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Obj == nil || loopOf[id.Obj] == nil || reported[id.Obj] {
+			return true
+		}
+		reported[id.Obj] = true
+		finding := ConcurrencyFinding{
+			Issue: Issue{
+				RuleID:   "QLK-LOOP-VAR-CAPTURE",
+				Severity: SeverityMedium,
+				Message:  fmt.Sprintf("goroutine started in the loop on line %d captures loop variable %s; before Go 1.22 every iteration shares it, so the goroutine may see a later value. Pass %s as an argument", v.line(loopOf[id.Obj]), id.Name, id.Name),
+				Span:     v.span(id),
+			},
+			Kind:     "loop_capture",
+			Variable: id.Name,
+		}
+		if v.scope != nil {
+			finding.Function = v.scope.name
+		}
+		v.result.ConcurrencyFindings = append(v.result.ConcurrencyFindings, finding)
+		return true
+	})
+}
+
+// detectUncancellableLoops flags infinite for/select loops that never wait on
+// a cancellation signal although the function has a context to honour.
+func (v *GoVisitor) detectUncancellableLoops(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		if len(v.contextParams(fn.Type)) > 0 {
+			v.checkSelectLoops(fn, fn.Type, fn.Body)
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.FuncLit); ok && len(v.contextParams(lit.Type)) > 0 {
+				v.checkSelectLoops(fn, lit.Type, lit.Body)
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// contextFuncs are the context package functions whose first result is a
+// context.
+var contextFuncs = map[string]bool{
+	"Background": true, "TODO": true, "WithCancel": true, "WithCancelCause": true,
+	"WithDeadline": true, "WithDeadlineCause": true, "WithTimeout": true,
+	"WithTimeoutCause": true, "WithValue": true, "WithoutCancel": true,
+}
+
+// contextNames returns the names bound to a context in a function: its
+// context parameters and the locals assigned from the context package's
+// constructors, as in ctx, cancel := context.WithCancel(ctx).
+func (v *GoVisitor) contextNames(ft *ast.FuncType, body *ast.BlockStmt) map[string]bool {
+	names := map[string]bool{}
+	for _, name := range v.contextParams(ft) {
+		names[name] = true
+	}
+	// Func literals are included: their loops are checked with body's.
+	ast.Inspect(body, func(n ast.Node) bool {
+		as, ok := n.(*ast.AssignStmt)
+		if !ok || len(as.Rhs) != 1 || len(as.Lhs) == 0 {
+			return true
+		}
+		call, ok := as.Rhs[0].(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && contextFuncs[sel.Sel.Name] {
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "context" {
+				if id, ok := as.Lhs[0].(*ast.Ident); ok {
+					names[id.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// isContextCall reports whether call is ctx.method() on one of ctxNames.
+func isContextCall(call *ast.CallExpr, method string, ctxNames map[string]bool) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != method || len(call.Args) != 0 {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && ctxNames[id.Name]
+}
+
+// checkSelectLoops flags each infinite loop in body that selects without a
+// case receiving from ctx.Done() or a stop channel and never polls
+// ctx.Err(). A select inside nested infinite loops is reported once, for
+// the outermost loop that cannot be cancelled.
+func (v *GoVisitor) checkSelectLoops(fn *ast.FuncDecl, ft *ast.FuncType, body *ast.BlockStmt) {
+	ctxNames := v.contextNames(ft, body)
+	reported := map[token.Pos]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		loop, ok := n.(*ast.ForStmt)
+		if !ok || loop.Cond != nil {
+			return true
+		}
+		var selects []token.Pos
+		cancellable := false
+		ast.Inspect(loop.Body, func(m ast.Node) bool {
+			switch s := m.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.SelectStmt:
+				selects = append(selects, s.Pos())
+				for _, clause := range s.Body.List {
+					cc, ok := clause.(*ast.CommClause)
+					if !ok || cc.Comm == nil {
+						continue
+					}
+					ch := commChannel(cc.Comm)
+					if call, ok := ch.(*ast.CallExpr); ok {
+						cancellable = cancellable || isContextCall(call, "Done", ctxNames)
+					} else if ch != nil && isStopSignal(ch) {
+						cancellable = true
+					}
+				}
+			case *ast.CallExpr:
+				// Polling ctx.Err() is an accepted alternative to a Done case.
+				if isContextCall(s, "Err", ctxNames) {
+					cancellable = true
+				}
+			}
+			return true
+		})
+		if cancellable {
+			return true
+		}
+		fresh := false
+		for _, pos := range selects {
+			fresh = fresh || !reported[pos]
+			reported[pos] = true
+		}
+		if fresh {
+			v.result.UncancellableLoop = append(v.result.UncancellableLoop, Issue{
+				RuleID:   "QLK-UNCANCELLABLE-LOOP",
+				Severity: SeverityMedium,
+				Message:  "infinite select loop has no ctx.Done() or stop-channel case and cannot be cancelled",
+				Function: funcDisplayName(fn),
+				Span:     v.span(loop),
+			})
+		}
+		return true
+	})
+}
+
+var storeOps = map[string]bool{
+	"KVStore": true, "TransientStore": true, "Set": true, "Get": true, "Has": true,
+	"Delete": true, "Iterator": true, "ReverseIterator": true,
+}
+
+// sdkContexts returns the names bound to an sdk.Context in fn: parameters of
+// a non-stdlib *.Context type and locals from sdk.UnwrapSDKContext.
+func (v *GoVisitor) sdkContexts(fn *ast.FuncDecl) map[string]bool {
+	names := map[string]bool{}
+	if fn.Type.Params != nil {
+		for _, field := range fn.Type.Params.List {
+			typ := v.typeToString(field.Type)
+			if baseTypeName(typ) != "Context" || typ == "context.Context" {
+				continue
+			}
+			for _, name := range field.Names {
+				names[name.Name] = true
+			}
+		}
+	}
+	inspectBody(fn.Body, func(n ast.Node) bool {
+		as, ok := n.(*ast.AssignStmt)
+		if !ok || len(as.Lhs) != 1 || len(as.Rhs) != 1 {
+			return true
+		}
+		if call, ok := as.Rhs[0].(*ast.CallExpr); ok && calleeName(call) == "UnwrapSDKContext" {
+			if id, ok := as.Lhs[0].(*ast.Ident); ok {
+				names[id.Name] = true
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// storeAccess returns the first call in node that touches state through a
+// context: a store operation, or any call handed one of ctxNames.
+func storeAccess(node ast.Node, ctxNames map[string]bool) *ast.CallExpr {
+	var found *ast.CallExpr
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || found != nil {
+			return found == nil
+		}
+		if storeOps[calleeName(call)] {
+			found = call
+		}
+		for _, arg := range call.Args {
+			if id, ok := arg.(*ast.Ident); ok && ctxNames[id.Name] {
+				found = call
+			}
+		}
+		return found == nil
+	})
+	return found
+}
+
+// detectConcurrentContextUse flags go statements that hand an sdk.Context to
+// another goroutine, by capture or argument, which then reaches the store.
+// sdk.Context and the stores behind it are not safe for concurrent use.
+func (v *GoVisitor) detectConcurrentContextUse(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		ctxNames := v.sdkContexts(fn)
+		if len(ctxNames) == 0 {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			gs, ok := n.(*ast.GoStmt)
+			if !ok {
+				return true
+			}
+			passed := ""
+			for _, arg := range gs.Call.Args {
+				if id, ok := arg.(*ast.Ident); ok && ctxNames[id.Name] {
+					passed = id.Name
+				}
+			}
+
+			var access *ast.CallExpr
+			switch fun := gs.Call.Fun.(type) {
+			case *ast.FuncLit:
+				captured := map[string]bool{}
+				for name := range ctxNames {
+					captured[name] = true
+				}
+				for _, name := range v.contextParams(fun.Type) {
+					captured[name] = passed != ""
+				}
+				uses := false
+				ast.Inspect(fun.Body, func(n ast.Node) bool {
+					if id, ok := n.(*ast.Ident); ok && captured[id.Name] {
+						uses = true
+					}
+					return !uses
+				})
+				if uses {
+					access = storeAccess(fun.Body, captured)
+				}
+			default:
+				if passed == "" {
+					return true
+				}
+				access = gs.Call
+				if target := v.funcs.resolve(gs.Call, fn); target != nil && target.Body != nil {
+					access = storeAccess(target.Body, v.sdkContexts(target))
+				}
+			}
+			if access == nil {
+				return true
+			}
+			v.result.ConcurrentContextUse = append(v.result.ConcurrentContextUse, Issue{
+				RuleID:   "QLK-CONCURRENT-CTX",
+				Severity: SeverityHigh,
+				Message:  fmt.Sprintf("goroutine uses sdk.Context via %s; sdk.Context is not safe for concurrent use", v.nodeText(access.Fun)),
+				Function: funcDisplayName(fn),
+				Span:     v.span(gs),
+			})
+			return true
+		})
+	}
+}
+
+// ConcurrencyFinding is state reachable from several goroutines without a
+// lock: a field read or written before the method takes the struct's mutex,
+// a map used from a goroutine in a struct with no mutex, or a loop variable
+// a goroutine started in the loop closes over.
+type ConcurrencyFinding struct {
+	Issue `proto:"1"`
+	// Kind is "unlocked_access", "no_mutex" or "loop_capture".
+	Kind   string `json:"kind" proto:"9"`
+	Struct string `json:"struct,omitempty" proto:"10"`
+	Field  string `json:"field,omitempty" proto:"11"`
+	// Variable is the loop variable a loop_capture goroutine closes over.
+	Variable string `json:"variable,omitempty" proto:"12"`
+}
+
+// isMutexType reports whether a field type is a sync mutex, by value or
+// pointer.
+func isMutexType(typ string) bool {
+	typ = strings.TrimPrefix(typ, "*")
+	return typ == "sync.Mutex" || typ == "sync.RWMutex"
+}
+
+// isSelfSynchronized reports whether a field type is safe for concurrent
+// use on its own: channels and the sync and sync/atomic types.
+func isSelfSynchronized(typ string) bool {
+	typ = strings.TrimPrefix(typ, "*")
+	return strings.HasPrefix(typ, "sync.") || strings.HasPrefix(typ, "atomic.") ||
+		strings.HasPrefix(typ, "chan") || strings.HasPrefix(typ, "<-chan")
+}
+
+// receiverFieldRefs returns the selectors on recv in node naming one of
+// fields, in source order.
+func receiverFieldRefs(node ast.Node, recv string, fields map[string]bool) []*ast.SelectorExpr {
+	var refs []*ast.SelectorExpr
+	if recv == "" || recv == "_" {
+		return refs
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Name == recv && fields[sel.Sel.Name] {
+				refs = append(refs, sel)
+			}
+		}
+		return true
+	})
+	return refs
+}
+
+// detectMutexGaps flags two ways struct state escapes its lock. In structs
+// with a sync.Mutex or sync.RWMutex field, methods that touch another field
+// before calling Lock or RLock on it are reported; methods named *Locked
+// expect the caller to hold the lock and are skipped. In structs without a
+// mutex, map fields used from a goroutine started by one of the struct's
+// methods are reported.
+func (v *GoVisitor) detectMutexGaps(file *ast.File) {
+	methods := map[string]map[string]*ast.FuncDecl{}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil && fn.Body != nil {
+			typ := receiverTypeName(fn)
+			if methods[typ] == nil {
+				methods[typ] = map[string]*ast.FuncDecl{}
+			}
+			methods[typ][fn.Name.Name] = fn
+		}
+	}
+	recvName := func(fn *ast.FuncDecl) string {
+		if len(fn.Recv.List[0].Names) > 0 {
+			return fn.Recv.List[0].Names[0].Name
+		}
+		return ""
+	}
+	report := func(node ast.Node, fn *ast.FuncDecl, ruleID, kind, st, field, message string) {
+		v.result.ConcurrencyFindings = append(v.result.ConcurrencyFindings, ConcurrencyFinding{
+			Issue: Issue{
+				RuleID:   ruleID,
+				Severity: SeverityMedium,
+				Message:  message,
+				Function: funcDisplayName(fn),
+				Span:     v.span(node),
+			},
+			Kind:   kind,
+			Struct: st,
+			Field:  field,
+		})
+	}
+
+	for _, st := range v.result.Structs {
+		// locks are the names a lock call is made on: the mutex field, or
+		// the promoted Lock of an embedded mutex.
+		locks := map[string]bool{}
+		guarded := map[string]bool{}
+		maps := map[string]bool{}
+		for _, field := range st.Fields {
+			switch {
+			case isMutexType(field.Type) && field.IsEmbedded:
+				locks[""] = true
+			case isMutexType(field.Type):
+				locks[field.Name] = true
+			case field.Name != "" && !isSelfSynchronized(field.Type):
+				guarded[field.Name] = true
+				if strings.HasPrefix(field.Type, "map[") {
+					maps[field.Name] = true
+				}
+			}
+		}
+		names := make([]string, 0, len(methods[st.Name]))
+		for name := range methods[st.Name] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fn := methods[st.Name][name]
+			recv := recvName(fn)
+			if len(locks) > 0 {
+				if strings.HasSuffix(name, "Locked") {
+					continue
+				}
+				// firstLock is the position of the first Lock or RLock on
+				// one of the struct's mutexes.
+				firstLock := token.NoPos
+				ast.Inspect(fn.Body, func(n ast.Node) bool {
+					if firstLock.IsValid() {
+						return false
+					}
+					call, ok := n.(*ast.CallExpr)
+					if !ok {
+						return true
+					}
+					sel, ok := call.Fun.(*ast.SelectorExpr)
+					if !ok || (sel.Sel.Name != "Lock" && sel.Sel.Name != "RLock") {
+						return true
+					}
+					if id, ok := sel.X.(*ast.Ident); ok && id.Name == recv && locks[""] {
+						firstLock = call.Pos()
+					}
+					if field := receiverField(sel.X, recv); field != "" && locks[field] {
+						firstLock = call.Pos()
+					}
+					return true
+				})
+				seen := map[string]bool{}
+				for _, ref := range receiverFieldRefs(fn.Body, recv, guarded) {
+					field := ref.Sel.Name
+					if seen[field] || (firstLock.IsValid() && ref.Pos() > firstLock) {
+						continue
+					}
+					seen[field] = true
+					report(ref, fn, "QLK-UNLOCKED-ACCESS", "unlocked_access", st.Name, field,
+						fmt.Sprintf("%s uses %s.%s without holding the struct's mutex", funcDisplayName(fn), recv, field))
+				}
+				continue
+			}
+			if len(maps) == 0 {
+				continue
+			}
+			seen := map[string]bool{}
+			inspectBody(fn.Body, func(n ast.Node) bool {
+				g, ok := n.(*ast.GoStmt)
+				if !ok {
+					return true
+				}
+				refs := receiverFieldRefs(g.Call.Fun, recv, maps)
+				if sel, ok := g.Call.Fun.(*ast.SelectorExpr); ok {
+					if id, ok := sel.X.(*ast.Ident); ok && id.Name == recv {
+						if callee := methods[st.Name][sel.Sel.Name]; callee != nil {
+							refs = append(refs, receiverFieldRefs(callee.Body, recvName(callee), maps)...)
+						}
+					}
+				}
+				for _, ref := range refs {
+					field := ref.Sel.Name
+					if seen[field] {
+						continue
+					}
+					seen[field] = true
+					report(g, fn, "QLK-UNGUARDED-SHARED-MAP", "no_mutex", st.Name, field,
+						fmt.Sprintf("map %s.%s is used from a goroutine but %s has no mutex guarding it", st.Name, field, st.Name))
+				}
+				return true
+			})
+		}
+	}
+}
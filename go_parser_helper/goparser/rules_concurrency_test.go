@@ -0,0 +1,499 @@
+package goparser
+
+import "testing"
+
+func TestUncancellableLoop(t *testing.T) {
+	checkRule(t, "QLK-UNCANCELLABLE-LOOP", []ruleCase{
+		{"select without a done case", `package p
+
+import "context"
+
+func run(ctx context.Context, ch chan int) {
+	for {
+		select {
+		case <-ch:
+		}
+	}
+}
+`, []int{6}},
+		{"ctx.Done case", `package p
+
+import "context"
+
+func run(ctx context.Context, ch chan int) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+		}
+	}
+}
+`, nil},
+		{"derived context", `package p
+
+import (
+	"context"
+	"time"
+)
+
+func run(parent context.Context, ch chan int) {
+	ctx, cancel := context.WithTimeout(parent, time.Second)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+		}
+	}
+}
+`, nil},
+		{"polls ctx.Err", `package p
+
+import "context"
+
+func run(ctx context.Context, ch chan int) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-ch:
+		}
+	}
+}
+`, nil},
+		{"stop channel", `package p
+
+import "context"
+
+func run(ctx context.Context, ch chan int, quit chan struct{}) {
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ch:
+		}
+	}
+}
+`, nil},
+		{"Done and Err on other values", `package p
+
+import "context"
+
+type tracker interface {
+	Done() <-chan struct{}
+	Err() error
+}
+
+func run(ctx context.Context, ch chan int, t tracker) {
+	for {
+		if t.Err() != nil {
+			return
+		}
+		select {
+		case <-t.Done():
+		case <-ch:
+		}
+	}
+}
+`, []int{11}},
+		{"nested loops report one select once", `package p
+
+import "context"
+
+func run(ctx context.Context, ch chan int) {
+	for {
+		for {
+			select {
+			case <-ch:
+			}
+		}
+	}
+}
+`, []int{6}},
+		{"inner loop cannot reach the outer check", `package p
+
+import "context"
+
+func run(ctx context.Context, ch chan int) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		for {
+			select {
+			case <-ch:
+			}
+		}
+	}
+}
+`, []int{10}},
+		{"no context to honour", `package p
+
+func run(ch chan int) {
+	for {
+		select {
+		case <-ch:
+		}
+	}
+}
+`, nil},
+	})
+}
+
+func TestGoroutineLeak(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		body  string
+		leaks bool
+	}{
+		{"loops forever", `for {
+			work()
+		}`, true},
+		{"returns", `for {
+			if work() {
+				return
+			}
+		}`, false},
+		{"breaks out", `for {
+			if work() {
+				break
+			}
+		}`, false},
+		{"break only leaves the select", `for {
+			select {
+			case <-tick:
+				break
+			}
+		}`, true},
+		{"break only leaves the switch", `for {
+			switch {
+			case work():
+				break
+			}
+		}`, true},
+		{"labelled break from a select", `loop:
+		for {
+			select {
+			case <-tick:
+				break loop
+			}
+		}`, false},
+		{"labelled break aimed at an inner loop", `for {
+		inner:
+			for {
+				break inner
+			}
+		}`, true},
+		{"goto", `for {
+			if work() {
+				goto out
+			}
+		}
+	out:`, false},
+		{"return inside a nested func literal", `for {
+			func() { return }()
+		}`, true},
+		{"conditional loop", `for work() {
+		}`, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := parseFixture(t, `package p
+
+var tick chan int
+
+func work() bool { return false }
+
+func start() {
+	go func() {
+		`+tc.body+`
+	}()
+}
+`)
+			if len(result.Goroutines) != 1 {
+				t.Fatalf("got %d goroutines, want 1", len(result.Goroutines))
+			}
+			if got := result.Goroutines[0].LeakSuspect; got != tc.leaks {
+				t.Errorf("got LeakSuspect %v, want %v", got, tc.leaks)
+			}
+			if got := len(findingsFor(result, "QLK-GOROUTINE-LEAK")) == 1; got != tc.leaks {
+				t.Errorf("got a QLK-GOROUTINE-LEAK finding %v, want %v", got, tc.leaks)
+			}
+		})
+	}
+}
+
+func TestGoroutineLeakSignals(t *testing.T) {
+	for _, tc := range []struct {
+		name, src string
+	}{
+		{"context parameter", `package p
+
+import "context"
+
+func start(ctx context.Context) {
+	go func(ctx context.Context) {
+		for {
+		}
+	}(ctx)
+}
+`},
+		{"stop channel", `package p
+
+func start(quit chan struct{}) {
+	go func() {
+		for {
+			<-quit
+		}
+	}()
+}
+`},
+		{"wait group", `package p
+
+import "sync"
+
+func start(wg *sync.WaitGroup) {
+	go func() {
+		defer wg.Done()
+		for {
+		}
+	}()
+}
+`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := findingsFor(parseFixture(t, tc.src), "QLK-GOROUTINE-LEAK"); len(got) != 0 {
+				t.Errorf("got %d findings, want none", len(got))
+			}
+		})
+	}
+}
+
+func TestConcurrentContext(t *testing.T) {
+	checkRule(t, "QLK-CONCURRENT-CTX", []ruleCase{
+		{"captured context reaches the store", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type Keeper struct{ key string }
+
+func (k Keeper) Sync(ctx sdk.Context) {
+	go func() {
+		ctx.KVStore(k.key).Set([]byte("a"), nil)
+	}()
+}
+`, []int{8}},
+		{"context passed to a method that writes", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type Keeper struct{ key string }
+
+func (k Keeper) write(ctx sdk.Context) {
+	ctx.KVStore(k.key).Set([]byte("a"), nil)
+}
+
+func (k Keeper) Sync(ctx sdk.Context) {
+	go k.write(ctx)
+}
+`, []int{12}},
+		{"goroutine does not use the context", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type Keeper struct{ key string }
+
+func (k Keeper) Sync(ctx sdk.Context, done chan bool) {
+	ctx.KVStore(k.key).Set([]byte("a"), nil)
+	go func() {
+		done <- true
+	}()
+}
+`, nil},
+		{"standard library context", `package p
+
+import "context"
+
+type Store interface{ Set(k, v []byte) }
+
+func Sync(ctx context.Context, s Store) {
+	go func() {
+		s.Set([]byte("a"), nil)
+		<-ctx.Done()
+	}()
+}
+`, nil},
+	})
+}
+
+func TestUnlockedAccess(t *testing.T) {
+	checkRule(t, "QLK-UNLOCKED-ACCESS", []ruleCase{
+		{"read before the lock", `package p
+
+import "sync"
+
+type Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *Counter) Inc() {
+	if c.n > 10 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.n++
+}
+`, []int{11}},
+		{"embedded mutex never locked", `package p
+
+import "sync"
+
+type Counter struct {
+	sync.Mutex
+	n int
+}
+
+func (c *Counter) Get() int {
+	return c.n
+}
+`, []int{11}},
+		{"locked first", `package p
+
+import "sync"
+
+type Counter struct {
+	mu sync.RWMutex
+	n  int
+}
+
+func (c *Counter) Get() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.n
+}
+`, nil},
+		{"Locked suffix expects the caller to hold it", `package p
+
+import "sync"
+
+type Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *Counter) incLocked() {
+	c.n++
+}
+`, nil},
+	})
+}
+
+func TestUnguardedSharedMap(t *testing.T) {
+	checkRule(t, "QLK-UNGUARDED-SHARED-MAP", []ruleCase{
+		{"map written from a goroutine", `package p
+
+type Cache struct {
+	items map[string]int
+}
+
+func (c *Cache) Warm(keys []string) {
+	go func() {
+		for _, k := range keys {
+			c.items[k] = 1
+		}
+	}()
+}
+`, []int{8}},
+		{"map used by a method started as a goroutine", `package p
+
+type Cache struct {
+	items map[string]int
+}
+
+func (c *Cache) fill() {
+	c.items["a"] = 1
+}
+
+func (c *Cache) Start() {
+	go c.fill()
+}
+`, []int{12}},
+		{"struct has a mutex", `package p
+
+import "sync"
+
+type Cache struct {
+	mu    sync.Mutex
+	items map[string]int
+}
+
+func (c *Cache) Warm() {
+	go func() {
+		c.mu.Lock()
+		c.items["a"] = 1
+		c.mu.Unlock()
+	}()
+}
+`, nil},
+		{"map used without a goroutine", `package p
+
+type Cache struct {
+	items map[string]int
+}
+
+func (c *Cache) Put(k string) {
+	c.items[k] = 1
+}
+`, nil},
+	})
+}
+
+func TestLoopVarCapture(t *testing.T) {
+	checkRule(t, "QLK-LOOP-VAR-CAPTURE", []ruleCase{
+		{"range variable captured", `package p
+
+func Start(items []string, out chan string) {
+	for _, item := range items {
+		go func() {
+			out <- item
+		}()
+	}
+}
+`, []int{6}},
+		{"for clause variable captured", `package p
+
+func Start(out chan int) {
+	for i := 0; i < 3; i++ {
+		go func() {
+			out <- i
+		}()
+	}
+}
+`, []int{6}},
+		{"passed as an argument", `package p
+
+func Start(items []string, out chan string) {
+	for _, item := range items {
+		go func(item string) {
+			out <- item
+		}(item)
+	}
+}
+`, nil},
+		{"copied per iteration", `package p
+
+func Start(items []string, out chan string) {
+	for _, item := range items {
+		item := item
+		go func() {
+			out <- item
+		}()
+	}
+}
+`, nil},
+	})
+}
@@ -0,0 +1,939 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+var eventEmitters = map[string]bool{
+	"EmitEvent":       true,
+	"EmitEvents":      true,
+	"EmitTypedEvent":  true,
+	"EmitTypedEvents": true,
+}
+
+// isValidatingCall reports whether a call name suggests its arguments are
+// checked (ValidateBasic, AccAddressFromBech32, ParseCoins, ...).
+func isValidatingCall(name string) bool {
+	for _, marker := range []string{"Valid", "Verify", "Check", "Parse", "Bech32", "Must"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// messageFieldRefs returns the message fields read inside node, keyed by
+// field name, ignoring method calls on the message itself.
+func messageFieldRefs(node ast.Node, msgParams map[string]bool) map[string]bool {
+	fields := map[string]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok && msgParams[id.Name] {
+					for _, arg := range call.Args {
+						for field := range messageFieldRefs(arg, msgParams) {
+							fields[field] = true
+						}
+					}
+					return false
+				}
+			}
+		}
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && msgParams[id.Name] {
+				fields[id.Name+"."+sel.Sel.Name] = true
+			}
+		}
+		return true
+	})
+	return fields
+}
+
+// detectEventInjection flags event attributes populated straight from message
+// fields that were never validated earlier in the handler.
+func (v *GoVisitor) detectEventInjection(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		msgParams := v.messageParams(fn)
+		if len(msgParams) == 0 {
+			continue
+		}
+
+		// Record where each message (or message field) is first validated.
+		validatedAt := map[string]token.Pos{}
+		markValidated := func(key string, pos token.Pos) {
+			if _, seen := validatedAt[key]; !seen {
+				validatedAt[key] = pos
+			}
+		}
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.CallExpr:
+				name := calleeName(s)
+				if sel, ok := s.Fun.(*ast.SelectorExpr); ok {
+					if id, ok := sel.X.(*ast.Ident); ok && msgParams[id.Name] && isValidatingCall(name) {
+						markValidated(id.Name, s.Pos())
+					}
+				}
+				if isValidatingCall(name) {
+					for _, arg := range s.Args {
+						for field := range messageFieldRefs(arg, msgParams) {
+							markValidated(field, s.Pos())
+						}
+					}
+				}
+			case *ast.IfStmt:
+				for field := range messageFieldRefs(s.Cond, msgParams) {
+					markValidated(field, s.Pos())
+				}
+			case *ast.SwitchStmt:
+				if s.Tag != nil {
+					for field := range messageFieldRefs(s.Tag, msgParams) {
+						markValidated(field, s.Pos())
+					}
+				}
+			}
+			return true
+		})
+		isValidated := func(field string, before token.Pos) bool {
+			msg := field[:strings.Index(field, ".")]
+			for _, key := range []string{msg, field} {
+				if pos, ok := validatedAt[key]; ok && pos < before {
+					return true
+				}
+			}
+			return false
+		}
+
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || !eventEmitters[calleeName(call)] {
+				return true
+			}
+			reported := map[string]bool{}
+			for _, arg := range call.Args {
+				for field := range messageFieldRefs(arg, msgParams) {
+					if reported[field] || isValidated(field, call.Pos()) {
+						continue
+					}
+					reported[field] = true
+					v.result.EventInjection = append(v.result.EventInjection, Issue{
+						RuleID:   "QLK-EVENT-INJECTION",
+						Severity: SeverityLow,
+						Message:  fmt.Sprintf("event attribute built from unvalidated message field %s in %s", field, calleeName(call)),
+						Function: funcDisplayName(fn),
+						Span:     v.span(call),
+					})
+				}
+			}
+			return true
+		})
+	}
+}
+
+// MessageValidationFinding is a message-level validation or authorization
+// gap. Kind names the specific problem, e.g. "permissive_signers".
+type MessageValidationFinding struct {
+	Issue       `proto:"1"`
+	MessageType string `json:"message_type" proto:"9"`
+	Kind        string `json:"kind" proto:"10"`
+}
+
+// isEmptyList reports whether expr is nil or an empty composite literal.
+func isEmptyList(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name == "nil"
+	case *ast.CompositeLit:
+		return len(e.Elts) == 0
+	}
+	return false
+}
+
+// detectPermissiveSigners flags GetSigners implementations that return
+// addresses not derived from the message's own fields, such as hardcoded or
+// package-level addresses.
+func (v *GoVisitor) detectPermissiveSigners(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || fn.Name.Name != "GetSigners" || fn.Recv == nil {
+			continue
+		}
+		recv := fn.Recv.List[0]
+
+		// derived holds the receiver plus every local computed from it.
+		derived := map[string]bool{}
+		if len(recv.Names) > 0 && recv.Names[0].Name != "_" {
+			derived[recv.Names[0].Name] = true
+		}
+		refsDerived := func(expr ast.Node) bool {
+			found := false
+			ast.Inspect(expr, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && derived[id.Name] {
+					found = true
+				}
+				return !found
+			})
+			return found
+		}
+		markDerived := func(exprs []ast.Expr) {
+			for _, expr := range exprs {
+				if id, ok := expr.(*ast.Ident); ok && id.Name != "_" {
+					derived[id.Name] = true
+				}
+			}
+		}
+
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.AssignStmt:
+				for _, rhs := range s.Rhs {
+					if refsDerived(rhs) {
+						markDerived(s.Lhs)
+						break
+					}
+				}
+			case *ast.RangeStmt:
+				if refsDerived(s.X) {
+					markDerived([]ast.Expr{s.Key, s.Value})
+				}
+			case *ast.ValueSpec:
+				for _, value := range s.Values {
+					if refsDerived(value) {
+						for _, name := range s.Names {
+							derived[name.Name] = true
+						}
+						break
+					}
+				}
+			case *ast.ReturnStmt:
+				for _, result := range s.Results {
+					if isEmptyList(result) || refsDerived(result) {
+						continue
+					}
+					msgType := receiverTypeName(fn)
+					v.result.MessageValidation = append(v.result.MessageValidation, MessageValidationFinding{
+						Issue: Issue{
+							RuleID:   "QLK-PERMISSIVE-SIGNERS",
+							Severity: SeverityHigh,
+							Message:  fmt.Sprintf("%s.GetSigners returns %s, which is not derived from a message field", msgType, v.nodeText(result)),
+							Function: funcDisplayName(fn),
+							Span:     v.span(s),
+						},
+						MessageType: msgType,
+						Kind:        "permissive_signers",
+					})
+					break
+				}
+			}
+			return true
+		})
+	}
+}
+
+// detectSignerGaps flags messages whose signers cannot be checked: a
+// GetSigners that returns nil or an empty list, which leaves the message
+// with no required signature, and message types that implement
+// ValidateBasic without GetSigners.
+func (v *GoVisitor) detectSignerGaps(file *ast.File) {
+	report := func(msgType, function, ruleID, severity, kind, message string, span Span) {
+		v.result.MessageValidation = append(v.result.MessageValidation, MessageValidationFinding{
+			Issue: Issue{
+				RuleID:   ruleID,
+				Severity: severity,
+				Message:  message,
+				Function: function,
+				Span:     span,
+			},
+			MessageType: msgType,
+			Kind:        kind,
+		})
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || fn.Name.Name != "GetSigners" || fn.Recv == nil {
+			continue
+		}
+		msgType := receiverTypeName(fn)
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			ret, ok := n.(*ast.ReturnStmt)
+			if ok && len(ret.Results) == 1 && isEmptyList(ret.Results[0]) {
+				report(msgType, funcDisplayName(fn), "QLK-EMPTY-SIGNERS", SeverityCritical, "empty_signers",
+					fmt.Sprintf("%s.GetSigners returns %s, so the message requires no signature", msgType, v.nodeText(ret.Results[0])),
+					v.span(ret))
+			}
+			return true
+		})
+	}
+
+	for _, st := range v.result.Structs {
+		if !isMessageTypeName(st.Name) {
+			continue
+		}
+		hasValidate, hasSigners := false, false
+		for _, method := range st.Methods {
+			hasValidate = hasValidate || method == "ValidateBasic"
+			hasSigners = hasSigners || method == "GetSigners"
+		}
+		if hasValidate && !hasSigners {
+			report(st.Name, "", "QLK-MISSING-GETSIGNERS", SeverityMedium, "missing_signers",
+				fmt.Sprintf("%s implements ValidateBasic but not GetSigners", st.Name),
+				Span{LineStart: st.LineStart, ColStart: st.ColStart})
+		}
+	}
+}
+
+// detectKeeperCoupling flags keeper fields holding a concrete *XKeeper
+// from another package instead of an "expected keepers" interface. A
+// pointer to a keeper of the same package is not a cross-module
+// dependency.
+func (v *GoVisitor) detectKeeperCoupling(file *ast.File) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok || !strings.HasSuffix(ts.Name.Name, "Keeper") {
+			return true
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok || st.Fields == nil {
+			return true
+		}
+		for _, field := range st.Fields.List {
+			star, ok := field.Type.(*ast.StarExpr)
+			if !ok {
+				continue
+			}
+			sel, ok := star.X.(*ast.SelectorExpr)
+			if !ok || !strings.HasSuffix(sel.Sel.Name, "Keeper") {
+				continue
+			}
+			typ := v.typeToString(sel)
+			name := typ
+			if len(field.Names) > 0 {
+				name = field.Names[0].Name
+			}
+			v.result.KeeperCoupling = append(v.result.KeeperCoupling, Issue{
+				RuleID:   "QLK-KEEPER-COUPLING",
+				Severity: SeverityLow,
+				Message:  fmt.Sprintf("%s.%s depends on concrete *%s; declare an expected-keeper interface instead", ts.Name.Name, name, typ),
+				Span:     v.span(field),
+			})
+		}
+		return true
+	})
+}
+
+// balanceMarkers are lower-cased substrings of field names and types that
+// indicate a keeper holds or moves funds.
+var balanceMarkers = []string{"balance", "supply", "coin", "bank", "escrow", "vault", "fund"}
+
+// balanceField returns the first field of st that looks like balance state.
+func (v *GoVisitor) balanceField(st *ast.StructType) *ast.Field {
+	for _, field := range st.Fields.List {
+		text := strings.ToLower(v.typeToString(field.Type))
+		for _, name := range field.Names {
+			text += " " + strings.ToLower(name.Name)
+		}
+		for _, marker := range balanceMarkers {
+			if strings.Contains(text, marker) {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+// detectMissingInvariants flags keepers holding balance-like state in files
+// that neither declare nor call RegisterInvariants/RegisterInvariant.
+func (v *GoVisitor) detectMissingInvariants(file *ast.File) {
+	registers := func(name string) bool {
+		return name == "RegisterInvariants" || name == "RegisterInvariant"
+	}
+	registered := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.FuncDecl:
+			registered = registered || registers(s.Name.Name)
+		case *ast.CallExpr:
+			registered = registered || registers(calleeName(s))
+		}
+		return !registered
+	})
+	if registered {
+		return
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok || !strings.HasSuffix(ts.Name.Name, "Keeper") {
+			return true
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok || st.Fields == nil {
+			return true
+		}
+		field := v.balanceField(st)
+		if field == nil {
+			return true
+		}
+		name := v.typeToString(field.Type)
+		if len(field.Names) > 0 {
+			name = field.Names[0].Name
+		}
+		v.result.InvariantIssues = append(v.result.InvariantIssues, Issue{
+			RuleID:   "QLK-MISSING-INVARIANT",
+			Severity: SeverityLow,
+			Message:  fmt.Sprintf("%s manages balance state (%s) but no invariant is registered for it", ts.Name.Name, name),
+			Span:     v.span(ts),
+		})
+		return true
+	})
+}
+
+// isGuardCall reports whether a call name suggests validation or an
+// authorization check. Unlike isValidatingCall it ignores case, so private
+// helpers such as requireAdmin count.
+func isGuardCall(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range []string{"valid", "verify", "check", "auth", "require", "ensure", "assert"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStateWriteCall reports whether a call name suggests a store or keeper
+// write (store.Set, k.SetBalance, bank.SendCoins, ...).
+func isStateWriteCall(name string) bool {
+	for _, prefix := range []string{"Set", "Delete", "Remove", "Mint", "Burn", "Send"} {
+		if name == prefix || (strings.HasPrefix(name, prefix) && ast.IsExported(name[len(prefix):])) {
+			return true
+		}
+	}
+	return false
+}
+
+// rootIdent returns the identifier at the base of a selector/index chain.
+func rootIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return e
+		case *ast.SelectorExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// detectValidationOrdering flags functions that write state before their
+// first validation or authorization call, breaking checks-before-effects.
+// Field writes count when rooted at the receiver or a non-message parameter.
+func (v *GoVisitor) detectValidationOrdering(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		msgParams := v.messageParams(fn)
+		stateRoots := map[string]bool{}
+		var fields []*ast.Field
+		if fn.Recv != nil {
+			fields = append(fields, fn.Recv.List...)
+		}
+		if fn.Type.Params != nil {
+			fields = append(fields, fn.Type.Params.List...)
+		}
+		for _, field := range fields {
+			for _, name := range field.Names {
+				if !msgParams[name.Name] {
+					stateRoots[name.Name] = true
+				}
+			}
+		}
+
+		var guard *ast.CallExpr
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok && guard == nil && isGuardCall(calleeName(call)) {
+				guard = call
+			}
+			return guard == nil
+		})
+		if guard == nil {
+			continue
+		}
+
+		var mutation ast.Node
+		describe := ""
+		isStateTarget := func(expr ast.Expr) bool {
+			if _, ok := expr.(*ast.Ident); ok {
+				return false
+			}
+			root := rootIdent(expr)
+			return root != nil && stateRoots[root.Name]
+		}
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			if mutation != nil || n == nil || n.Pos() >= guard.Pos() {
+				return false
+			}
+			switch s := n.(type) {
+			case *ast.AssignStmt:
+				for _, lhs := range s.Lhs {
+					if isStateTarget(lhs) {
+						mutation, describe = s, v.nodeText(lhs)
+						return false
+					}
+				}
+			case *ast.IncDecStmt:
+				if isStateTarget(s.X) {
+					mutation, describe = s, v.nodeText(s.X)
+					return false
+				}
+			case *ast.CallExpr:
+				if isStateWriteCall(calleeName(s)) {
+					mutation, describe = s, v.nodeText(s.Fun)
+					return false
+				}
+			}
+			return true
+		})
+		if mutation == nil || v.line(mutation) >= v.line(guard) {
+			continue
+		}
+		v.result.ValidationOrdering = append(v.result.ValidationOrdering, Issue{
+			RuleID:   "QLK-VALIDATION-ORDER",
+			Severity: SeverityHigh,
+			Message:  fmt.Sprintf("%s mutates state (%s) before its first check %s on line %d", funcDisplayName(fn), describe, v.nodeText(guard.Fun), v.line(guard)),
+			Function: funcDisplayName(fn),
+			Span:     v.span(mutation),
+		})
+	}
+}
+
+// isStubBody reports whether a validation body checks nothing: it is empty
+// or consists of a single "return nil".
+func isStubBody(body *ast.BlockStmt) bool {
+	if body == nil || len(body.List) == 0 {
+		return true
+	}
+	if len(body.List) != 1 {
+		return false
+	}
+	ret, ok := body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return false
+	}
+	id, ok := ret.Results[0].(*ast.Ident)
+	return ok && id.Name == "nil"
+}
+
+// detectGenesisValidation flags a GenesisState whose Validate method is
+// missing or a stub, and InitGenesis functions that apply state without
+// first calling a Validate/ValidateGenesis.
+func (v *GoVisitor) detectGenesisValidation(file *ast.File) {
+	var genesis *ast.TypeSpec
+	var validate *ast.FuncDecl
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.TypeSpec:
+			if _, ok := s.Type.(*ast.StructType); ok && s.Name.Name == "GenesisState" {
+				genesis = s
+			}
+		case *ast.FuncDecl:
+			if s.Name.Name == "Validate" && receiverTypeName(s) == "GenesisState" {
+				validate = s
+			}
+			return false
+		}
+		return true
+	})
+	report := func(n ast.Node, function, message string) {
+		v.result.GenesisValidationIssues = append(v.result.GenesisValidationIssues, Issue{
+			RuleID:   "QLK-GENESIS-VALIDATION",
+			Severity: SeverityMedium,
+			Message:  message,
+			Function: function,
+			Span:     v.span(n),
+		})
+	}
+	switch {
+	case genesis != nil && validate == nil:
+		report(genesis, "", "GenesisState has no Validate method; invalid genesis files are accepted")
+	case validate != nil && isStubBody(validate.Body):
+		report(validate, funcDisplayName(validate), "GenesisState.Validate is a stub and accepts any genesis state")
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || fn.Name.Name != "InitGenesis" {
+			continue
+		}
+		var validated, write *ast.CallExpr
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			name := calleeName(call)
+			if validated == nil && (name == "Validate" || name == "ValidateGenesis") {
+				validated = call
+			}
+			if write == nil && isStateWriteCall(name) {
+				write = call
+			}
+			return true
+		})
+		switch {
+		case validated == nil:
+			report(fn, funcDisplayName(fn), "InitGenesis applies genesis state without calling Validate")
+		case write != nil && write.Pos() < validated.Pos():
+			report(write, funcDisplayName(fn), fmt.Sprintf("InitGenesis writes state via %s before calling %s", v.nodeText(write.Fun), v.nodeText(validated.Fun)))
+		}
+	}
+}
+
+// StubValidationFinding is a validator or handler that returns an error
+// but never a non-nil one, so every input is accepted.
+type StubValidationFinding struct {
+	Issue `proto:"1"`
+	// Body is "empty" when the function returns straight away and
+	// "no_error_path" when it does work but no path returns an error.
+	Body string `json:"body" proto:"9"`
+}
+
+// isValidatorName reports whether name reads as validating or handling
+// its input.
+func isValidatorName(name string) bool {
+	return strings.HasPrefix(name, "Validate") || strings.HasPrefix(name, "Handle")
+}
+
+// neverFails reports whether every return in body, outside function
+// literals, has a literal nil as its last result. A bare return of named
+// results or a panic could still signal failure, so either one counts as
+// a way to fail.
+func neverFails(body *ast.BlockStmt) bool {
+	fails := false
+	inspectBody(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.ReturnStmt:
+			if len(s.Results) == 0 {
+				fails = true
+			} else if id, ok := s.Results[len(s.Results)-1].(*ast.Ident); !ok || id.Name != "nil" {
+				fails = true
+			}
+		case *ast.CallExpr:
+			if id, ok := s.Fun.(*ast.Ident); ok && id.Name == "panic" {
+				fails = true
+			}
+		}
+		return !fails
+	})
+	return !fails
+}
+
+// isReturn reports whether stmt is a return statement.
+func isReturn(stmt ast.Stmt) bool {
+	_, ok := stmt.(*ast.ReturnStmt)
+	return ok
+}
+
+// detectStubValidations flags Validate* and Handle* functions whose last
+// result is an error but that only ever return nil for it.
+// GenesisState.Validate is left to QLK-GENESIS-VALIDATION.
+func (v *GoVisitor) detectStubValidations(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || !isValidatorName(fn.Name.Name) || fn.Type.Results == nil {
+			continue
+		}
+		if fn.Name.Name == "Validate" && receiverTypeName(fn) == "GenesisState" {
+			continue
+		}
+		results := v.fieldTypes(fn.Type.Results)
+		if len(results) == 0 || results[len(results)-1] != "error" || !neverFails(fn.Body) {
+			continue
+		}
+		finding := StubValidationFinding{Body: "no_error_path"}
+		finding.Issue = Issue{
+			RuleID:   "QLK-STUB-VALIDATION",
+			Severity: SeverityMedium,
+			Message:  fmt.Sprintf("%s does work but never returns a non-nil error, so it accepts every input", funcDisplayName(fn)),
+			Function: funcDisplayName(fn),
+			Span:     v.span(fn),
+		}
+		if len(fn.Body.List) == 0 || isReturn(fn.Body.List[0]) {
+			finding.Body = "empty"
+			finding.Severity = SeverityHigh
+			finding.Message = fmt.Sprintf("%s returns a nil error without checking anything, so it is a stub that accepts every input", funcDisplayName(fn))
+		}
+		v.result.StubValidations = append(v.result.StubValidations, finding)
+	}
+}
+
+// AuthFinding is a keeper method that writes to the store without checking
+// who is calling.
+type AuthFinding struct {
+	Issue     `proto:"1"`
+	Receiver  string `json:"receiver" proto:"9"`
+	Operation string `json:"operation" proto:"10"`
+}
+
+// isAuthorityRef reports whether an identifier names who is calling or who
+// may: GetAuthority, an authority field, msg.Sender or GetSigners.
+func isAuthorityRef(name string) bool {
+	switch name {
+	case "GetAuthority", "authority", "Authority", "Sender":
+		return true
+	}
+	return strings.Contains(name, "Signer")
+}
+
+// mentions reports whether node contains an identifier that match accepts.
+func mentions(node ast.Node, match func(string) bool) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && match(id.Name) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// checksAuthority reports whether body checks the caller: an if comparing
+// an authority reference, a GetAuthority call, or a return of
+// ErrUnauthorized. Passing msg.Sender along as a key is not a check.
+func checksAuthority(body *ast.BlockStmt) bool {
+	unauthorized := func(name string) bool { return name == "ErrUnauthorized" }
+	found := false
+	inspectBody(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.IfStmt:
+			for _, cmp := range comparisons(s.Cond) {
+				found = found || mentions(cmp, isAuthorityRef)
+			}
+		case *ast.CallExpr:
+			found = found || calleeName(s) == "GetAuthority"
+		case *ast.ReturnStmt:
+			for _, result := range s.Results {
+				found = found || mentions(result, unauthorized)
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// detectMissingAuthorization flags Keeper methods calling store Set or
+// Delete with no authority check anywhere in the body, at the first such
+// call. The receiver must be a store as isStoreExpr sees it, so Set on a
+// big.Int or Delete on a sync.Map is not a write. Opening a KVStore only to
+// read from it is not flagged.
+func (v *GoVisitor) detectMissingAuthorization(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || !strings.Contains(receiverTypeName(fn), "Keeper") {
+			continue
+		}
+		// stores holds the locals assigned an opened store.
+		stores := map[string]bool{}
+		// write is the first store Set or Delete call.
+		var write *ast.CallExpr
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.AssignStmt:
+				for i, lhs := range s.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok && i < len(s.Rhs) {
+						if call, ok := s.Rhs[i].(*ast.CallExpr); ok && storeOpeners[calleeName(call)] {
+							stores[id.Name] = true
+						}
+					}
+				}
+			case *ast.CallExpr:
+				if sel, ok := s.Fun.(*ast.SelectorExpr); ok && write == nil && isStoreExpr(sel.X, stores) {
+					if sel.Sel.Name == "Set" || sel.Sel.Name == "Delete" {
+						write = s
+					}
+				}
+			}
+			return true
+		})
+		if write == nil || checksAuthority(fn.Body) {
+			continue
+		}
+		operation := v.nodeText(write.Fun)
+		v.result.AuthorizationFindings = append(v.result.AuthorizationFindings, AuthFinding{
+			Issue: Issue{
+				RuleID:   "QLK-MISSING-AUTH",
+				Severity: SeverityHigh,
+				Message:  fmt.Sprintf("%s calls %s without checking the caller's authority", funcDisplayName(fn), operation),
+				Function: funcDisplayName(fn),
+				Span:     v.span(write),
+			},
+			Receiver:  receiverTypeName(fn),
+			Operation: operation,
+		})
+	}
+}
+
+// ContextFinding is a keeper method that sidesteps the sdk.Context it is
+// given: it never reads the context, or it writes module state held in Go
+// maps, which the store's commit and rollback never see.
+type ContextFinding struct {
+	Issue `proto:"1"`
+	// Parameter is the name of the sdk.Context parameter, "" when the
+	// method takes none.
+	Parameter string `json:"parameter" proto:"9"`
+	Mutation  string `json:"mutation,omitempty" proto:"10"`
+}
+
+// isStateOwner reports whether a receiver type holds module state: a
+// keeper, or a contract type standing in for one.
+func isStateOwner(typ string) bool {
+	return strings.Contains(typ, "Keeper") || strings.Contains(typ, "Contract")
+}
+
+// directMapWrite returns the statement's target when it writes, resets or
+// deletes from a map held in a receiver field or a package variable.
+func directMapWrite(stmt ast.Node, recv string, scope *mapScope, globals map[string]bool) ast.Expr {
+	persistent := func(expr ast.Expr) bool {
+		for {
+			paren, ok := expr.(*ast.ParenExpr)
+			if !ok {
+				break
+			}
+			expr = paren.X
+		}
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return globals[e.Name]
+		case *ast.SelectorExpr:
+			id, ok := e.X.(*ast.Ident)
+			return ok && recv != "" && recv != "_" && id.Name == recv && scope.isMap(e)
+		}
+		return false
+	}
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		if s.Tok == token.DEFINE {
+			return nil
+		}
+		for _, lhs := range s.Lhs {
+			if index, ok := lhs.(*ast.IndexExpr); ok && persistent(index.X) {
+				return lhs
+			}
+			if persistent(lhs) {
+				return lhs
+			}
+		}
+	case *ast.IncDecStmt:
+		if index, ok := s.X.(*ast.IndexExpr); ok && persistent(index.X) {
+			return s.X
+		}
+	case *ast.CallExpr:
+		if id, ok := s.Fun.(*ast.Ident); ok && id.Name == "delete" && len(s.Args) == 2 && persistent(s.Args[0]) {
+			return s.Args[0]
+		}
+	}
+	return nil
+}
+
+// detectContextMisuse flags methods on keeper and contract receivers that
+// take an sdk.Context but never refer to it, and methods that write maps
+// held by the receiver or the package instead of going through the
+// context's KVStore.
+func (v *GoVisitor) detectContextMisuse(file *ast.File) {
+	_, globals := packageVars(file)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || !isStateOwner(receiverTypeName(fn)) {
+			continue
+		}
+		name := funcDisplayName(fn)
+		recv := ""
+		if len(fn.Recv.List[0].Names) > 0 {
+			recv = fn.Recv.List[0].Names[0].Name
+		}
+
+		// param is the first sdk.Context parameter; unnamed and blank
+		// parameters cannot be referenced and count as unused.
+		var param *ast.Field
+		paramName := ""
+		for _, field := range fn.Type.Params.List {
+			typ := v.typeToString(field.Type)
+			if baseTypeName(typ) != "Context" || typ == "context.Context" {
+				continue
+			}
+			param, paramName = field, "_"
+			if len(field.Names) > 0 {
+				paramName = field.Names[0].Name
+			}
+			break
+		}
+		if param != nil {
+			used := false
+			if paramName != "_" {
+				ast.Inspect(fn.Body, func(n ast.Node) bool {
+					if id, ok := n.(*ast.Ident); ok && id.Name == paramName {
+						used = true
+					}
+					return !used
+				})
+			}
+			if !used {
+				v.result.UnusedContextFindings = append(v.result.UnusedContextFindings, ContextFinding{
+					Issue: Issue{
+						RuleID:   "QLK-UNUSED-CONTEXT",
+						Severity: SeverityLow,
+						Message:  fmt.Sprintf("%s takes %s %s but never uses it", name, paramName, v.typeToString(param.Type)),
+						Function: name,
+						Span:     v.span(param),
+					},
+					Parameter: paramName,
+				})
+			}
+		}
+
+		scope := v.newMapScope(file, fn)
+		var stmt ast.Node
+		var target ast.Expr
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if target == nil {
+				if target = directMapWrite(n, recv, scope, globals); target != nil {
+					stmt = n
+				}
+			}
+			return target == nil
+		})
+		if target == nil {
+			continue
+		}
+		if paramName == "_" {
+			paramName = ""
+		}
+		v.result.UnusedContextFindings = append(v.result.UnusedContextFindings, ContextFinding{
+			Issue: Issue{
+				RuleID:   "QLK-DIRECT-STATE-WRITE",
+				Severity: SeverityMedium,
+				Message:  fmt.Sprintf("%s writes %s directly instead of through the context's KVStore, bypassing store commit and rollback", name, v.nodeText(target)),
+				Function: name,
+				Span:     v.span(stmt),
+			},
+			Parameter: paramName,
+			Mutation:  v.nodeText(target),
+		})
+	}
+}
@@ -0,0 +1,492 @@
+package goparser
+
+import "testing"
+
+// keeperFixture declares a Keeper whose SetCount method has body.
+func keeperFixture(body string) string {
+	return `package keeper
+
+import "errors"
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+type Store interface {
+	Get(key []byte) []byte
+	Set(key, value []byte)
+	Delete(key []byte)
+}
+
+type Keeper struct {
+	store     Store
+	authority string
+}
+
+func (k Keeper) SetCount(sender string, value []byte) error {
+	` + body + `
+	return nil
+}
+`
+}
+
+func TestMissingAuth(t *testing.T) {
+	result := parseFixture(t, keeperFixture(`k.store.Get([]byte("count"))
+	k.store.Set([]byte("count"), value)`))
+	found := findingsFor(result, "QLK-MISSING-AUTH")
+	if len(found) != 1 {
+		t.Fatalf("got %d findings, want 1", len(found))
+	}
+	// The span covers the Set call, not the method.
+	if f := found[0]; f.Line != 20 || f.Column != 2 || f.EndLine != 20 || f.EndColumn != 37 {
+		t.Errorf("got span %d:%d-%d:%d, want 20:2-20:37", f.Line, f.Column, f.EndLine, f.EndColumn)
+	}
+	if op := result.AuthorizationFindings[0].Operation; op != "k.store.Set" {
+		t.Errorf("got operation %q, want k.store.Set", op)
+	}
+}
+
+func TestMissingAuthWrites(t *testing.T) {
+	checkRule(t, "QLK-MISSING-AUTH", []ruleCase{
+		{"write through a local KVStore", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type Keeper struct{ key sdk.StoreKey }
+
+func (k Keeper) SetCount(ctx sdk.Context, value []byte) {
+	kv := ctx.KVStore(k.key)
+	kv.Set([]byte("count"), value)
+}
+`, []int{9}},
+		{"sender only used as a key", `package keeper
+
+type Store interface{ Set(key, value []byte) }
+
+type MsgSetName struct{ Sender, Name string }
+
+type Keeper struct{ store Store }
+
+func (k Keeper) SetName(msg MsgSetName) {
+	k.store.Set([]byte(msg.Sender), []byte(msg.Name))
+}
+`, []int{10}},
+	})
+}
+
+func TestMissingAuthNegatives(t *testing.T) {
+	for _, tc := range []struct {
+		name, src string
+	}{
+		{"authority checked", keeperFixture(`if sender != k.authority {
+		return ErrUnauthorized
+	}
+	k.store.Set([]byte("count"), value)`)},
+		{"compared against GetAuthority", keeperFixture(`if k.GetAuthority() != sender {
+		return ErrUnauthorized
+	}
+	k.store.Set([]byte("count"), value)`)},
+		{"unauthorized path", keeperFixture(`if !k.isAdmin(sender) {
+		return ErrUnauthorized
+	}
+	k.store.Set([]byte("count"), value)`)},
+		{"read only", keeperFixture(`_ = k.store.Get([]byte("count"))`)},
+		{"not a keeper", `package p
+
+type Store interface{ Set(key, value []byte) }
+
+type Cache struct{ store Store }
+
+func (c Cache) Put(key, value []byte) {
+	c.store.Set(key, value)
+}
+`},
+		{"Set on a big.Int", `package keeper
+
+import "math/big"
+
+type Keeper struct{}
+
+func (k Keeper) Reset(a *big.Int) {
+	a.Set(big.NewInt(1))
+}
+`},
+		{"Delete on a sync.Map", `package keeper
+
+import "sync"
+
+type Keeper struct{ cache sync.Map }
+
+func (k *Keeper) Evict(key string) {
+	k.cache.Delete(key)
+}
+`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := findingsFor(parseFixture(t, tc.src), "QLK-MISSING-AUTH"); len(got) != 0 {
+				t.Errorf("got %d findings, want none", len(got))
+			}
+		})
+	}
+}
+
+func TestEventInjection(t *testing.T) {
+	checkRule(t, "QLK-EVENT-INJECTION", []ruleCase{
+		{"unvalidated field in an attribute", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type MsgSend struct{ Memo string }
+
+func HandleSend(ctx sdk.Context, msg MsgSend) {
+	ctx.EventManager().EmitEvent(sdk.NewEvent("send", sdk.NewAttribute("memo", msg.Memo)))
+}
+`, []int{8}},
+		{"message validated first", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type MsgSend struct{ Memo string }
+
+func (m MsgSend) ValidateBasic() error { return nil }
+
+func HandleSend(ctx sdk.Context, msg MsgSend) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	ctx.EventManager().EmitEvent(sdk.NewEvent("send", sdk.NewAttribute("memo", msg.Memo)))
+	return nil
+}
+`, nil},
+		{"field checked by an if", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type MsgSend struct{ Memo string }
+
+func HandleSend(ctx sdk.Context, msg MsgSend) {
+	if len(msg.Memo) > 64 {
+		return
+	}
+	ctx.EventManager().EmitEvent(sdk.NewEvent("send", sdk.NewAttribute("memo", msg.Memo)))
+}
+`, nil},
+	})
+}
+
+func TestSigners(t *testing.T) {
+	const msg = `package types
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+var admin sdk.AccAddress
+
+type MsgSend struct{ From string }
+
+`
+	checkRule(t, "QLK-PERMISSIVE-SIGNERS", []ruleCase{
+		{"package-level address", msg + `func (m MsgSend) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{admin}
+}
+`, []int{10}},
+		{"derived from the message", msg + `func (m MsgSend) GetSigners() []sdk.AccAddress {
+	from, _ := sdk.AccAddressFromBech32(m.From)
+	return []sdk.AccAddress{from}
+}
+`, nil},
+	})
+	checkRule(t, "QLK-EMPTY-SIGNERS", []ruleCase{
+		{"returns nil", msg + `func (m MsgSend) GetSigners() []sdk.AccAddress {
+	return nil
+}
+`, []int{10}},
+		{"returns an empty list", msg + `func (m MsgSend) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{}
+}
+`, []int{10}},
+		{"returns the sender", msg + `func (m MsgSend) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{sdk.MustAccAddressFromBech32(m.From)}
+}
+`, nil},
+	})
+	checkRule(t, "QLK-MISSING-GETSIGNERS", []ruleCase{
+		{"ValidateBasic without GetSigners", msg + `func (m MsgSend) ValidateBasic() error { return nil }
+`, []int{7}},
+		{"both methods", msg + `func (m MsgSend) ValidateBasic() error { return nil }
+
+func (m MsgSend) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{sdk.MustAccAddressFromBech32(m.From)}
+}
+`, nil},
+	})
+}
+
+func TestKeeperCoupling(t *testing.T) {
+	checkRule(t, "QLK-KEEPER-COUPLING", []ruleCase{
+		{"concrete keeper pointer", `package keeper
+
+import bankkeeper "github.com/cosmos/cosmos-sdk/x/bank/keeper"
+
+type Keeper struct {
+	bank *bankkeeper.BaseKeeper
+}
+`, []int{6}},
+		{"expected-keeper interface", `package keeper
+
+type BankKeeper interface {
+	SendCoins() error
+}
+
+type Keeper struct {
+	bank BankKeeper
+}
+`, nil},
+		{"same-package keeper", `package keeper
+
+type Keeper struct {
+	parent *Keeper
+}
+`, nil},
+		{"not a keeper struct", `package keeper
+
+import bankkeeper "github.com/cosmos/cosmos-sdk/x/bank/keeper"
+
+type KeeperOptions struct {
+	bank *bankkeeper.BaseKeeper
+}
+`, nil},
+	})
+}
+
+func TestMissingInvariant(t *testing.T) {
+	const keeper = `package keeper
+
+type Keeper struct {
+	balances map[string]uint64
+}
+`
+	checkRule(t, "QLK-MISSING-INVARIANT", []ruleCase{
+		{"balance state without invariants", keeper, []int{3}},
+		{"invariants registered", keeper + `
+type InvariantRegistry interface{ RegisterRoute(module, route string) }
+
+func RegisterInvariants(ir InvariantRegistry, k Keeper) {}
+`, nil},
+		{"no balance state", `package keeper
+
+type Keeper struct {
+	params map[string]string
+}
+`, nil},
+	})
+}
+
+func TestValidationOrder(t *testing.T) {
+	checkRule(t, "QLK-VALIDATION-ORDER", []ruleCase{
+		{"state written before the check", `package keeper
+
+type MsgSend struct{ Amount uint64 }
+
+type Keeper struct{ total uint64 }
+
+func (k *Keeper) Send(msg MsgSend) error {
+	k.total += msg.Amount
+	if err := validateAmount(msg.Amount); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateAmount(amount uint64) error { return nil }
+`, []int{8}},
+		{"checked first", `package keeper
+
+type MsgSend struct{ Amount uint64 }
+
+type Keeper struct{ total uint64 }
+
+func (k *Keeper) Send(msg MsgSend) error {
+	if err := validateAmount(msg.Amount); err != nil {
+		return err
+	}
+	k.total += msg.Amount
+	return nil
+}
+
+func validateAmount(amount uint64) error { return nil }
+`, nil},
+	})
+}
+
+func TestGenesisValidation(t *testing.T) {
+	checkRule(t, "QLK-GENESIS-VALIDATION", []ruleCase{
+		{"no Validate method", `package types
+
+type GenesisState struct{ Params string }
+`, []int{3}},
+		{"stub Validate", `package types
+
+type GenesisState struct{ Params string }
+
+func (gs GenesisState) Validate() error {
+	return nil
+}
+`, []int{5}},
+		{"InitGenesis writes before validating", `package keeper
+
+type GenesisState struct{ Params string }
+
+func (gs GenesisState) Validate() error {
+	if gs.Params == "" {
+		return errEmpty
+	}
+	return nil
+}
+
+var errEmpty error
+
+type Keeper struct{}
+
+func (k Keeper) SetParams(p string) {}
+
+func InitGenesis(k Keeper, gs GenesisState) {
+	k.SetParams(gs.Params)
+	if err := gs.Validate(); err != nil {
+		panic(err)
+	}
+}
+`, []int{19}},
+		{"validated genesis", `package keeper
+
+type GenesisState struct{ Params string }
+
+func (gs GenesisState) Validate() error {
+	if gs.Params == "" {
+		return errEmpty
+	}
+	return nil
+}
+
+var errEmpty error
+
+type Keeper struct{}
+
+func (k Keeper) SetParams(p string) {}
+
+func InitGenesis(k Keeper, gs GenesisState) {
+	if err := gs.Validate(); err != nil {
+		panic(err)
+	}
+	k.SetParams(gs.Params)
+}
+`, nil},
+	})
+}
+
+func TestStubValidation(t *testing.T) {
+	checkRule(t, "QLK-STUB-VALIDATION", []ruleCase{
+		{"empty validator", `package types
+
+type MsgSend struct{ Amount int }
+
+func (m MsgSend) ValidateBasic() error {
+	return nil
+}
+`, []int{5}},
+		{"handler without an error path", `package types
+
+type MsgSend struct{ Amount int }
+
+func HandleSend(msg MsgSend) error {
+	_ = msg.Amount * 2
+	return nil
+}
+`, []int{5}},
+		{"validator that fails", `package types
+
+import "errors"
+
+type MsgSend struct{ Amount int }
+
+func (m MsgSend) ValidateBasic() error {
+	if m.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	return nil
+}
+`, nil},
+		{"GenesisState.Validate is another rule's", `package types
+
+type GenesisState struct{}
+
+func (gs GenesisState) Validate() error {
+	return nil
+}
+`, nil},
+	})
+}
+
+func TestUnusedContext(t *testing.T) {
+	checkRule(t, "QLK-UNUSED-CONTEXT", []ruleCase{
+		{"context never read", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type Keeper struct{ count int }
+
+func (k Keeper) Count(ctx sdk.Context) int {
+	return k.count
+}
+`, []int{7}},
+		{"context used", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type Keeper struct{ key string }
+
+func (k Keeper) Has(ctx sdk.Context) bool {
+	return ctx.KVStore(k.key).Has([]byte("a"))
+}
+`, nil},
+	})
+}
+
+func TestDirectStateWrite(t *testing.T) {
+	checkRule(t, "QLK-DIRECT-STATE-WRITE", []ruleCase{
+		{"receiver map written", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type Keeper struct {
+	balances map[string]uint64
+}
+
+func (k Keeper) Credit(ctx sdk.Context, addr string, amount uint64) {
+	_ = ctx
+	k.balances[addr] = amount
+}
+`, []int{11}},
+		{"written through the store", `package keeper
+
+import sdk "github.com/cosmos/cosmos-sdk/types"
+
+type Keeper struct {
+	key string
+}
+
+func (k Keeper) Credit(ctx sdk.Context, addr string, amount []byte) {
+	ctx.KVStore(k.key).Set([]byte(addr), amount)
+}
+`, nil},
+		{"local map", `package keeper
+
+type Keeper struct{}
+
+func (k Keeper) Tally(votes []string) map[string]int {
+	counts := map[string]int{}
+	for _, v := range votes {
+		counts[v]++
+	}
+	return counts
+}
+`, nil},
+	})
+}
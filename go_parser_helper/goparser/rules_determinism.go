@@ -0,0 +1,162 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// DeterminismFinding is contract logic whose outcome can differ between
+// validators replaying the same transaction.
+type DeterminismFinding struct {
+	Issue `proto:"1"`
+	// Kind is "call" for a non-deterministic call and "map_iteration" for
+	// output built in map iteration order.
+	Kind string `json:"kind" proto:"9"`
+	// Call is the call's import path and name, "time.Now" or
+	// "math/rand.Intn", or the ranged map for map_iteration.
+	Call string `json:"call" proto:"10"`
+}
+
+// mapIterationEntry stands for ranging over a map in the non-deterministic
+// set, so it can be dropped like any call.
+const mapIterationEntry = "map-iteration"
+
+// defaultNondeterministic is the set reported when the config lists none:
+// import path and function, or a whole import path.
+var defaultNondeterministic = []string{
+	"time.Now", "time.Since", "time.Until",
+	"math/rand", "math/rand/v2", "crypto/rand",
+	"os.Getenv", "os.LookupEnv", "os.Environ", "os.Hostname",
+	mapIterationEntry,
+}
+
+// isContractLogic reports whether fn runs as part of transaction or block
+// processing: a keeper, contract or msgServer method, a dispatch target, a
+// function taking a message, or an entry point of the file's framework.
+func (v *GoVisitor) isContractLogic(fn *ast.FuncDecl, handlers map[string]bool) bool {
+	recv := receiverTypeName(fn)
+	if isStateOwner(recv) || isMsgServerType(recv) || handlers[funcDisplayName(fn)] {
+		return true
+	}
+	for _, fw := range frameworks {
+		if fw.name != v.result.ContractType {
+			continue
+		}
+		for _, method := range fw.methods {
+			if fn.Name.Name == method {
+				return true
+			}
+		}
+	}
+	for _, typ := range v.fieldTypes(fn.Type.Params) {
+		if isMessageTypeName(typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// orderDependent reports whether body builds ordered output from each
+// iteration: appending to a slice, writing the store or emitting an event.
+func orderDependent(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			switch calleeName(call) {
+			case "append", "Set", "EmitEvent", "EmitEvents", "EmitTypedEvent":
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// sortsAfter reports whether fn calls a sort or slices sorting function
+// after the end of node, restoring a deterministic order.
+func sortsAfter(fn *ast.FuncDecl, node ast.Node) bool {
+	found := false
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || call.Pos() < node.End() {
+			return !found
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok && (pkg.Name == "sort" || pkg.Name == "slices" && strings.HasPrefix(sel.Sel.Name, "Sort")) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// detectNondeterminism flags calls in contract logic to functions of the
+// configured non-deterministic set, matched by import path so an aliased
+// or shadowed "time" is told apart, and map ranges whose body builds
+// ordered output that is not sorted afterwards.
+func (v *GoVisitor) detectNondeterminism(file *ast.File) {
+	entries := v.config.Nondeterministic
+	if len(entries) == 0 {
+		entries = defaultNondeterministic
+	}
+	forbidden := map[string]bool{}
+	for _, entry := range entries {
+		forbidden[entry] = true
+	}
+	imports := importLocalNames(file)
+	handlers := map[string]bool{}
+	for _, route := range v.result.Dispatch {
+		handlers[route.Handler] = true
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || !v.isContractLogic(fn, handlers) {
+			continue
+		}
+		scope := v.newMapScope(file, fn)
+		report := func(node ast.Node, kind, call, severity, message string) {
+			v.result.DeterminismFindings = append(v.result.DeterminismFindings, DeterminismFinding{
+				Issue: Issue{
+					RuleID:   "QLK-NONDETERMINISM",
+					Severity: severity,
+					Message:  message,
+					Function: funcDisplayName(fn),
+					Span:     v.span(node),
+				},
+				Kind: kind,
+				Call: call,
+			})
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.CallExpr:
+				sel, ok := s.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				// A package name has no object; a local variable named
+				// time does.
+				pkg, ok := sel.X.(*ast.Ident)
+				if !ok || pkg.Obj != nil || imports[pkg.Name] == "" {
+					return true
+				}
+				path := imports[pkg.Name]
+				if !forbidden[path+"."+sel.Sel.Name] && !forbidden[path] {
+					return true
+				}
+				call := path + "." + sel.Sel.Name
+				report(s, "call", call, SeverityHigh, fmt.Sprintf("%s calls %s, which can return different values on different validators", funcDisplayName(fn), call))
+			case *ast.RangeStmt:
+				if !forbidden[mapIterationEntry] || !scope.isMap(s.X) || !orderDependent(s.Body) || sortsAfter(fn, s) {
+					return true
+				}
+				ranged := v.nodeText(s.X)
+				report(s.X, "map_iteration", ranged, SeverityMedium, fmt.Sprintf("%s builds ordered output while ranging over map %s, whose iteration order is random; sort the keys first", funcDisplayName(fn), ranged))
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package goparser
+
+import "testing"
+
+func TestNondeterminism(t *testing.T) {
+	checkRule(t, "QLK-NONDETERMINISM", []ruleCase{
+		{"time in a keeper method", `package keeper
+
+import "time"
+
+type Keeper struct{}
+
+func (k Keeper) Stamp() int64 {
+	return time.Now().Unix()
+}
+`, []int{8}},
+		{"unsorted map iteration", `package keeper
+
+type Keeper struct{ balances map[string]int }
+
+func (k Keeper) Holders() []string {
+	var out []string
+	for addr := range k.balances {
+		out = append(out, addr)
+	}
+	return out
+}
+`, []int{7}},
+		{"aliased import", `package keeper
+
+import clock "time"
+
+type Keeper struct{}
+
+func (k Keeper) Stamp() int64 {
+	return clock.Now().Unix()
+}
+`, []int{8}},
+		{"outside contract logic", `package util
+
+import "time"
+
+func Stamp() int64 {
+	return time.Now().Unix()
+}
+`, nil},
+		{"local variable named time", `package keeper
+
+type clock struct{}
+
+func (clock) Now() int64 { return 0 }
+
+type Keeper struct{}
+
+func (k Keeper) Stamp() int64 {
+	time := clock{}
+	return time.Now()
+}
+`, nil},
+		{"sorted after iteration", `package keeper
+
+import "sort"
+
+type Keeper struct{ balances map[string]int }
+
+func (k Keeper) Holders() []string {
+	var out []string
+	for addr := range k.balances {
+		out = append(out, addr)
+	}
+	sort.Strings(out)
+	return out
+}
+`, nil},
+	})
+}
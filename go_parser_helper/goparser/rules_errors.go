@@ -0,0 +1,605 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// isNakedReturn reports whether ret is a bare "return" in a function with
+// named results.
+func isNakedReturn(ret *ast.ReturnStmt) bool {
+	return len(ret.Results) == 0
+}
+
+// namedErrorResult returns the name of fn's named error result, if any.
+func namedErrorResult(fn *ast.FuncDecl) string {
+	if fn.Type.Results == nil {
+		return ""
+	}
+	for _, field := range fn.Type.Results.List {
+		if id, ok := field.Type.(*ast.Ident); ok && id.Name == "error" {
+			for _, name := range field.Names {
+				if name.Name != "_" {
+					return name.Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// deferAssigns reports whether a deferred call in body assigns name, in which
+// case the named result may be set on the way out and the function is skipped.
+func deferAssigns(body *ast.BlockStmt, name string) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		def, ok := n.(*ast.DeferStmt)
+		if !ok {
+			return !found
+		}
+		ast.Inspect(def.Call, func(m ast.Node) bool {
+			if as, ok := m.(*ast.AssignStmt); ok {
+				for _, lhs := range as.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok && id.Name == name {
+						found = true
+					}
+				}
+			}
+			return !found
+		})
+		return false
+	})
+	return found
+}
+
+// detectNamedErrorNotSet flags naked returns reachable before the function's
+// named error result has been assigned on that path.
+func (v *GoVisitor) detectNamedErrorNotSet(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		errName := namedErrorResult(fn)
+		if errName == "" || deferAssigns(fn.Body, errName) {
+			continue
+		}
+		checker := &namedErrorChecker{name: errName}
+		checker.block(fn.Body.List, false, true)
+		for _, ret := range checker.unset {
+			v.result.NamedErrorNotSet = append(v.result.NamedErrorNotSet, Issue{
+				RuleID:   "QLK-NAMED-ERR-NOT-SET",
+				Severity: SeverityMedium,
+				Message:  fmt.Sprintf("naked return before named result %q is assigned; the function returns a nil error on this path", errName),
+				Function: funcDisplayName(fn),
+				Span:     v.span(ret),
+			})
+		}
+	}
+}
+
+// namedErrorChecker tracks, statement by statement, whether the named error
+// result is definitely assigned on the current path.
+type namedErrorChecker struct {
+	name  string
+	unset []*ast.ReturnStmt
+}
+
+// block walks a statement list and returns whether the error is assigned
+// once the list completes normally. Only assignments in the function's top
+// scope count for ":=", since inner ":=" shadows the result.
+func (c *namedErrorChecker) block(stmts []ast.Stmt, assigned, top bool) bool {
+	for _, stmt := range stmts {
+		assigned = c.stmt(stmt, assigned, top)
+	}
+	return assigned
+}
+
+func (c *namedErrorChecker) stmt(stmt ast.Stmt, assigned, top bool) bool {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		if s.Tok == token.DEFINE && !top {
+			return assigned
+		}
+		for _, lhs := range s.Lhs {
+			if id, ok := lhs.(*ast.Ident); ok && id.Name == c.name {
+				return true
+			}
+		}
+	case *ast.ReturnStmt:
+		if isNakedReturn(s) && !assigned {
+			c.unset = append(c.unset, s)
+		}
+		return true
+	case *ast.BlockStmt:
+		return c.block(s.List, assigned, false)
+	case *ast.LabeledStmt:
+		return c.stmt(s.Stmt, assigned, top)
+	case *ast.IfStmt:
+		if s.Init != nil {
+			assigned = c.stmt(s.Init, assigned, false)
+		}
+		thenAssigned := c.block(s.Body.List, assigned, false)
+		elseAssigned := assigned
+		if s.Else != nil {
+			elseAssigned = c.stmt(s.Else, assigned, false)
+		}
+		return assigned || (thenAssigned && elseAssigned)
+	case *ast.ForStmt:
+		if s.Init != nil {
+			assigned = c.stmt(s.Init, assigned, false)
+		}
+		c.block(s.Body.List, assigned, false)
+	case *ast.RangeStmt:
+		c.block(s.Body.List, assigned, false)
+	case *ast.SwitchStmt:
+		if s.Init != nil {
+			assigned = c.stmt(s.Init, assigned, false)
+		}
+		c.clauses(s.Body, assigned)
+	case *ast.TypeSwitchStmt:
+		if s.Init != nil {
+			assigned = c.stmt(s.Init, assigned, false)
+		}
+		c.clauses(s.Body, assigned)
+	case *ast.SelectStmt:
+		c.clauses(s.Body, assigned)
+	}
+	return assigned
+}
+
+func (c *namedErrorChecker) clauses(body *ast.BlockStmt, assigned bool) {
+	for _, clause := range body.List {
+		switch cl := clause.(type) {
+		case *ast.CaseClause:
+			c.block(cl.Body, assigned, false)
+		case *ast.CommClause:
+			c.block(cl.Body, assigned, false)
+		}
+	}
+}
+
+// methodVerb returns the leading camelCase word of a method name ("Set" for
+// SetParams), or "" when the name is a single word.
+func methodVerb(name string) string {
+	for i := 1; i < len(name); i++ {
+		if unicode.IsUpper(rune(name[i])) {
+			return name[:i]
+		}
+	}
+	return ""
+}
+
+// detectInconsistentErrorReturns flags method families of a type, grouped by
+// leading verb (Set*, Delete*, ...), in which some members return an error
+// and others do not.
+func (v *GoVisitor) detectInconsistentErrorReturns() {
+	type family struct {
+		withErr, withoutErr []ParsedFunction
+	}
+	families := map[string]*family{}
+	var order []string
+	for _, fn := range v.result.Functions {
+		if fn.Receiver == nil {
+			continue
+		}
+		verb := methodVerb(fn.Name)
+		if verb == "" {
+			continue
+		}
+		key := baseTypeName(fn.Receiver.Type) + "." + verb
+		if families[key] == nil {
+			families[key] = &family{}
+			order = append(order, key)
+		}
+		if n := len(fn.ReturnTypes); n > 0 && fn.ReturnTypes[n-1] == "error" {
+			families[key].withErr = append(families[key].withErr, fn)
+		} else {
+			families[key].withoutErr = append(families[key].withoutErr, fn)
+		}
+	}
+
+	names := func(fns []ParsedFunction) string {
+		var out []string
+		for _, fn := range fns {
+			out = append(out, fn.Name)
+		}
+		return strings.Join(out, ", ")
+	}
+	for _, key := range order {
+		f := families[key]
+		if len(f.withErr) == 0 || len(f.withoutErr) == 0 {
+			continue
+		}
+		typeName := key[:strings.Index(key, ".")]
+		odd := f.withoutErr[0]
+		v.result.InconsistentErrorReturns = append(v.result.InconsistentErrorReturns, Issue{
+			RuleID:   "QLK-INCONSISTENT-ERR-RETURN",
+			Severity: SeverityLow,
+			Message:  fmt.Sprintf("%s.%s* methods disagree on returning error: returned by %s but not by %s", typeName, key[len(typeName)+1:], names(f.withErr), names(f.withoutErr)),
+			Function: typeName + "." + odd.Name,
+			Span:     Span{LineStart: odd.LineStart, ColStart: odd.ColStart},
+		})
+	}
+}
+
+// ShadowFinding is a := in a nested block that declares a new variable
+// instead of assigning the outer one of the same name, whose later check
+// then never sees the inner value.
+type ShadowFinding struct {
+	Issue     `proto:"1"`
+	Name      string `json:"name" proto:"9"`
+	OuterLine int    `json:"outer_line" proto:"10"`
+	InnerLine int    `json:"inner_line" proto:"11"`
+	CheckLine int    `json:"check_line" proto:"12"` // first use of the outer variable after the inner block
+}
+
+// checkAfter returns the first read of d after pos, unless d is written
+// again before it.
+func (d *shadowDecl) checkAfter(pos token.Pos) *ast.Ident {
+	for _, use := range d.checks {
+		if use.Pos() <= pos {
+			continue
+		}
+		for _, write := range d.writes {
+			if write > pos && write < use.Pos() {
+				return nil
+			}
+		}
+		return use
+	}
+	return nil
+}
+
+// consumedBefore reports whether d's value was already read, or never
+// set, by pos: a later check of d is then waiting for a new value rather
+// than for the one d holds.
+func (d *shadowDecl) consumedBefore(pos token.Pos) bool {
+	last := token.NoPos
+	for _, write := range d.writes {
+		if write < pos && write > last {
+			last = write
+		}
+	}
+	if last == token.NoPos {
+		return true
+	}
+	for _, use := range d.uses {
+		if use.Pos() > last && use.Pos() < pos {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultShadowNames are the variables checked when the config lists none.
+var defaultShadowNames = []string{"err"}
+
+// shadowDecl is one declaration of a tracked name, with the positions it
+// is read and written at. checks are the reads outside function literals,
+// which run where they appear; a deferred closure reads the variable
+// whenever it returns.
+type shadowDecl struct {
+	ident  *ast.Ident
+	uses   []*ast.Ident
+	checks []*ast.Ident
+	writes []token.Pos
+}
+
+// shadowScope is one lexical scope. A function literal's outermost scope
+// is a boundary: a := inside the literal does not shadow in the sense
+// reported here, though its uses of outer variables still count.
+type shadowScope struct {
+	decls    map[string]*shadowDecl
+	boundary bool
+}
+
+type shadow struct {
+	outer, inner *shadowDecl
+	// end closes the block the inner declaration lives in, and terminates
+	// is set when that block cannot fall through to the code after it.
+	end        token.Pos
+	terminates bool
+}
+
+// shadowWalker resolves tracked names through a stack of scopes as it
+// descends, since ast.Walk alone does not say which declaration a name
+// refers to.
+type shadowWalker struct {
+	tracked map[string]bool
+	scopes  []*shadowScope
+	shadows []shadow
+	// blockEnd is the end of the innermost block being walked, and
+	// blockLast its last statement.
+	blockEnd  token.Pos
+	blockLast ast.Stmt
+	// closures counts the function literals being walked.
+	closures int
+}
+
+func (w *shadowWalker) push(boundary bool) {
+	w.scopes = append(w.scopes, &shadowScope{decls: map[string]*shadowDecl{}, boundary: boundary})
+}
+
+func (w *shadowWalker) pop() { w.scopes = w.scopes[:len(w.scopes)-1] }
+
+// lookup returns the visible declaration of name, and whether it lies
+// beyond the innermost function boundary.
+func (w *shadowWalker) lookup(name string) (decl *shadowDecl, crossed bool) {
+	for i := len(w.scopes) - 1; i >= 0; i-- {
+		if d := w.scopes[i].decls[name]; d != nil {
+			return d, crossed
+		}
+		crossed = crossed || w.scopes[i].boundary
+	}
+	return nil, crossed
+}
+
+// declare adds id to the innermost scope, as written when it is given a
+// value. A name already declared in that scope is only written. When
+// report is set and id hides a declaration of the same function, the
+// shadow is recorded.
+func (w *shadowWalker) declare(id *ast.Ident, written, report bool) {
+	if !w.tracked[id.Name] {
+		return
+	}
+	scope := w.scopes[len(w.scopes)-1]
+	if decl := scope.decls[id.Name]; decl != nil {
+		decl.writes = append(decl.writes, id.Pos())
+		return
+	}
+	outer, crossed := w.lookup(id.Name)
+	decl := &shadowDecl{ident: id}
+	if written {
+		decl.writes = append(decl.writes, id.Pos())
+	}
+	scope.decls[id.Name] = decl
+	if report && outer != nil && !crossed {
+		w.shadows = append(w.shadows, shadow{outer: outer, inner: decl, end: w.blockEnd, terminates: isTerminating(w.blockLast)})
+	}
+}
+
+// isTerminating reports whether stmt ends its block for good: a return,
+// branch, panic or exit.
+func isTerminating(stmt ast.Stmt) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	case *ast.ExprStmt:
+		if call, ok := s.X.(*ast.CallExpr); ok {
+			name := calleeName(call)
+			return name == "panic" || name == "Exit" || strings.HasPrefix(name, "Fatal")
+		}
+	}
+	return false
+}
+
+// declareFields declares parameters as written and named results as not.
+func (w *shadowWalker) declareFields(list *ast.FieldList, written bool) {
+	if list == nil {
+		return
+	}
+	for _, field := range list.List {
+		for _, name := range field.Names {
+			w.declare(name, written, false)
+		}
+	}
+}
+
+// block walks stmts in a new scope ending at end.
+func (w *shadowWalker) block(stmts []ast.Stmt, end token.Pos) {
+	savedEnd, savedLast := w.blockEnd, w.blockLast
+	w.blockEnd, w.blockLast = end, nil
+	if len(stmts) > 0 {
+		w.blockLast = stmts[len(stmts)-1]
+	}
+	w.push(false)
+	for _, stmt := range stmts {
+		w.walk(stmt)
+	}
+	w.pop()
+	w.blockEnd, w.blockLast = savedEnd, savedLast
+}
+
+// define walks a := or var declaration: values first, then the names it
+// declares. Declarations in if, for, switch and select headers are
+// scoped to that statement by design and are not reported.
+func (w *shadowWalker) define(names []*ast.Ident, values []ast.Expr, report bool) {
+	for _, value := range values {
+		w.walk(value)
+	}
+	for _, name := range names {
+		w.declare(name, len(values) > 0, report)
+	}
+}
+
+func definedIdents(exprs []ast.Expr) []*ast.Ident {
+	var ids []*ast.Ident
+	for _, expr := range exprs {
+		if id, ok := expr.(*ast.Ident); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// header walks the init statement of an if, switch or for, without
+// reporting what it declares.
+func (w *shadowWalker) header(stmt ast.Stmt) {
+	if as, ok := stmt.(*ast.AssignStmt); ok && as.Tok == token.DEFINE {
+		w.define(definedIdents(as.Lhs), as.Rhs, false)
+		return
+	}
+	if stmt != nil {
+		w.walk(stmt)
+	}
+}
+
+func (w *shadowWalker) walk(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.BlockStmt:
+			w.block(s.List, s.End())
+		case *ast.CaseClause:
+			for _, expr := range s.List {
+				w.walk(expr)
+			}
+			w.block(s.Body, s.End())
+		case *ast.CommClause:
+			w.push(false)
+			w.header(s.Comm)
+			w.block(s.Body, s.End())
+			w.pop()
+		case *ast.IfStmt:
+			w.push(false)
+			w.header(s.Init)
+			w.walk(s.Cond)
+			w.walk(s.Body)
+			if s.Else != nil {
+				w.walk(s.Else)
+			}
+			w.pop()
+		case *ast.ForStmt:
+			w.push(false)
+			w.header(s.Init)
+			if s.Cond != nil {
+				w.walk(s.Cond)
+			}
+			if s.Post != nil {
+				w.walk(s.Post)
+			}
+			w.walk(s.Body)
+			w.pop()
+		case *ast.RangeStmt:
+			w.walk(s.X)
+			w.push(false)
+			if s.Tok == token.DEFINE {
+				for _, id := range definedIdents([]ast.Expr{s.Key, s.Value}) {
+					w.declare(id, true, false)
+				}
+			} else {
+				for _, expr := range []ast.Expr{s.Key, s.Value} {
+					if expr != nil {
+						w.walk(expr)
+					}
+				}
+			}
+			w.walk(s.Body)
+			w.pop()
+		case *ast.SwitchStmt:
+			w.push(false)
+			w.header(s.Init)
+			if s.Tag != nil {
+				w.walk(s.Tag)
+			}
+			w.walk(s.Body)
+			w.pop()
+		case *ast.TypeSwitchStmt:
+			w.push(false)
+			w.header(s.Init)
+			w.header(s.Assign)
+			w.walk(s.Body)
+			w.pop()
+		case *ast.FuncLit:
+			w.closures++
+			w.push(true)
+			w.declareFields(s.Type.Params, true)
+			w.declareFields(s.Type.Results, false)
+			for _, stmt := range s.Body.List {
+				w.walk(stmt)
+			}
+			w.pop()
+			w.closures--
+		case *ast.AssignStmt:
+			if s.Tok == token.DEFINE {
+				w.define(definedIdents(s.Lhs), s.Rhs, true)
+				return false
+			}
+			for _, rhs := range s.Rhs {
+				w.walk(rhs)
+			}
+			// Plain assignments write the variable rather than check it.
+			for _, lhs := range s.Lhs {
+				id, ok := lhs.(*ast.Ident)
+				if !ok {
+					w.walk(lhs)
+				} else if decl, _ := w.lookup(id.Name); decl != nil && w.tracked[id.Name] {
+					decl.writes = append(decl.writes, id.Pos())
+				}
+			}
+		case *ast.ValueSpec:
+			w.define(s.Names, s.Values, true)
+			if s.Type != nil {
+				w.walk(s.Type)
+			}
+		case *ast.SelectorExpr:
+			w.walk(s.X)
+		case *ast.Ident:
+			if decl, _ := w.lookup(s.Name); decl != nil && w.tracked[s.Name] {
+				decl.uses = append(decl.uses, s)
+				if w.closures == 0 {
+					decl.checks = append(decl.checks, s)
+				}
+			}
+			return false
+		default:
+			return true
+		}
+		return false
+	})
+}
+
+// detectErrShadowing flags := declarations of a tracked name, err by
+// default, in a block nested inside the scope of an earlier declaration of
+// that name, when the outer variable is read after the block ends without
+// being written in between: the code after the block looks at a variable
+// the block never set. The outer value must already have been checked, or
+// never set, before the block, and the block must be able to fall through.
+func (v *GoVisitor) detectErrShadowing(file *ast.File) {
+	names := v.config.ShadowNames
+	if len(names) == 0 {
+		names = defaultShadowNames
+	}
+	tracked := map[string]bool{}
+	for _, name := range names {
+		tracked[name] = true
+	}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		w := &shadowWalker{tracked: tracked}
+		w.push(true)
+		w.declareFields(fn.Recv, true)
+		w.declareFields(fn.Type.Params, true)
+		w.declareFields(fn.Type.Results, false)
+		w.block(fn.Body.List, fn.Body.End())
+		for _, s := range w.shadows {
+			if s.terminates {
+				continue
+			}
+			check := s.outer.checkAfter(s.end)
+			if check == nil || !s.outer.consumedBefore(s.inner.ident.Pos()) {
+				continue
+			}
+			name := s.inner.ident.Name
+			v.result.ShadowFindings = append(v.result.ShadowFindings, ShadowFinding{
+				Issue: Issue{
+					RuleID:   "QLK-ERR-SHADOW",
+					Severity: SeverityMedium,
+					Message:  fmt.Sprintf("%s := on line %d declares a new %s, hiding the one declared on line %d that is checked on line %d; use = to assign the outer variable", name, v.line(s.inner.ident), name, v.line(s.outer.ident), v.line(check)),
+					Function: funcDisplayName(fn),
+					Span:     v.span(s.inner.ident),
+				},
+				Name:      name,
+				OuterLine: v.line(s.outer.ident),
+				InnerLine: v.line(s.inner.ident),
+				CheckLine: v.line(check),
+			})
+		}
+	}
+}
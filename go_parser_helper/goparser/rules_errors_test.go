@@ -0,0 +1,169 @@
+package goparser
+
+import "testing"
+
+func TestNamedErrorNotSet(t *testing.T) {
+	checkRule(t, "QLK-NAMED-ERR-NOT-SET", []ruleCase{
+		{"naked return before assignment", `package p
+
+func Load(ok bool) (n int, err error) {
+	if !ok {
+		return
+	}
+	n = 1
+	return
+}
+`, []int{5, 8}},
+		{"assigned on one branch only", `package p
+
+import "errors"
+
+func Load(ok bool) (err error) {
+	if ok {
+		err = errors.New("bad")
+	}
+	return
+}
+`, []int{9}},
+		{"assigned before return", `package p
+
+import "errors"
+
+func Load(ok bool) (err error) {
+	err = errors.New("bad")
+	if ok {
+		err = nil
+	}
+	return
+}
+`, nil},
+		{"set by a deferred closure", `package p
+
+import "errors"
+
+func Load() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("panicked")
+		}
+	}()
+	return
+}
+`, nil},
+		{"explicit returns", `package p
+
+func Load(ok bool) (n int, err error) {
+	if !ok {
+		return 0, nil
+	}
+	return 1, nil
+}
+`, nil},
+	})
+}
+
+func TestInconsistentErrorReturn(t *testing.T) {
+	checkRule(t, "QLK-INCONSISTENT-ERR-RETURN", []ruleCase{
+		{"one setter without an error", `package keeper
+
+type Keeper struct{}
+
+func (k Keeper) SetParams(p int) error { return nil }
+
+func (k Keeper) SetOwner(o string) {}
+`, []int{7}},
+		{"consistent family", `package keeper
+
+type Keeper struct{}
+
+func (k Keeper) SetParams(p int) error { return nil }
+
+func (k Keeper) SetOwner(o string) error { return nil }
+`, nil},
+		{"different verbs", `package keeper
+
+type Keeper struct{}
+
+func (k Keeper) SetParams(p int) error { return nil }
+
+func (k Keeper) GetOwner() string { return "" }
+`, nil},
+		{"different receivers", `package keeper
+
+type Keeper struct{}
+
+type Store struct{}
+
+func (k Keeper) SetParams(p int) error { return nil }
+
+func (s Store) SetOwner(o string) {}
+`, nil},
+	})
+}
+
+func TestErrShadow(t *testing.T) {
+	checkRule(t, "QLK-ERR-SHADOW", []ruleCase{
+		{"inner := hides the checked err", `package p
+
+func step() error { return nil }
+
+func Run(retry bool) error {
+	err := step()
+	if err != nil {
+		return err
+	}
+	if retry {
+		err := step()
+		_ = err
+	}
+	return err
+}
+`, []int{11}},
+		{"inner = assigns the outer err", `package p
+
+func step() error { return nil }
+
+func Run(retry bool) error {
+	err := step()
+	if err != nil {
+		return err
+	}
+	if retry {
+		err = step()
+	}
+	return err
+}
+`, nil},
+		{"inner block returns", `package p
+
+func step() error { return nil }
+
+func Run(retry bool) error {
+	err := step()
+	if err != nil {
+		return err
+	}
+	if retry {
+		err := step()
+		return err
+	}
+	return err
+}
+`, nil},
+		{"declared in an if header", `package p
+
+func step() error { return nil }
+
+func Run() error {
+	err := step()
+	if err != nil {
+		return err
+	}
+	if err := step(); err != nil {
+		return err
+	}
+	return err
+}
+`, nil},
+	})
+}
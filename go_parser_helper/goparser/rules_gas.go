@@ -0,0 +1,185 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// GasRisk is a loop whose iteration count is set by the caller.
+type GasRisk struct {
+	Issue    `proto:"1"`
+	Variable string `json:"variable" proto:"9"`
+}
+
+// collectionParams returns the names of fn's slice, map and variadic
+// parameters.
+func (v *GoVisitor) collectionParams(fn *ast.FuncDecl) map[string]bool {
+	params := map[string]bool{}
+	if fn.Type.Params == nil {
+		return params
+	}
+	for _, field := range fn.Type.Params.List {
+		typ := v.typeToString(field.Type)
+		if !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && !strings.HasPrefix(typ, "...") {
+			continue
+		}
+		for _, name := range field.Names {
+			params[name.Name] = true
+		}
+	}
+	return params
+}
+
+// lenOf returns the identifier measured by a len(x) call, or "".
+func lenOf(expr ast.Expr) string {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return ""
+	}
+	if fun, ok := call.Fun.(*ast.Ident); !ok || fun.Name != "len" {
+		return ""
+	}
+	if id, ok := call.Args[0].(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// comparisons returns the relational operands of a condition, looking
+// through && and ||.
+func comparisons(expr ast.Expr) []*ast.BinaryExpr {
+	if paren, ok := expr.(*ast.ParenExpr); ok {
+		expr = paren.X
+	}
+	bin, ok := expr.(*ast.BinaryExpr)
+	if !ok {
+		return nil
+	}
+	switch bin.Op {
+	case token.LAND, token.LOR:
+		return append(comparisons(bin.X), comparisons(bin.Y)...)
+	case token.LSS, token.LEQ, token.GTR, token.GEQ, token.EQL, token.NEQ:
+		return []*ast.BinaryExpr{bin}
+	}
+	return nil
+}
+
+// isConstBound reports whether expr is a numeric literal or a package
+// constant.
+func (v *GoVisitor) isConstBound(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return e.Kind == token.INT
+	case *ast.Ident:
+		for _, c := range v.result.Constants {
+			if c.Name == e.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasConstBound reports whether a condition compares against a constant.
+func (v *GoVisitor) hasConstBound(cond ast.Expr) bool {
+	for _, cmp := range comparisons(cond) {
+		if v.isConstBound(cmp.X) || v.isConstBound(cmp.Y) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLimitBreak reports whether a loop body breaks out under a condition
+// comparing a counter against a constant limit.
+func (v *GoVisitor) hasLimitBreak(body *ast.BlockStmt) bool {
+	found := false
+	inspectBody(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			// A break in an inner loop does not end this one.
+			return false
+		case *ast.IfStmt:
+			if !v.hasConstBound(s.Cond) {
+				return true
+			}
+			for _, stmt := range s.Body.List {
+				if br, ok := stmt.(*ast.BranchStmt); ok && br.Tok == token.BREAK {
+					found = true
+				}
+				if _, ok := stmt.(*ast.ReturnStmt); ok {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// detectUnboundedLoops flags loops ranging over, or counting up to the
+// length of, a slice or map parameter that the function never checks
+// against a limit.
+func (v *GoVisitor) detectUnboundedLoops(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		params := v.collectionParams(fn)
+		if len(params) == 0 {
+			continue
+		}
+		// checked holds parameters whose length an if compares against a
+		// constant, such as "if len(msgs) > MaxMsgs { return err }".
+		checked := map[string]bool{}
+		report := func(loop ast.Node, name string, body *ast.BlockStmt) {
+			if checked[name] || v.hasLimitBreak(body) {
+				return
+			}
+			v.result.GasRisks = append(v.result.GasRisks, GasRisk{
+				Issue: Issue{
+					RuleID:   "QLK-UNBOUNDED-LOOP",
+					Severity: SeverityMedium,
+					Message:  fmt.Sprintf("loop over caller-supplied %s has no upper bound", name),
+					Function: funcDisplayName(fn),
+					Span:     v.span(loop),
+				},
+				Variable: name,
+			})
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.IfStmt:
+				for _, cmp := range comparisons(s.Cond) {
+					for _, side := range []ast.Expr{cmp.X, cmp.Y} {
+						if name := lenOf(side); params[name] && v.hasConstBound(cmp) {
+							checked[name] = true
+						}
+					}
+				}
+			case *ast.RangeStmt:
+				if id, ok := s.X.(*ast.Ident); ok && params[id.Name] {
+					report(s, id.Name, s.Body)
+				}
+			case *ast.ForStmt:
+				if s.Cond == nil || v.hasConstBound(s.Cond) {
+					return true
+				}
+				for _, cmp := range comparisons(s.Cond) {
+					name := lenOf(cmp.Y)
+					if name == "" {
+						name = lenOf(cmp.X)
+					}
+					if params[name] {
+						report(s, name, s.Body)
+						break
+					}
+				}
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package goparser
+
+import "testing"
+
+func TestUnboundedLoop(t *testing.T) {
+	checkRule(t, "QLK-UNBOUNDED-LOOP", []ruleCase{
+		{"range over a slice parameter", `package p
+
+func Sum(amounts []int) int {
+	total := 0
+	for _, a := range amounts {
+		total += a
+	}
+	return total
+}
+`, []int{5}},
+		{"count up to a parameter's length", `package p
+
+func Sum(amounts []int) int {
+	total := 0
+	for i := 0; i < len(amounts); i++ {
+		total += amounts[i]
+	}
+	return total
+}
+`, []int{5}},
+		{"length checked against a constant", `package p
+
+import "errors"
+
+const MaxAmounts = 100
+
+func Sum(amounts []int) (int, error) {
+	if len(amounts) > MaxAmounts {
+		return 0, errors.New("too many amounts")
+	}
+	total := 0
+	for _, a := range amounts {
+		total += a
+	}
+	return total, nil
+}
+`, nil},
+		{"break at a limit", `package p
+
+func Sum(amounts []int) int {
+	total := 0
+	for i, a := range amounts {
+		if i >= 100 {
+			break
+		}
+		total += a
+	}
+	return total
+}
+`, nil},
+		{"range over a local", `package p
+
+func Sum() int {
+	total := 0
+	for _, a := range []int{1, 2, 3} {
+		total += a
+	}
+	return total
+}
+`, nil},
+	})
+}
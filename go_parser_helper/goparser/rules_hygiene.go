@@ -0,0 +1,498 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// duplicateLiteralThreshold is the number of occurrences at which a string
+// literal should become a named constant.
+const duplicateLiteralThreshold = 3
+
+var formatVerbPattern = regexp.MustCompile(`%[-+# 0]*[0-9.*]*[a-zA-Z%]`)
+
+// DuplicateLiteral groups the occurrences of a repeated string literal.
+type DuplicateLiteral struct {
+	Issue `proto:"1"`
+	Value string `json:"value" proto:"9"`
+	Lines []int  `json:"lines" proto:"10"`
+}
+
+// detectDuplicateLiterals flags non-trivial string literals repeated often
+// enough that a typo in one copy (a denom or store key) would go unnoticed.
+// Import paths, struct tags and format strings are ignored.
+func (v *GoVisitor) detectDuplicateLiterals(file *ast.File) {
+	occurrences := map[string][]int{}
+	first := map[string]Span{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.ImportSpec, *ast.Field:
+			return false
+		case *ast.BasicLit:
+			if node.Kind != token.STRING {
+				return true
+			}
+			value, err := strconv.Unquote(node.Value)
+			if err != nil || utf8.RuneCountInString(value) <= 3 || formatVerbPattern.MatchString(value) {
+				return true
+			}
+			if len(occurrences[value]) == 0 {
+				first[value] = v.span(node)
+			}
+			occurrences[value] = append(occurrences[value], v.line(node))
+		}
+		return true
+	})
+
+	var duplicates []DuplicateLiteral
+	for value, lines := range occurrences {
+		if len(lines) < duplicateLiteralThreshold {
+			continue
+		}
+		duplicates = append(duplicates, DuplicateLiteral{
+			Issue: Issue{
+				RuleID:   "QLK-DUPLICATE-LITERAL",
+				Severity: SeverityLow,
+				Message:  fmt.Sprintf("string literal %q appears %d times; consider a named constant", value, len(lines)),
+				Span:     first[value],
+			},
+			Value: value,
+			Lines: lines,
+		})
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].LineStart < duplicates[j].LineStart
+	})
+	v.result.DuplicateLiterals = append(v.result.DuplicateLiterals, duplicates...)
+}
+
+// ImportFinding groups the imports of one path made more than once in a
+// file, or holds a single import that looks unused. Names holds the name
+// each import binds, the default package name for unaliased ones, in source
+// order alongside Lines.
+type ImportFinding struct {
+	Issue `proto:"1"`
+	Path  string   `json:"path" proto:"9"`
+	Names []string `json:"names" proto:"10"`
+	Lines []int    `json:"lines" proto:"11"`
+}
+
+// detectDuplicateImports flags import paths imported more than once, as
+// with a package imported both plainly and under an alias. Blank imports
+// only run the package's init and do not count.
+func (v *GoVisitor) detectDuplicateImports(file *ast.File) {
+	var paths []string
+	specs := map[string][]*ast.ImportSpec{}
+	for _, imp := range file.Imports {
+		if imp.Name != nil && imp.Name.Name == "_" {
+			continue
+		}
+		path := strings.Trim(imp.Path.Value, `"`)
+		if specs[path] == nil {
+			paths = append(paths, path)
+		}
+		specs[path] = append(specs[path], imp)
+	}
+	for _, path := range paths {
+		if len(specs[path]) < 2 {
+			continue
+		}
+		finding := ImportFinding{Path: path, Names: []string{}, Lines: []int{}}
+		for _, imp := range specs[path] {
+			name := defaultImportName(path)
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			finding.Names = append(finding.Names, name)
+			finding.Lines = append(finding.Lines, v.line(imp))
+		}
+		finding.Issue = Issue{
+			RuleID:   "QLK-DUPLICATE-IMPORT",
+			Severity: SeverityLow,
+			Message:  fmt.Sprintf("%q is imported %d times, as %s", path, len(finding.Names), strings.Join(finding.Names, ", ")),
+			Span:     v.span(specs[path][1]),
+		}
+		v.result.ImportFindings = append(v.result.ImportFindings, finding)
+	}
+}
+
+// detectUnusedImports flags imports whose name never qualifies a selector in
+// the file. Blank and dot imports are skipped, and unaliased ones are matched
+// by the name guessed from their path, so a package whose name differs from
+// its last path element is reported as only possibly unused.
+func (v *GoVisitor) detectUnusedImports(file *ast.File) {
+	for _, imp := range file.Imports {
+		path := strings.Trim(imp.Path.Value, `"`)
+		name := defaultImportName(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if name == "_" || name == "." || v.qualifiers[name] {
+			continue
+		}
+		v.result.ImportFindings = append(v.result.ImportFindings, ImportFinding{
+			Issue: Issue{
+				RuleID:   "QLK-UNUSED-IMPORT",
+				Severity: SeverityLow,
+				Message:  fmt.Sprintf("%q is imported as %s but %s is never used as a qualifier; it may be unused", path, name, name),
+				Span:     v.span(imp),
+			},
+			Path:  path,
+			Names: []string{name},
+			Lines: []int{v.line(imp)},
+		})
+	}
+}
+
+// detectUnusedFields flags struct fields that no selector, composite-literal
+// key or positional literal in the file touches. Matching is by field name
+// only, and tagged fields are skipped since encoders reach them by reflection.
+func (v *GoVisitor) detectUnusedFields(file *ast.File) {
+	used := map[string]bool{}
+	positional := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			used[node.Sel.Name] = true
+		case *ast.KeyValueExpr:
+			if id, ok := node.Key.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		case *ast.CompositeLit:
+			if len(node.Elts) > 0 && node.Type != nil {
+				if _, keyed := node.Elts[0].(*ast.KeyValueExpr); !keyed {
+					positional[baseTypeName(v.typeToString(node.Type))] = true
+				}
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok || st.Fields == nil || positional[ts.Name.Name] {
+			return true
+		}
+		for _, field := range st.Fields.List {
+			if field.Tag != nil {
+				continue
+			}
+			for _, name := range field.Names {
+				if name.Name == "_" || used[name.Name] {
+					continue
+				}
+				v.result.UnusedFields = append(v.result.UnusedFields, Issue{
+					RuleID:   "QLK-UNUSED-FIELD",
+					Severity: SeverityLow,
+					Message:  fmt.Sprintf("field %s.%s is never read or written in this file", ts.Name.Name, name.Name),
+					Span:     v.span(name),
+				})
+			}
+		}
+		return true
+	})
+}
+
+// isCollectionFuncName reports whether a function name promises a collection
+// callers will iterate (ListValidators, GetAllBalances, AllPools).
+func isCollectionFuncName(name string) bool {
+	for _, prefix := range []string{"List", "GetAll", "All"} {
+		if strings.HasPrefix(name, prefix) && (len(name) == len(prefix) || unicode.IsUpper(rune(name[len(prefix)]))) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectNilCollectionReturns flags "return nil" for a slice or map result of
+// a collection-named function. Returns that also carry a non-nil error are
+// left alone, since nil alongside an error is the usual convention.
+func (v *GoVisitor) detectNilCollectionReturns(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || fn.Type.Results == nil || !isCollectionFuncName(fn.Name.Name) {
+			continue
+		}
+		// kinds holds, per result position, "collection", "error" or "".
+		var kinds []string
+		for _, field := range fn.Type.Results.List {
+			kind := ""
+			switch t := field.Type.(type) {
+			case *ast.ArrayType, *ast.MapType:
+				kind = "collection"
+			case *ast.Ident:
+				if t.Name == "error" {
+					kind = "error"
+				}
+			}
+			for n := max(len(field.Names), 1); n > 0; n-- {
+				kinds = append(kinds, kind)
+			}
+		}
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			ret, ok := n.(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != len(kinds) {
+				return true
+			}
+			nilCollection := false
+			for i, result := range ret.Results {
+				id, isIdent := result.(*ast.Ident)
+				isNil := isIdent && id.Name == "nil"
+				switch {
+				case kinds[i] == "collection" && isNil:
+					nilCollection = true
+				case kinds[i] == "error" && !isNil:
+					return true
+				}
+			}
+			if nilCollection {
+				v.result.NilCollectionReturn = append(v.result.NilCollectionReturn, Issue{
+					RuleID:   "QLK-NIL-COLLECTION-RETURN",
+					Severity: SeverityLow,
+					Message:  fmt.Sprintf("%s returns a nil collection; return an empty one so callers get a consistent result", fn.Name.Name),
+					Function: funcDisplayName(fn),
+					Span:     v.span(ret),
+				})
+			}
+			return true
+		})
+	}
+}
+
+// detectShouldBeMethod flags free functions whose first parameter is a
+// pointer to a struct declared in the file and whose body reaches fields or
+// methods of nothing but that parameter (package selectors aside).
+func (v *GoVisitor) detectShouldBeMethod(file *ast.File) {
+	structs := map[string]bool{}
+	for _, st := range v.result.Structs {
+		structs[st.Name] = true
+	}
+	imports := importLocalNames(file)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || fn.Recv != nil || strings.HasPrefix(fn.Name.Name, "New") {
+			continue
+		}
+		params := fn.Type.Params.List
+		if len(params) == 0 || len(params[0].Names) == 0 {
+			continue
+		}
+		star, ok := params[0].Type.(*ast.StarExpr)
+		if !ok {
+			continue
+		}
+		typeName, ok := star.X.(*ast.Ident)
+		if !ok || !structs[typeName.Name] {
+			continue
+		}
+		param := params[0].Names[0].Name
+
+		accesses, others := 0, false
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return !others
+			}
+			id, ok := sel.X.(*ast.Ident)
+			switch {
+			case !ok:
+			case id.Name == param:
+				accesses++
+			case imports[id.Name] == "":
+				others = true
+			}
+			return !others
+		})
+		if others || accesses == 0 {
+			continue
+		}
+		v.result.ShouldBeMethod = append(v.result.ShouldBeMethod, Issue{
+			RuleID:   "QLK-SHOULD-BE-METHOD",
+			Severity: SeverityLow,
+			Message:  fmt.Sprintf("%s only operates on its *%s parameter %s; consider making it a method of %s", fn.Name.Name, typeName.Name, param, typeName.Name),
+			Function: fn.Name.Name,
+			Span:     v.span(fn),
+		})
+	}
+}
+
+// constableTypes are the declared types a const can take.
+var constableTypes = map[string]bool{
+	"string": true, "bool": true, "int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"float32": true, "float64": true, "byte": true, "rune": true,
+}
+
+// isLiteralExpr reports whether expr is a basic literal, possibly negated,
+// or true/false.
+func isLiteralExpr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return e.Kind != token.IMAG
+	case *ast.Ident:
+		return e.Name == "true" || e.Name == "false"
+	case *ast.UnaryExpr:
+		return (e.Op == token.SUB || e.Op == token.ADD) && isLiteralExpr(e.X)
+	case *ast.ParenExpr:
+		return isLiteralExpr(e.X)
+	}
+	return false
+}
+
+// detectShouldBeConst flags package-level vars initialized from a literal
+// that the file never reassigns, increments or takes the address of.
+func (v *GoVisitor) detectShouldBeConst(file *ast.File) {
+	mutated := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			if s.Tok != token.DEFINE {
+				for _, lhs := range s.Lhs {
+					if id := rootIdent(lhs); id != nil {
+						mutated[id.Name] = true
+					}
+				}
+			}
+		case *ast.IncDecStmt:
+			if id := rootIdent(s.X); id != nil {
+				mutated[id.Name] = true
+			}
+		case *ast.UnaryExpr:
+			if s.Op == token.AND {
+				if id := rootIdent(s.X); id != nil {
+					mutated[id.Name] = true
+				}
+			}
+		}
+		return true
+	})
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if len(vs.Values) != len(vs.Names) {
+				continue
+			}
+			if vs.Type != nil {
+				if id, ok := vs.Type.(*ast.Ident); !ok || !constableTypes[id.Name] {
+					continue
+				}
+			}
+			for i, name := range vs.Names {
+				if name.Name == "_" || mutated[name.Name] || !isLiteralExpr(vs.Values[i]) {
+					continue
+				}
+				v.result.ShouldBeConst = append(v.result.ShouldBeConst, Issue{
+					RuleID:   "QLK-SHOULD-BE-CONST",
+					Severity: SeverityLow,
+					Message:  fmt.Sprintf("package variable %s = %s is never reassigned; declare it const", name.Name, v.nodeText(vs.Values[i])),
+					Span:     v.span(name),
+				})
+			}
+		}
+	}
+}
+
+// hasCall reports whether expr contains a call, whose repeated evaluation
+// may not be redundant.
+func hasCall(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if _, ok := n.(*ast.CallExpr); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// logicalOperands flattens a chain of the same && or || operator, recording
+// each binary expression of the chain in links.
+func logicalOperands(expr ast.Expr, op token.Token, links map[ast.Expr]bool) []ast.Expr {
+	if paren, ok := expr.(*ast.ParenExpr); ok {
+		expr = paren.X
+	}
+	if bin, ok := expr.(*ast.BinaryExpr); ok && bin.Op == op {
+		links[bin] = true
+		return append(logicalOperands(bin.X, op, links), logicalOperands(bin.Y, op, links)...)
+	}
+	return []ast.Expr{expr}
+}
+
+// detectRedundantConditions flags && / || chains that repeat an operand and
+// else-if branches whose condition repeats an earlier one in the same chain.
+// Operands containing calls are ignored since they may differ per call.
+func (v *GoVisitor) detectRedundantConditions(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		report := func(n ast.Node, message string) {
+			v.result.RedundantConditions = append(v.result.RedundantConditions, Issue{
+				RuleID:   "QLK-REDUNDANT-CONDITION",
+				Severity: SeverityLow,
+				Message:  message,
+				Function: funcDisplayName(fn),
+				Span:     v.span(n),
+			})
+		}
+		inChain := map[ast.Expr]bool{}
+		elseIfs := map[*ast.IfStmt]bool{}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.BinaryExpr:
+				if (s.Op != token.LAND && s.Op != token.LOR) || inChain[s] {
+					return true
+				}
+				seen := map[string]bool{}
+				for _, operand := range logicalOperands(s, s.Op, inChain) {
+					text := v.nodeText(operand)
+					if seen[text] && !hasCall(operand) {
+						report(s, fmt.Sprintf("condition repeats %s on both sides of %s", text, s.Op))
+						break
+					}
+					seen[text] = true
+				}
+			case *ast.IfStmt:
+				if elseIfs[s] {
+					return true
+				}
+				seen := map[string]int{}
+				for stmt := s; stmt != nil; {
+					if stmt != s && stmt.Init != nil {
+						break
+					}
+					text := v.nodeText(stmt.Cond)
+					if line, ok := seen[text]; ok && !hasCall(stmt.Cond) {
+						report(stmt, fmt.Sprintf("else-if condition %s repeats the condition on line %d and can never be reached", text, line))
+					} else {
+						seen[text] = v.line(stmt)
+					}
+					next, ok := stmt.Else.(*ast.IfStmt)
+					if !ok {
+						break
+					}
+					elseIfs[next] = true
+					stmt = next
+				}
+			}
+			return true
+		})
+	}
+}
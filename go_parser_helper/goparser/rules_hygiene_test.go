@@ -0,0 +1,317 @@
+package goparser
+
+import "testing"
+
+func TestDuplicateLiteral(t *testing.T) {
+	checkRule(t, "QLK-DUPLICATE-LITERAL", []ruleCase{
+		{"denom repeated three times", `package p
+
+func Denoms() []string {
+	return []string{
+		"uatom",
+		"uatom",
+		"uatom",
+	}
+}
+`, []int{5}},
+		{"repeated only twice", `package p
+
+func Denoms() []string {
+	return []string{"uatom", "uatom"}
+}
+`, nil},
+		{"short and format strings", `package p
+
+import "fmt"
+
+func Show(n int) []string {
+	return []string{"ok", "ok", "ok", fmt.Sprintf("n=%d", n), fmt.Sprintf("n=%d", n), fmt.Sprintf("n=%d", n)}
+}
+`, nil},
+		{"struct tags", `package p
+
+type A struct {
+	X int ` + "`json:\"amount\"`" + `
+}
+
+type B struct {
+	X int ` + "`json:\"amount\"`" + `
+}
+
+type C struct {
+	X int ` + "`json:\"amount\"`" + `
+}
+`, nil},
+	})
+}
+
+func TestDuplicateImport(t *testing.T) {
+	checkRule(t, "QLK-DUPLICATE-IMPORT", []ruleCase{
+		{"plain and aliased", `package p
+
+import (
+	"strings"
+	str "strings"
+)
+
+var _ = strings.ToUpper
+var _ = str.ToLower
+`, []int{5}},
+		{"blank import alongside", `package p
+
+import (
+	"strings"
+	_ "strings"
+)
+
+var _ = strings.ToUpper
+`, nil},
+		{"distinct paths", `package p
+
+import (
+	"strings"
+	"strconv"
+)
+
+var _ = strings.ToUpper
+var _ = strconv.Itoa
+`, nil},
+	})
+}
+
+func TestUnusedImport(t *testing.T) {
+	checkRule(t, "QLK-UNUSED-IMPORT", []ruleCase{
+		{"never qualifies a selector", `package p
+
+import (
+	"strconv"
+	"strings"
+)
+
+var _ = strings.ToUpper
+`, []int{4}},
+		{"unused alias", `package p
+
+import str "strings"
+
+func Upper(s string) string { return s }
+`, []int{3}},
+		{"used", `package p
+
+import "strings"
+
+var _ = strings.ToUpper
+`, nil},
+		{"blank and dot imports", `package p
+
+import (
+	_ "embed"
+	. "strings"
+)
+
+var _ = ToUpper
+`, nil},
+	})
+}
+
+func TestUnusedField(t *testing.T) {
+	checkRule(t, "QLK-UNUSED-FIELD", []ruleCase{
+		{"field never touched", `package p
+
+type Pool struct {
+	Reserve int
+	legacy  int
+}
+
+func (p Pool) Total() int { return p.Reserve }
+`, []int{5}},
+		{"set in a keyed literal", `package p
+
+type Pool struct {
+	Reserve int
+}
+
+var pool = Pool{Reserve: 1}
+`, nil},
+		{"positional literal", `package p
+
+type Pool struct {
+	Reserve int
+}
+
+var pool = Pool{1}
+`, nil},
+		{"tagged field", `package p
+
+type Pool struct {
+	Reserve int ` + "`json:\"reserve\"`" + `
+}
+`, nil},
+	})
+}
+
+func TestNilCollectionReturn(t *testing.T) {
+	checkRule(t, "QLK-NIL-COLLECTION-RETURN", []ruleCase{
+		{"nil slice from a listing function", `package p
+
+func ListPools(n int) []string {
+	if n == 0 {
+		return nil
+	}
+	return []string{"pool"}
+}
+`, []int{5}},
+		{"nil map with a nil error", `package p
+
+func GetAllBalances() (map[string]int, error) {
+	return nil, nil
+}
+`, []int{4}},
+		{"nil alongside an error", `package p
+
+import "errors"
+
+func ListPools(n int) ([]string, error) {
+	if n == 0 {
+		return nil, errors.New("no pools")
+	}
+	return []string{"pool"}, nil
+}
+`, nil},
+		{"not a collection name", `package p
+
+func Lookup() []string {
+	return nil
+}
+`, nil},
+		{"Allowance is not All", `package p
+
+func Allowances() []string {
+	return nil
+}
+`, nil},
+	})
+}
+
+func TestShouldBeMethod(t *testing.T) {
+	checkRule(t, "QLK-SHOULD-BE-METHOD", []ruleCase{
+		{"only touches its pointer parameter", `package p
+
+import "strings"
+
+type Pool struct{ Name string }
+
+func Rename(p *Pool, name string) {
+	p.Name = strings.ToUpper(name)
+}
+`, []int{7}},
+		{"constructor", `package p
+
+type Pool struct{ Name string }
+
+func NewPool(p *Pool) *Pool {
+	p.Name = "pool"
+	return p
+}
+`, nil},
+		{"touches another value", `package p
+
+type Pool struct{ Name string }
+
+type Registry struct{ Names []string }
+
+func Register(p *Pool, r *Registry) {
+	r.Names = append(r.Names, p.Name)
+}
+`, nil},
+		{"value parameter", `package p
+
+type Pool struct{ Name string }
+
+func Name(p Pool) string {
+	return p.Name
+}
+`, nil},
+	})
+}
+
+func TestShouldBeConst(t *testing.T) {
+	checkRule(t, "QLK-SHOULD-BE-CONST", []ruleCase{
+		{"literal never reassigned", `package p
+
+var maxValidators = 100
+
+var (
+	denom   string = "uatom"
+	enabled        = true
+)
+
+func Limit() int { return maxValidators }
+`, []int{3, 6, 7}},
+		{"reassigned, incremented or addressed", `package p
+
+var a = 1
+var b = 2
+var c = 3
+
+func Bump() *int {
+	a = 4
+	b++
+	return &c
+}
+`, nil},
+		{"not a literal or constable type", `package p
+
+import "errors"
+
+var ErrNotFound = errors.New("not found")
+
+var scale float64 = 1 << 2
+
+var limits []int = nil
+`, nil},
+	})
+}
+
+func TestRedundantCondition(t *testing.T) {
+	checkRule(t, "QLK-REDUNDANT-CONDITION", []ruleCase{
+		{"repeated operand", `package p
+
+func Valid(a, b bool) bool {
+	return a && b && a
+}
+`, []int{4}},
+		{"repeated else-if", `package p
+
+func Kind(n int) string {
+	if n > 0 {
+		return "positive"
+	} else if n < 0 {
+		return "negative"
+	} else if n > 0 {
+		return "unreachable"
+	}
+	return "zero"
+}
+`, []int{8}},
+		{"operands with calls", `package p
+
+func next() bool { return true }
+
+func Valid() bool {
+	return next() && next()
+}
+`, nil},
+		{"distinct conditions", `package p
+
+func Kind(n int, ok bool) string {
+	if n > 0 && ok {
+		return "positive"
+	} else if n < 0 || !ok {
+		return "negative"
+	}
+	return "zero"
+}
+`, nil},
+	})
+}
@@ -0,0 +1,230 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// ReentrancyRisk is an external call made before the function finishes
+// updating its own state, so a callee that calls back in sees stale state.
+type ReentrancyRisk struct {
+	Issue        `proto:"1"`
+	Call         string `json:"call" proto:"9"`
+	MutationLine int    `json:"mutation_line" proto:"10"`
+	Mutation     string `json:"mutation" proto:"11"`
+}
+
+// externalCallMethods are the method names that hand control to another
+// contract or account.
+var externalCallMethods = map[string]bool{
+	"Call": true, "CallContract": true, "Transfer": true, "Send": true,
+	"SendTransaction": true, "Transact": true,
+}
+
+// orderedStmts calls visit for every statement of body in source order,
+// descending into nested blocks but not into function literals.
+func orderedStmts(stmts []ast.Stmt, visit func(ast.Stmt)) {
+	for _, stmt := range stmts {
+		visit(stmt)
+		switch s := stmt.(type) {
+		case *ast.BlockStmt:
+			orderedStmts(s.List, visit)
+		case *ast.LabeledStmt:
+			orderedStmts([]ast.Stmt{s.Stmt}, visit)
+		case *ast.IfStmt:
+			orderedStmts(s.Body.List, visit)
+			if s.Else != nil {
+				orderedStmts([]ast.Stmt{s.Else}, visit)
+			}
+		case *ast.ForStmt:
+			orderedStmts(s.Body.List, visit)
+		case *ast.RangeStmt:
+			orderedStmts(s.Body.List, visit)
+		case *ast.SwitchStmt:
+			orderedStmts(s.Body.List, visit)
+		case *ast.TypeSwitchStmt:
+			orderedStmts(s.Body.List, visit)
+		case *ast.SelectStmt:
+			orderedStmts(s.Body.List, visit)
+		case *ast.CaseClause:
+			orderedStmts(s.Body, visit)
+		case *ast.CommClause:
+			orderedStmts(s.Body, visit)
+		}
+	}
+}
+
+// stmtHeader returns the parts of stmt that run before any nested block:
+// the statement itself when it has none.
+func stmtHeader(stmt ast.Stmt) []ast.Node {
+	var nodes []ast.Node
+	add := func(part ast.Node) { nodes = append(nodes, part) }
+	switch s := stmt.(type) {
+	case *ast.BlockStmt, *ast.LabeledStmt, *ast.CaseClause, *ast.CommClause, *ast.SelectStmt:
+	case *ast.IfStmt:
+		if s.Init != nil {
+			add(s.Init)
+		}
+		add(s.Cond)
+	case *ast.ForStmt:
+		if s.Init != nil {
+			add(s.Init)
+		}
+		if s.Cond != nil {
+			add(s.Cond)
+		}
+	case *ast.RangeStmt:
+		add(s.X)
+	case *ast.SwitchStmt:
+		if s.Init != nil {
+			add(s.Init)
+		}
+		if s.Tag != nil {
+			add(s.Tag)
+		}
+	case *ast.TypeSwitchStmt:
+		if s.Init != nil {
+			add(s.Init)
+		}
+		add(s.Assign)
+	default:
+		add(stmt)
+	}
+	return nodes
+}
+
+// ethClients returns the names of fn's parameters and of struct fields in
+// the file whose type is a go-ethereum client.
+func (v *GoVisitor) ethClients(fn *ast.FuncDecl) map[string]bool {
+	names := map[string]bool{}
+	if fn.Type.Params != nil {
+		for _, field := range fn.Type.Params.List {
+			if strings.Contains(v.typeToString(field.Type), "ethclient.") {
+				for _, name := range field.Names {
+					names[name.Name] = true
+				}
+			}
+		}
+	}
+	for _, st := range v.result.Structs {
+		for _, field := range st.Fields {
+			if strings.Contains(field.Type, "ethclient.") && field.Name != "" {
+				names[field.Name] = true
+			}
+		}
+	}
+	return names
+}
+
+// externalCall returns the first call in node that leaves the contract: a
+// known external-call method, or any method of a go-ethereum client.
+func externalCall(node ast.Node, clients map[string]bool) *ast.CallExpr {
+	var found *ast.CallExpr
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if externalCallMethods[sel.Sel.Name] {
+			found = call
+		}
+		switch x := sel.X.(type) {
+		case *ast.Ident:
+			if clients[x.Name] {
+				found = call
+			}
+		case *ast.SelectorExpr:
+			if clients[x.Sel.Name] {
+				found = call
+			}
+		}
+		return found == nil
+	})
+	return found
+}
+
+// stateMutation returns the assigned expression when stmt writes a field
+// of the receiver recv, possibly through an index, or an entry of a map
+// that outlives the call.
+func stateMutation(stmt ast.Stmt, recv string, scope *mapScope, globals map[string]bool) ast.Expr {
+	var targets []ast.Expr
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		if s.Tok == token.DEFINE {
+			return nil
+		}
+		targets = s.Lhs
+	case *ast.IncDecStmt:
+		targets = []ast.Expr{s.X}
+	default:
+		return nil
+	}
+	for _, target := range targets {
+		if index, ok := target.(*ast.IndexExpr); ok {
+			if id, ok := index.X.(*ast.Ident); ok && globals[id.Name] && scope.isMap(id) {
+				return target
+			}
+		}
+		if receiverField(target, recv) != "" {
+			return target
+		}
+	}
+	return nil
+}
+
+// detectReentrancy flags functions that make an external call and update
+// receiver fields or persistent maps after it, instead of following the
+// checks-effects-interactions order.
+func (v *GoVisitor) detectReentrancy(file *ast.File) {
+	globals, _ := packageVars(file)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		recv := ""
+		if fn.Recv != nil && len(fn.Recv.List) > 0 && len(fn.Recv.List[0].Names) > 0 {
+			recv = fn.Recv.List[0].Names[0].Name
+		}
+		scope := v.newMapScope(file, fn)
+		clients := v.ethClients(fn)
+		// pending holds the external calls not yet followed by a mutation.
+		var pending []*ast.CallExpr
+		orderedStmts(fn.Body.List, func(stmt ast.Stmt) {
+			if target := stateMutation(stmt, recv, scope, globals); target != nil {
+				for _, call := range pending {
+					v.result.ReentrancyRisks = append(v.result.ReentrancyRisks, ReentrancyRisk{
+						Issue: Issue{
+							RuleID:   "QLK-REENTRANCY",
+							Severity: SeverityHigh,
+							Message:  fmt.Sprintf("external call %s happens before %s is updated on line %d", v.nodeText(call.Fun), v.nodeText(target), v.line(stmt)),
+							Function: funcDisplayName(fn),
+							Span:     v.span(call),
+						},
+						Call:         v.nodeText(call.Fun),
+						MutationLine: v.line(stmt),
+						Mutation:     v.nodeText(target),
+					})
+				}
+				pending = nil
+			}
+			for _, part := range stmtHeader(stmt) {
+				if call := externalCall(part, clients); call != nil {
+					pending = append(pending, call)
+				}
+			}
+		})
+	}
+}
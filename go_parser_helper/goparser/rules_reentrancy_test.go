@@ -0,0 +1,61 @@
+package goparser
+
+import "testing"
+
+func TestReentrancy(t *testing.T) {
+	checkRule(t, "QLK-REENTRANCY", []ruleCase{
+		{"call before the balance update", `package vault
+
+type Bank interface{ Transfer(to string, amount int) error }
+
+type Vault struct {
+	bank     Bank
+	balances map[string]int
+}
+
+func (v *Vault) Withdraw(to string, amount int) error {
+	if err := v.bank.Transfer(to, amount); err != nil {
+		return err
+	}
+	v.balances[to] -= amount
+	return nil
+}
+`, []int{11}},
+		{"package map written after the call", `package vault
+
+type Bank interface{ Send(to string, amount int) }
+
+var balances = map[string]int{}
+
+func Withdraw(bank Bank, to string, amount int) {
+	bank.Send(to, amount)
+	balances[to] = 0
+}
+`, []int{8}},
+		{"checks-effects-interactions order", `package vault
+
+type Bank interface{ Transfer(to string, amount int) error }
+
+type Vault struct {
+	bank     Bank
+	balances map[string]int
+}
+
+func (v *Vault) Withdraw(to string, amount int) error {
+	v.balances[to] -= amount
+	return v.bank.Transfer(to, amount)
+}
+`, nil},
+		{"local state after the call", `package vault
+
+type Bank interface{ Send(to string, amount int) }
+
+func Withdraw(bank Bank, to string, amount int) int {
+	bank.Send(to, amount)
+	sent := map[string]int{}
+	sent[to] = amount
+	return len(sent)
+}
+`, nil},
+	})
+}
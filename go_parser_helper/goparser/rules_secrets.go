@@ -0,0 +1,230 @@
+package goparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SecretFinding is a string literal that looks like a hardcoded address,
+// key or owner. Preview shows only the ends of the value.
+type SecretFinding struct {
+	Issue    `proto:"1"`
+	Category string `json:"category" proto:"9"`
+	Preview  string `json:"preview" proto:"10"`
+}
+
+// SecretPattern matches whole string literals of one category. Severity
+// defaults to high.
+type SecretPattern struct {
+	Category string `json:"category"`
+	Pattern  string `json:"pattern"`
+	Severity string `json:"severity,omitempty"`
+	// needsDigit drops matches without a digit, which for a generic blob
+	// pattern are words or identifiers rather than keys.
+	needsDigit bool
+}
+
+var defaultSecretPatterns = []SecretPattern{
+	// Bech32 data excludes 1, b, i and o.
+	{Category: "bech32_address", Pattern: `^[a-z]{2,12}1[02-9ac-hj-np-z]{38,58}$`, Severity: SeverityMedium},
+	{Category: "hex_address", Pattern: `^0x[0-9a-fA-F]{40}$`, Severity: SeverityMedium},
+	{Category: "hex_key", Pattern: `^(0x)?[0-9a-fA-F]{64}$`},
+	{Category: "key_blob", Pattern: `^([0-9a-fA-F]{80,}|[A-Za-z0-9+/]{40,}={0,2})$`, needsDigit: true},
+}
+
+// ownerNameParts mark a field or variable as holding a privileged account.
+var ownerNameParts = []string{"owner", "admin", "authority", "governance", "operator"}
+
+// LoadSecretPatterns reads a JSON array of SecretPattern, checking that every
+// pattern compiles.
+func LoadSecretPatterns(path string) ([]SecretPattern, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret patterns: %v", err)
+	}
+	var patterns []SecretPattern
+	if err := json.Unmarshal(data, &patterns); err != nil {
+		return nil, fmt.Errorf("invalid secret patterns %s: %v", path, err)
+	}
+	for i, p := range patterns {
+		if p.Category == "" {
+			return nil, fmt.Errorf("invalid secret patterns %s: entry %d has no category", path, i)
+		}
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid secret patterns %s: entry %d: %v", path, i, err)
+		}
+	}
+	return patterns, nil
+}
+
+// redact keeps the first six and last four characters of a long value and
+// the first two of a short one.
+func redact(value string) string {
+	if len(value) > 12 {
+		return value[:6] + "..." + value[len(value)-4:]
+	}
+	if len(value) > 2 {
+		return value[:2] + strings.Repeat("*", len(value)-2)
+	}
+	return strings.Repeat("*", len(value))
+}
+
+func hasDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
+
+func isOwnerName(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range ownerNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectSecrets flags string literals matching the secret patterns, and
+// literals assigned to owner or credential names. Import paths and struct
+// tags are not scanned.
+func (v *GoVisitor) detectSecrets(file *ast.File) {
+	type matcher struct {
+		SecretPattern
+		re *regexp.Regexp
+	}
+	var matchers []matcher
+	for _, p := range append(append([]SecretPattern{}, defaultSecretPatterns...), v.config.SecretPatterns...) {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			continue
+		}
+		if p.Severity == "" {
+			p.Severity = SeverityHigh
+		}
+		matchers = append(matchers, matcher{p, re})
+	}
+
+	// function is the display name of the function being scanned, empty at
+	// package scope.
+	function := ""
+	reported := map[*ast.BasicLit]bool{}
+	report := func(lit *ast.BasicLit, value, category, severity, message string) {
+		if reported[lit] {
+			return
+		}
+		reported[lit] = true
+		v.redactions[lit.Value] = strconv.Quote(redact(value))
+		v.result.SecretFindings = append(v.result.SecretFindings, SecretFinding{
+			Issue: Issue{
+				RuleID:   "QLK-HARDCODED-SECRET",
+				Severity: severity,
+				Message:  message,
+				Function: function,
+				Span:     v.span(lit),
+			},
+			Category: category,
+			Preview:  redact(value),
+		})
+	}
+	// match returns the first pattern value matches, or nil.
+	match := func(value string) *matcher {
+		for i := range matchers {
+			m := &matchers[i]
+			if m.re.MatchString(value) && (!m.needsDigit || hasDigit(value)) {
+				return m
+			}
+		}
+		return nil
+	}
+	reportMatch := func(lit *ast.BasicLit, value string, m *matcher) {
+		report(lit, value, m.Category, m.Severity,
+			fmt.Sprintf("string literal looks like a hardcoded %s (%s)", strings.ReplaceAll(m.Category, "_", " "), redact(value)))
+	}
+	literal := func(expr ast.Expr) (*ast.BasicLit, string) {
+		lit, ok := expr.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return nil, ""
+		}
+		value, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return nil, ""
+		}
+		return lit, value
+	}
+	// named checks a literal bound to a name: an owner account or a
+	// credential spelled out in source. A pattern match names the category
+	// more precisely, so it wins.
+	named := func(name string, expr ast.Expr) {
+		lit, value := literal(expr)
+		if lit == nil || value == "" {
+			return
+		}
+		switch m := match(value); {
+		case m != nil:
+			reportMatch(lit, value, m)
+		case isOwnerName(name):
+			report(lit, value, "hardcoded_owner", SeverityMedium,
+				fmt.Sprintf("%s is set to the hardcoded account %q", name, redact(value)))
+		case isSensitiveName(name) && len(value) >= 8:
+			report(lit, value, "hardcoded_credential", SeverityHigh,
+				fmt.Sprintf("%s is set to a hardcoded value", name))
+		}
+	}
+
+	scan := func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.ImportSpec, *ast.Field:
+			return false
+		case *ast.AssignStmt:
+			if len(e.Lhs) == len(e.Rhs) {
+				for i, lhs := range e.Lhs {
+					named(lastName(lhs), e.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range e.Names {
+				if i < len(e.Values) {
+					named(name.Name, e.Values[i])
+				}
+			}
+		case *ast.KeyValueExpr:
+			if key, ok := e.Key.(*ast.Ident); ok {
+				named(key.Name, e.Value)
+			}
+		case *ast.BasicLit:
+			if lit, value := literal(e); lit != nil {
+				if m := match(value); m != nil {
+					reportMatch(lit, value, m)
+				}
+			}
+		}
+		return true
+	}
+	for _, decl := range file.Decls {
+		function = ""
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			function = funcDisplayName(fn)
+		}
+		ast.Inspect(decl, scan)
+	}
+}
+
+// lastName returns the identifier an assignment target ends in: x for x,
+// s.x and s.x[i].
+func lastName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.IndexExpr:
+		return lastName(e.X)
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package goparser
+
+import "testing"
+
+func TestHardcodedSecret(t *testing.T) {
+	checkRule(t, "QLK-HARDCODED-SECRET", []ruleCase{
+		{"hex address", `package p
+
+func Treasury() string {
+	return "0x52908400098527886E0F7030069857D2E4169EE7"
+}
+`, []int{4}},
+		{"owner and credential names", `package p
+
+var owner = "treasury-multisig"
+
+type Config struct{ Password string }
+
+var cfg = Config{
+	Password: "hunter2hunter2",
+}
+`, []int{3, 8}},
+		{"ordinary strings", `package p
+
+var greeting = "hello, world"
+
+var denom = "uatom"
+`, nil},
+		{"word-like blob without digits", `package p
+
+var doc = "ThisIsAVeryLongIdentifierWithoutAnyDigitsAtAll"
+`, nil},
+		{"short credential and empty owner", `package p
+
+var password = "short"
+
+var admin = ""
+`, nil},
+	})
+}
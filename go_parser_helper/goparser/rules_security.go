@@ -0,0 +1,178 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// detectUnsafeUsage reports imports of the unsafe package and every selector
+// through it, resolving the local name so aliased imports are caught too.
+func (v *GoVisitor) detectUnsafeUsage(file *ast.File) {
+	for _, imp := range file.Imports {
+		if strings.Trim(imp.Path.Value, `"`) != "unsafe" {
+			continue
+		}
+		v.result.UnsafeUsage = append(v.result.UnsafeUsage, Issue{
+			RuleID:   "QLK-UNSAFE",
+			Severity: SeverityHigh,
+			Message:  "imports package unsafe, which bypasses Go's type safety",
+			Span:     v.span(imp),
+		})
+	}
+
+	local := map[string]bool{}
+	for name, path := range importLocalNames(file) {
+		if path == "unsafe" {
+			local[name] = true
+		}
+	}
+	if len(local) == 0 {
+		return
+	}
+	for _, decl := range file.Decls {
+		function := ""
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			function = funcDisplayName(fn)
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if id, ok := sel.X.(*ast.Ident); ok && local[id.Name] {
+				v.result.UnsafeUsage = append(v.result.UnsafeUsage, Issue{
+					RuleID:   "QLK-UNSAFE",
+					Severity: SeverityHigh,
+					Message:  fmt.Sprintf("uses unsafe.%s", sel.Sel.Name),
+					Function: function,
+					Span:     v.span(sel),
+				})
+			}
+			return true
+		})
+	}
+}
+
+// sensitiveWords are the name components that mark an identifier as holding
+// key material or credentials.
+var sensitiveWords = map[string]bool{
+	"password":   true,
+	"passwd":     true,
+	"passphrase": true,
+	"mnemonic":   true,
+	"seed":       true,
+	"secret":     true,
+	"token":      true,
+	"privkey":    true,
+	"apikey":     true,
+}
+
+// isSensitiveName reports whether an identifier, split on camelCase and
+// underscores, contains a sensitive word or a "private key" pair.
+func isSensitiveName(name string) bool {
+	var words []string
+	start := 0
+	for i := 1; i <= len(name); i++ {
+		if i == len(name) || name[i] == '_' || (name[i] >= 'A' && name[i] <= 'Z' && name[i-1] >= 'a' && name[i-1] <= 'z') {
+			if word := strings.ToLower(strings.Trim(name[start:i], "_")); word != "" {
+				words = append(words, word)
+			}
+			start = i
+		}
+	}
+	for i, word := range words {
+		if sensitiveWords[word] {
+			return true
+		}
+		if (word == "private" || word == "priv") && i+1 < len(words) && words[i+1] == "key" {
+			return true
+		}
+	}
+	return false
+}
+
+var printFuncs = map[string]bool{
+	"Print": true, "Printf": true, "Println": true,
+	"Fprint": true, "Fprintf": true, "Fprintln": true,
+}
+
+var logMethods = map[string]bool{
+	"Print": true, "Printf": true, "Println": true,
+	"Fatal": true, "Fatalf": true, "Fatalln": true,
+	"Panic": true, "Panicf": true, "Panicln": true,
+	"Debug": true, "Debugf": true, "Info": true, "Infof": true,
+	"Warn": true, "Warnf": true, "Error": true, "Errorf": true,
+}
+
+// isLogCall reports whether call prints or logs: the print builtins, fmt and
+// log package output functions, and level methods on anything named like a
+// logger, such as ctx.Logger().Info.
+func isLogCall(call *ast.CallExpr, imports map[string]string) bool {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name == "print" || fun.Name == "println"
+	case *ast.SelectorExpr:
+		if id, ok := fun.X.(*ast.Ident); ok {
+			switch imports[id.Name] {
+			case "fmt":
+				return printFuncs[fun.Sel.Name]
+			case "log", "log/slog":
+				return logMethods[fun.Sel.Name]
+			}
+		}
+		if !logMethods[fun.Sel.Name] {
+			return false
+		}
+		receiver := fun.X
+		if inner, ok := receiver.(*ast.CallExpr); ok {
+			receiver = inner.Fun
+		}
+		var name string
+		switch r := receiver.(type) {
+		case *ast.Ident:
+			name = r.Name
+		case *ast.SelectorExpr:
+			name = r.Sel.Name
+		}
+		return strings.Contains(strings.ToLower(name), "log")
+	}
+	return false
+}
+
+// detectSensitiveLogging flags log and print calls whose arguments reference
+// identifiers named like secrets.
+func (v *GoVisitor) detectSensitiveLogging(file *ast.File) {
+	imports := importLocalNames(file)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || !isLogCall(call, imports) {
+				return true
+			}
+			reported := map[string]bool{}
+			for _, arg := range call.Args {
+				ast.Inspect(arg, func(n ast.Node) bool {
+					id, ok := n.(*ast.Ident)
+					if !ok || reported[id.Name] || !isSensitiveName(id.Name) {
+						return true
+					}
+					reported[id.Name] = true
+					v.result.SensitiveLogging = append(v.result.SensitiveLogging, Issue{
+						RuleID:   "QLK-SENSITIVE-LOGGING",
+						Severity: SeverityHigh,
+						Message:  fmt.Sprintf("%s is passed to %s and may leak secret material", id.Name, v.nodeText(call.Fun)),
+						Function: funcDisplayName(fn),
+						Span:     v.span(call),
+					})
+					return true
+				})
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package goparser
+
+import "testing"
+
+func TestUnsafe(t *testing.T) {
+	checkRule(t, "QLK-UNSAFE", []ruleCase{
+		{"import and selector", `package p
+
+import "unsafe"
+
+func Size(n int) uintptr {
+	return unsafe.Sizeof(n)
+}
+`, []int{3, 6}},
+		{"aliased import", `package p
+
+import u "unsafe"
+
+var size = u.Sizeof(0)
+`, []int{3, 5}},
+		{"no unsafe import", `package p
+
+type unsafe struct{ Sizeof int }
+
+func Size(u unsafe) int {
+	return u.Sizeof
+}
+`, nil},
+	})
+}
+
+func TestSensitiveLogging(t *testing.T) {
+	checkRule(t, "QLK-SENSITIVE-LOGGING", []ruleCase{
+		{"secret passed to fmt and a logger", `package p
+
+import "fmt"
+
+type Logger interface{ Info(msg string, kv ...interface{}) }
+
+func Login(logger Logger, user, password string, privKey []byte) {
+	fmt.Printf("login %s %s\n", user, password)
+	logger.Info("signing", "key", privKey)
+}
+`, []int{8, 9}},
+		{"nothing sensitive", `package p
+
+import "fmt"
+
+func Login(user string) {
+	fmt.Printf("login %s\n", user)
+}
+`, nil},
+		{"secret used outside a log call", `package p
+
+import "fmt"
+
+func Login(password string) error {
+	if len(password) < 8 {
+		return fmt.Errorf("too short")
+	}
+	return nil
+}
+`, nil},
+	})
+}
@@ -0,0 +1,949 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// isMapTypeExpr reports whether expr is a map type or constructs a map via
+// make(map[...]...) or a map composite literal.
+func isMapTypeExpr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.MapType:
+		return true
+	case *ast.CompositeLit:
+		_, ok := e.Type.(*ast.MapType)
+		return ok
+	case *ast.CallExpr:
+		if id, ok := e.Fun.(*ast.Ident); ok && id.Name == "make" && len(e.Args) > 0 {
+			_, ok := e.Args[0].(*ast.MapType)
+			return ok
+		}
+	}
+	return false
+}
+
+// packageVars returns the package-level variable names, and the subset of
+// them holding maps.
+func packageVars(file *ast.File) (vars, maps map[string]bool) {
+	vars, maps = map[string]bool{}, map[string]bool{}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				vars[name.Name] = true
+				if (vs.Type != nil && isMapTypeExpr(vs.Type)) || (i < len(vs.Values) && isMapTypeExpr(vs.Values[i])) {
+					maps[name.Name] = true
+				}
+			}
+		}
+	}
+	return vars, maps
+}
+
+// mapScope answers "is this expression a map?" without type information,
+// from struct field declarations, package variables and a function's
+// parameters and locals.
+type mapScope struct {
+	fields map[string]bool
+	names  map[string]bool
+}
+
+func (v *GoVisitor) newMapScope(file *ast.File, fn *ast.FuncDecl) *mapScope {
+	scope := &mapScope{fields: map[string]bool{}}
+	for _, st := range v.result.Structs {
+		for _, field := range st.Fields {
+			if strings.HasPrefix(field.Type, "map[") {
+				scope.fields[field.Name] = true
+			}
+		}
+	}
+	_, scope.names = packageVars(file)
+	if fn == nil {
+		return scope
+	}
+	if fn.Type.Params != nil {
+		for _, field := range fn.Type.Params.List {
+			if isMapTypeExpr(field.Type) {
+				for _, name := range field.Names {
+					scope.names[name.Name] = true
+				}
+			}
+		}
+	}
+	inspectBody(fn.Body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			if len(s.Lhs) == len(s.Rhs) {
+				for i, rhs := range s.Rhs {
+					if id, ok := s.Lhs[i].(*ast.Ident); ok && isMapTypeExpr(rhs) {
+						scope.names[id.Name] = true
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range s.Names {
+				if (s.Type != nil && isMapTypeExpr(s.Type)) || (i < len(s.Values) && isMapTypeExpr(s.Values[i])) {
+					scope.names[name.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	return scope
+}
+
+func (s *mapScope) isMap(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return s.names[e.Name]
+	case *ast.SelectorExpr:
+		return s.fields[e.Sel.Name]
+	case *ast.ParenExpr:
+		return s.isMap(e.X)
+	}
+	return false
+}
+
+// detectUncheckedMapLookups flags single-value map reads stored into struct
+// fields or package variables, which silently store the zero value when
+// the key is absent.
+func (v *GoVisitor) detectUncheckedMapLookups(file *ast.File) {
+	globals, _ := packageVars(file)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		scope := v.newMapScope(file, fn)
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			as, ok := n.(*ast.AssignStmt)
+			if !ok || len(as.Lhs) != 1 || len(as.Rhs) != 1 {
+				return true
+			}
+			index, ok := as.Rhs[0].(*ast.IndexExpr)
+			if !ok || !scope.isMap(index.X) {
+				return true
+			}
+			target := ""
+			switch lhs := as.Lhs[0].(type) {
+			case *ast.SelectorExpr:
+				target = v.nodeText(lhs)
+			case *ast.Ident:
+				if globals[lhs.Name] && as.Tok == token.ASSIGN {
+					target = lhs.Name
+				}
+			}
+			if target == "" {
+				return true
+			}
+			v.result.UncheckedMapLookup = append(v.result.UncheckedMapLookup, Issue{
+				RuleID:   "QLK-UNCHECKED-MAP-LOOKUP",
+				Severity: SeverityMedium,
+				Message:  fmt.Sprintf("%s is assigned from %s without a comma-ok check; a missing key stores the zero value", target, v.nodeText(index)),
+				Function: funcDisplayName(fn),
+				Span:     v.span(as),
+			})
+			return true
+		})
+	}
+}
+
+var storeKeyCalls = map[string]bool{"Get": true, "Set": true, "Has": true, "Delete": true}
+
+// keyComponent classifies one piece of a concatenated key as "variable"
+// (addresses, strings, Bytes()), "delimited" (a separator byte or a
+// length-prefixed or fixed-width encoding) or "fixed".
+func keyComponent(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.BasicLit, *ast.CompositeLit:
+		return "delimited"
+	case *ast.CallExpr:
+		name := calleeName(e)
+		switch {
+		case strings.Contains(name, "LengthPrefix"):
+			return "delimited"
+		case strings.Contains(name, "BigEndian"), strings.Contains(name, "LittleEndian"):
+			return "fixed"
+		}
+		if arr, ok := e.Fun.(*ast.ArrayType); ok && arr.Len == nil && len(e.Args) == 1 {
+			if _, ok := e.Args[0].(*ast.BasicLit); ok {
+				return "delimited"
+			}
+		}
+		return "variable"
+	case *ast.Ident:
+		return namedKeyComponent(e.Name)
+	case *ast.SelectorExpr:
+		return namedKeyComponent(e.Sel.Name)
+	}
+	return "variable"
+}
+
+// namedKeyComponent classifies an identifier component by its name: KeyPrefix
+// is fixed, Separator delimits, anything else is assumed variable.
+func namedKeyComponent(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "sep"), strings.Contains(name, "delim"):
+		return "delimited"
+	case strings.Contains(name, "prefix"):
+		return "fixed"
+	}
+	return "variable"
+}
+
+// keyComponents flattens nested append calls into their components. A plain
+// identifier as the innermost base is the key prefix and is left out;
+// single bytes appended between components are kept as separators.
+func keyComponents(call *ast.CallExpr) []ast.Expr {
+	var parts []ast.Expr
+	if len(call.Args) == 0 {
+		return parts
+	}
+	if inner, ok := call.Args[0].(*ast.CallExpr); ok {
+		if calleeName(inner) == "append" {
+			parts = append(parts, keyComponents(inner)...)
+		} else {
+			parts = append(parts, inner)
+		}
+	}
+	for i, arg := range call.Args[1:] {
+		if call.Ellipsis.IsValid() && i == len(call.Args)-2 {
+			parts = append(parts, arg)
+		} else {
+			parts = append(parts, &ast.BasicLit{ValuePos: arg.Pos()})
+		}
+	}
+	return parts
+}
+
+// detectKeyCollisionRisk flags store keys built by appending two or more
+// variable-length components with no separator or length prefix between
+// them, which lets distinct (a, b) pairs produce the same key.
+func (v *GoVisitor) detectKeyCollisionRisk(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		keyFunc := strings.Contains(strings.ToLower(fn.Name.Name), "key")
+		var candidates []*ast.CallExpr
+		addCandidate := func(expr ast.Expr) {
+			if call, ok := expr.(*ast.CallExpr); ok && calleeName(call) == "append" {
+				candidates = append(candidates, call)
+			}
+		}
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.CallExpr:
+				if calleeName(s) == "append" {
+					if keyFunc {
+						candidates = append(candidates, s)
+					}
+					return false
+				}
+				if storeKeyCalls[calleeName(s)] && len(s.Args) > 0 {
+					addCandidate(s.Args[0])
+				}
+			case *ast.AssignStmt:
+				for i, lhs := range s.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok && i < len(s.Rhs) && strings.Contains(strings.ToLower(id.Name), "key") {
+						addCandidate(s.Rhs[i])
+					}
+				}
+			}
+			return true
+		})
+
+		seen := map[*ast.CallExpr]bool{}
+		for _, call := range candidates {
+			if seen[call] {
+				continue
+			}
+			seen[call] = true
+			variable := 0
+			delimited := false
+			for _, part := range keyComponents(call) {
+				switch keyComponent(part) {
+				case "variable":
+					variable++
+				case "delimited":
+					delimited = true
+				}
+			}
+			if variable < 2 || delimited {
+				continue
+			}
+			v.result.KeyCollisionRisk = append(v.result.KeyCollisionRisk, Issue{
+				RuleID:   "QLK-KEY-COLLISION",
+				Severity: SeverityMedium,
+				Message:  fmt.Sprintf("store key %s concatenates %d variable-length components without a separator or length prefix", v.nodeText(call), variable),
+				Function: funcDisplayName(fn),
+				Span:     v.span(call),
+			})
+		}
+	}
+}
+
+// StoreKeyFinding is a store access whose key is formatted or concatenated
+// text rather than built from length-prefixed components.
+type StoreKeyFinding struct {
+	Issue  `proto:"1"`
+	Method string `json:"method" proto:"9"` // Set, Get, Has or Delete
+	Key    string `json:"key" proto:"10"`
+	// Construction is "sprintf" or "concat".
+	Construction string `json:"construction" proto:"11"`
+}
+
+// storeOpeners are the calls that return a KVStore.
+var storeOpeners = map[string]bool{"KVStore": true, "TransientStore": true, "NewStore": true}
+
+// isStoreExpr reports whether expr is a store: a call opening one, a local
+// assigned from such a call, or a name ending in "store".
+func isStoreExpr(expr ast.Expr, locals map[string]bool) bool {
+	switch e := expr.(type) {
+	case *ast.CallExpr:
+		return storeOpeners[calleeName(e)]
+	case *ast.Ident:
+		return locals[e.Name] || strings.HasSuffix(strings.ToLower(e.Name), "store")
+	case *ast.SelectorExpr:
+		return strings.HasSuffix(strings.ToLower(e.Sel.Name), "store")
+	case *ast.ParenExpr:
+		return isStoreExpr(e.X, locals)
+	}
+	return false
+}
+
+// stringKeyConstruction returns "sprintf" or "concat" when expr, after
+// unwrapping []byte conversions, is a fmt.Sprintf call or a + expression.
+func stringKeyConstruction(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return stringKeyConstruction(e.X)
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			return "concat"
+		}
+	case *ast.CallExpr:
+		if arr, ok := e.Fun.(*ast.ArrayType); ok && arr.Len == nil && len(e.Args) == 1 {
+			return stringKeyConstruction(e.Args[0])
+		}
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Sprintf" {
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "fmt" {
+				return "sprintf"
+			}
+		}
+	}
+	return ""
+}
+
+// detectStringStoreKeys flags store Set, Get, Has and Delete calls whose key
+// is built with fmt.Sprintf or +, directly or through a local assigned
+// once in the function. Text keys have no length prefix, so
+// Sprintf("%s%s", "ab", "c") and Sprintf("%s%s", "a", "bc") collide.
+func (v *GoVisitor) detectStringStoreKeys(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		stores := map[string]bool{}
+		// built maps a local to the text-key expression it was assigned.
+		built := map[string]ast.Expr{}
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			as, ok := n.(*ast.AssignStmt)
+			if !ok || len(as.Lhs) != len(as.Rhs) {
+				return true
+			}
+			for i, lhs := range as.Lhs {
+				id, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if call, ok := as.Rhs[i].(*ast.CallExpr); ok && storeOpeners[calleeName(call)] {
+					stores[id.Name] = true
+				}
+				if stringKeyConstruction(as.Rhs[i]) != "" {
+					built[id.Name] = as.Rhs[i]
+				}
+			}
+			return true
+		})
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || !storeKeyCalls[sel.Sel.Name] || !isStoreExpr(sel.X, stores) {
+				return true
+			}
+			key := call.Args[0]
+			name := key
+			if conv, ok := key.(*ast.CallExpr); ok && len(conv.Args) == 1 {
+				if arr, ok := conv.Fun.(*ast.ArrayType); ok && arr.Len == nil {
+					name = conv.Args[0]
+				}
+			}
+			if id, ok := name.(*ast.Ident); ok && built[id.Name] != nil {
+				key = built[id.Name]
+			}
+			construction := stringKeyConstruction(key)
+			if construction == "" {
+				return true
+			}
+			how := "fmt.Sprintf"
+			if construction == "concat" {
+				how = "string concatenation"
+			}
+			v.result.StoreKeyFindings = append(v.result.StoreKeyFindings, StoreKeyFinding{
+				Issue: Issue{
+					RuleID:   "QLK-STRING-STORE-KEY",
+					Severity: SeverityMedium,
+					Message:  fmt.Sprintf("store %s key %s is built with %s; without length prefixes distinct inputs can produce the same key", sel.Sel.Name, v.nodeText(key), how),
+					Function: funcDisplayName(fn),
+					Span:     v.span(call.Args[0]),
+				},
+				Method:       sel.Sel.Name,
+				Key:          v.nodeText(key),
+				Construction: construction,
+			})
+			return true
+		})
+	}
+}
+
+// isVersionedTypeName reports whether a type name marks a versioned state
+// layout: StateV1, ParamsV2, PoolLegacy.
+func isVersionedTypeName(name string) bool {
+	if strings.HasSuffix(name, "Legacy") {
+		return true
+	}
+	digits := strings.TrimRight(name, "0123456789")
+	return len(digits) < len(name) && len(digits) > 1 && strings.HasSuffix(digits, "V")
+}
+
+// isMigrationFunc reports whether a function name or call looks like a store
+// migration or upgrade handler (Migrate1to2, migrateStore,
+// SetUpgradeHandler, RegisterMigration).
+func isMigrationFunc(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "migrat") || strings.Contains(lower, "upgradehandler")
+}
+
+// detectMissingMigrations flags files declaring versioned state types with
+// no migration or upgrade handler declared or registered alongside them.
+func (v *GoVisitor) detectMissingMigrations(file *ast.File) {
+	var versioned []*ast.TypeSpec
+	migrates := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.TypeSpec:
+			if isVersionedTypeName(s.Name.Name) {
+				versioned = append(versioned, s)
+			}
+		case *ast.FuncDecl:
+			migrates = migrates || isMigrationFunc(s.Name.Name)
+		case *ast.CallExpr:
+			migrates = migrates || isMigrationFunc(calleeName(s))
+		}
+		return true
+	})
+	if len(versioned) == 0 || migrates {
+		return
+	}
+	var names []string
+	for _, ts := range versioned {
+		names = append(names, ts.Name.Name)
+	}
+	v.result.MigrationIssues = append(v.result.MigrationIssues, Issue{
+		RuleID:   "QLK-MISSING-MIGRATION",
+		Severity: SeverityLow,
+		Message:  fmt.Sprintf("versioned state types %s have no migration or upgrade handler in this file", strings.Join(names, ", ")),
+		Span:     v.span(versioned[0]),
+	})
+}
+
+// OverflowRisk is arithmetic on stored state that can wrap around.
+type OverflowRisk struct {
+	Issue    `proto:"1"`
+	Operator string `json:"operator" proto:"9"`
+	Target   string `json:"target" proto:"10"`
+}
+
+// detectOverflowRisks flags +=, -= and *= (and x = x + y, x = x - y) on a
+// map or slice element or a field, unless an earlier if in the function
+// compares the target or the operand. Types are not inferred, so the
+// target may not be an unsigned integer at all. A statement that
+// detectMapAccessRisks also reported is reported once, here, with the
+// zero-value insert noted in the message.
+func (v *GoVisitor) detectOverflowRisks(file *ast.File) {
+	inserts := map[Span]bool{}
+	for _, risk := range v.result.MapAccessRisks {
+		inserts[risk.Span] = true
+	}
+	folded := map[Span]bool{}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		// compared holds the text of every operand of a comparison seen so
+		// far in the function.
+		compared := map[string]bool{}
+		inspectBody(fn.Body, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.IfStmt:
+				for _, cmp := range comparisons(s.Cond) {
+					compared[v.nodeText(cmp.X)] = true
+					compared[v.nodeText(cmp.Y)] = true
+				}
+			case *ast.AssignStmt:
+				if len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+					return true
+				}
+				switch s.Lhs[0].(type) {
+				case *ast.IndexExpr, *ast.SelectorExpr:
+				default:
+					return true
+				}
+				target := v.nodeText(s.Lhs[0])
+				var op token.Token
+				var operand ast.Expr
+				switch s.Tok {
+				case token.ADD_ASSIGN, token.SUB_ASSIGN, token.MUL_ASSIGN:
+					op, operand = s.Tok, s.Rhs[0]
+				case token.ASSIGN:
+					bin, ok := s.Rhs[0].(*ast.BinaryExpr)
+					if !ok || (bin.Op != token.ADD && bin.Op != token.SUB) || v.nodeText(bin.X) != target {
+						return true
+					}
+					op, operand = bin.Op, bin.Y
+				default:
+					return true
+				}
+				// Adding or scaling by a literal is a counter or a string
+				// concatenation, not a transfer.
+				if _, ok := operand.(*ast.BasicLit); ok && op != token.SUB_ASSIGN && op != token.SUB {
+					return true
+				}
+				if compared[target] || compared[v.nodeText(operand)] {
+					return true
+				}
+				kind := "overflow"
+				if op == token.SUB_ASSIGN || op == token.SUB {
+					kind = "underflow"
+				}
+				message := fmt.Sprintf("%s %s %s is not guarded by a comparison and can %s if %s is unsigned (type not inferred)", target, op, v.nodeText(operand), kind, target)
+				span := v.span(s)
+				if inserts[span] {
+					folded[span] = true
+					message += "; a missing key is also inserted with the zero value"
+				}
+				v.result.OverflowRisks = append(v.result.OverflowRisks, OverflowRisk{
+					Issue: Issue{
+						RuleID:   "QLK-INTEGER-OVERFLOW",
+						Severity: SeverityHigh,
+						Message:  message,
+						Function: funcDisplayName(fn),
+						Span:     span,
+					},
+					Operator: op.String(),
+					Target:   target,
+				})
+			}
+			return true
+		})
+	}
+	risks := v.result.MapAccessRisks[:0]
+	for _, risk := range v.result.MapAccessRisks {
+		if !folded[risk.Span] {
+			risks = append(risks, risk)
+		}
+	}
+	v.result.MapAccessRisks = risks
+}
+
+// MapAccessRisk is a read-modify-write of a map entry that inserts the zero
+// value when the key is absent.
+type MapAccessRisk struct {
+	Issue `proto:"1"`
+	Map   string `json:"map" proto:"9"`
+	Key   string `json:"key" proto:"10"`
+}
+
+// commaOkKey returns the text of m[k] when stmt is a comma-ok lookup, as in
+// "_, ok := m[k]", possibly as the init of an if.
+func (v *GoVisitor) commaOkKey(stmt ast.Stmt) string {
+	if is, ok := stmt.(*ast.IfStmt); ok && is.Init != nil {
+		stmt = is.Init
+	}
+	as, ok := stmt.(*ast.AssignStmt)
+	if !ok || len(as.Lhs) != 2 || len(as.Rhs) != 1 {
+		return ""
+	}
+	if index, ok := as.Rhs[0].(*ast.IndexExpr); ok {
+		return v.nodeText(index)
+	}
+	return ""
+}
+
+// existenceChecked reports whether a comma-ok lookup of entry precedes the
+// innermost node of path in one of its enclosing blocks, or is the init of
+// an enclosing if.
+func (v *GoVisitor) existenceChecked(path []ast.Node, entry string) bool {
+	for i := len(path) - 2; i >= 0; i-- {
+		var list []ast.Stmt
+		switch n := path[i].(type) {
+		case *ast.IfStmt:
+			if n.Init != nil && v.commaOkKey(n.Init) == entry {
+				return true
+			}
+			continue
+		case *ast.BlockStmt:
+			list = n.List
+		case *ast.CaseClause:
+			list = n.Body
+		case *ast.CommClause:
+			list = n.Body
+		default:
+			continue
+		}
+		for _, stmt := range list {
+			if stmt == path[i+1] {
+				break
+			}
+			if v.commaOkKey(stmt) == entry {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// detectMapAccessRisks flags compound assignments and increments of entries
+// in struct field or package-level maps with no comma-ok check of the same
+// key before them: a missing key is silently inserted with the zero value,
+// which can hide a transfer to or from an account that was never created.
+// Maps local to the function, such as counters, are left alone.
+func (v *GoVisitor) detectMapAccessRisks(file *ast.File) {
+	globals, _ := packageVars(file)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		scope := v.newMapScope(file, fn)
+		var path []ast.Node
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if n == nil {
+				path = path[:len(path)-1]
+				return true
+			}
+			path = append(path, n)
+			var target ast.Expr
+			var op token.Token
+			switch s := n.(type) {
+			case *ast.IncDecStmt:
+				target, op = s.X, s.Tok
+			case *ast.AssignStmt:
+				if s.Tok == token.ASSIGN || s.Tok == token.DEFINE || len(s.Lhs) != 1 {
+					return true
+				}
+				target, op = s.Lhs[0], s.Tok
+			default:
+				return true
+			}
+			index, ok := target.(*ast.IndexExpr)
+			if !ok || !scope.isMap(index.X) {
+				return true
+			}
+			if id, ok := index.X.(*ast.Ident); ok && !globals[id.Name] {
+				return true
+			}
+			entry := v.nodeText(index)
+			if v.existenceChecked(path, entry) {
+				return true
+			}
+			v.result.MapAccessRisks = append(v.result.MapAccessRisks, MapAccessRisk{
+				Issue: Issue{
+					RuleID:   "QLK-MAP-ZERO-INSERT",
+					Severity: SeverityMedium,
+					Message:  fmt.Sprintf("%s on %s inserts a zero-valued entry when %s is absent; check it with a comma-ok lookup first", op, entry, v.nodeText(index.Index)),
+					Function: funcDisplayName(fn),
+					Span:     v.span(n),
+				},
+				Map: v.nodeText(index.X),
+				Key: v.nodeText(index.Index),
+			})
+			return true
+		})
+	}
+}
+
+// RangeCopyFinding is a field assignment to a range loop's value variable,
+// which holds a copy of the element when the elements are not pointers.
+type RangeCopyFinding struct {
+	Issue      `proto:"1"`
+	Variable   string `json:"variable" proto:"9"`
+	Collection string `json:"collection" proto:"10"`
+	// ElementType is "" when the collection's type is not declared in the
+	// file.
+	ElementType string `json:"element_type,omitempty" proto:"11"`
+	Assignment  string `json:"assignment" proto:"12"`
+}
+
+// exprType renders the type of a value expression when the file states it:
+// a composite literal, make or new, a call to a local function with one
+// result, or a name resolved by declaredType.
+func (v *GoVisitor) exprType(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return v.exprType(e.X)
+	case *ast.CompositeLit:
+		if e.Type != nil {
+			return v.typeToString(e.Type)
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := e.X.(*ast.CompositeLit); ok && e.Op == token.AND && lit.Type != nil {
+			return "*" + v.typeToString(lit.Type)
+		}
+	case *ast.CallExpr:
+		switch calleeName(e) {
+		case "make":
+			if len(e.Args) > 0 {
+				return v.typeToString(e.Args[0])
+			}
+		case "new":
+			if len(e.Args) > 0 {
+				return "*" + v.typeToString(e.Args[0])
+			}
+		}
+		if results := v.localResultTypes(e); len(results) == 1 {
+			return results[0]
+		}
+	case *ast.Ident, *ast.SelectorExpr:
+		return v.declaredType(e)
+	}
+	return ""
+}
+
+// declaredType returns the type a variable or field was declared with in
+// the file: a parameter, receiver or var declaration, the value of a :=
+// assignment, or a field of a struct declared in the file. It returns ""
+// when the declaration does not say.
+func (v *GoVisitor) declaredType(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return v.declaredType(e.X)
+	case *ast.Ident:
+		if e.Obj == nil {
+			return ""
+		}
+		switch d := e.Obj.Decl.(type) {
+		case *ast.Field:
+			return v.typeToString(d.Type)
+		case *ast.ValueSpec:
+			if d.Type != nil {
+				return v.typeToString(d.Type)
+			}
+			for i, name := range d.Names {
+				if name.Name == e.Name && i < len(d.Values) {
+					return v.exprType(d.Values[i])
+				}
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range d.Lhs {
+				id, ok := lhs.(*ast.Ident)
+				if !ok || id.Name != e.Name {
+					continue
+				}
+				if len(d.Lhs) == len(d.Rhs) {
+					return v.exprType(d.Rhs[i])
+				}
+				if call, ok := d.Rhs[0].(*ast.CallExpr); ok && len(d.Rhs) == 1 {
+					if results := v.localResultTypes(call); i < len(results) {
+						return results[i]
+					}
+				}
+			}
+		}
+	case *ast.SelectorExpr:
+		owner := baseTypeName(v.declaredType(e.X))
+		for _, st := range v.result.Structs {
+			if st.Name != owner {
+				continue
+			}
+			for _, field := range st.Fields {
+				if field.Name == e.Sel.Name {
+					return field.Type
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// underlyingType follows type declarations in the file from typ to the type
+// they name, stopping at types declared elsewhere.
+func (v *GoVisitor) underlyingType(typ string) string {
+	// At most one step per declaration, so a cycle cannot loop forever.
+	for range v.result.TypeDefs {
+		next := ""
+		for _, def := range v.result.TypeDefs {
+			if def.Name == typ {
+				next = def.Underlying
+			}
+		}
+		if next == "" {
+			break
+		}
+		typ = next
+	}
+	return typ
+}
+
+// rangeElementType returns the element type of a rendered slice, array,
+// pointer-to-array or map type, or "" for any other type.
+func (v *GoVisitor) rangeElementType(typ string) string {
+	typ = strings.TrimPrefix(v.underlyingType(typ), "*")
+	switch {
+	case strings.HasPrefix(typ, "map["):
+		depth := 0
+		for i, r := range typ {
+			switch r {
+			case '[':
+				depth++
+			case ']':
+				if depth--; depth == 0 {
+					return typ[i+1:]
+				}
+			}
+		}
+	case strings.HasPrefix(typ, "["):
+		if i := strings.Index(typ, "]"); i >= 0 {
+			return typ[i+1:]
+		}
+	}
+	return ""
+}
+
+// selectorRoot returns the identifier a chain of field selectors starts
+// from, item for item.Balance.Amount, or nil for any other expression.
+func selectorRoot(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.SelectorExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e
+		default:
+			return nil
+		}
+	}
+}
+
+// detectRangeCopyMutations flags range loops that assign a field of the
+// value variable and use it no other way, so the write is lost with the
+// copy. Collections of pointers are skipped; a collection whose element type
+// the file does not declare is reported at low severity. Loops that store,
+// pass or call methods on the variable after changing it are left alone.
+func (v *GoVisitor) detectRangeCopyMutations(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			rs, ok := n.(*ast.RangeStmt)
+			if !ok || rs.Tok != token.DEFINE || rs.Value == nil {
+				return true
+			}
+			value, ok := rs.Value.(*ast.Ident)
+			if !ok || value.Name == "_" {
+				return true
+			}
+			refersToValue := func(id *ast.Ident) bool {
+				if value.Obj != nil {
+					return id.Obj == value.Obj
+				}
+				return id.Name == value.Name
+			}
+
+			elem := v.rangeElementType(v.declaredType(rs.X))
+			if strings.HasPrefix(v.underlyingType(elem), "*") {
+				return true
+			}
+			// fieldBases are the uses of value as the base of a field
+			// access; any other use keeps the copy.
+			fieldBases := map[*ast.Ident]bool{}
+			methodCalls := map[ast.Expr]bool{}
+			var assigned ast.Expr
+			ast.Inspect(rs.Body, func(n ast.Node) bool {
+				var targets []ast.Expr
+				switch s := n.(type) {
+				case *ast.CallExpr:
+					methodCalls[s.Fun] = true
+				case *ast.SelectorExpr:
+					if id, ok := s.X.(*ast.Ident); ok && refersToValue(id) && !methodCalls[s] {
+						fieldBases[id] = true
+					}
+				case *ast.AssignStmt:
+					targets = s.Lhs
+				case *ast.IncDecStmt:
+					targets = []ast.Expr{s.X}
+				}
+				for _, target := range targets {
+					if _, ok := target.(*ast.SelectorExpr); !ok {
+						continue
+					}
+					if root := selectorRoot(target); root != nil && refersToValue(root) && assigned == nil {
+						assigned = target
+					}
+				}
+				return true
+			})
+			if assigned == nil {
+				return true
+			}
+			keepsCopy := false
+			ast.Inspect(rs.Body, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && refersToValue(id) && !fieldBases[id] {
+					keepsCopy = true
+				}
+				return !keepsCopy
+			})
+			if keepsCopy {
+				return true
+			}
+
+			severity := SeverityMedium
+			message := fmt.Sprintf("%s is a copy of each %s element of %s; assigning %s does not change the collection", value.Name, elem, v.nodeText(rs.X), v.nodeText(assigned))
+			if elem == "" {
+				severity = SeverityLow
+				message = fmt.Sprintf("%s may be a copy of each element of %s, whose element type is not declared in this file; assigning %s then does not change the collection", value.Name, v.nodeText(rs.X), v.nodeText(assigned))
+			}
+			v.result.RangeCopyFindings = append(v.result.RangeCopyFindings, RangeCopyFinding{
+				Issue: Issue{
+					RuleID:   "QLK-RANGE-COPY-MUTATION",
+					Severity: severity,
+					Message:  message,
+					Function: funcDisplayName(fn),
+					Span:     v.span(assigned),
+				},
+				Variable:    value.Name,
+				Collection:  v.nodeText(rs.X),
+				ElementType: elem,
+				Assignment:  v.nodeText(assigned),
+			})
+			return true
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package goparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntegerOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		body  string
+		lines []int
+	}{
+		{"debit a stored balance", `vc.balances[msg.From] -= msg.Amount`, []int{14}},
+		{"credit a stored balance", `vc.balances[msg.To] += msg.Amount`, []int{14}},
+		{"field arithmetic", `vc.supply = vc.supply - msg.Amount`, []int{14}},
+		{"guarded by a comparison", `if vc.balances[msg.From] < msg.Amount {
+		return
+	}
+	vc.balances[msg.From] -= msg.Amount`, nil},
+		{"literal counter", `vc.supply += 1`, nil},
+		{"local variable", `total := msg.Amount
+	total += msg.Amount
+	_ = total`, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := parseFixture(t, `package p
+
+type Msg struct {
+	From, To string
+	Amount   uint64
+}
+
+type Contract struct {
+	balances map[string]uint64
+	supply   uint64
+}
+
+func (vc *Contract) Transfer(msg Msg) {
+	`+tc.body+`
+}
+`)
+			if got := lines(findingsFor(result, "QLK-INTEGER-OVERFLOW")); !reflect.DeepEqual(got, tc.lines) {
+				t.Errorf("got findings on lines %v, want %v", got, tc.lines)
+			}
+		})
+	}
+}
+
+func TestIntegerOverflowAlongsideMapAccess(t *testing.T) {
+	result := parseFixture(t, `package p
+
+type Contract struct {
+	balances map[string]uint64
+}
+
+func (vc *Contract) Burn(from string, amount uint64) {
+	vc.balances[from] -= amount
+}
+`)
+	// The zero-value insert is folded into the overflow finding.
+	found := append(findingsFor(result, "QLK-INTEGER-OVERFLOW"), findingsFor(result, "QLK-MAP-ZERO-INSERT")...)
+	if len(found) != 1 || found[0].Line != 8 || found[0].RuleID != "QLK-INTEGER-OVERFLOW" {
+		t.Fatalf("got %v, want one QLK-INTEGER-OVERFLOW finding", found)
+	}
+	if !strings.Contains(found[0].Message, "inserted with the zero value") {
+		t.Errorf("message %q does not note the zero-value insert", found[0].Message)
+	}
+	if len(result.MapAccessRisks) != 0 {
+		t.Errorf("got %d map access risks, want the one folded into the overflow finding", len(result.MapAccessRisks))
+	}
+}
+
+func TestUncheckedMapLookup(t *testing.T) {
+	checkRule(t, "QLK-UNCHECKED-MAP-LOOKUP", []ruleCase{
+		{"field and package variable from a lookup", `package p
+
+var prices = map[string]int{}
+
+var current int
+
+type Pool struct{ Price int }
+
+func (p *Pool) Load(denom string) {
+	p.Price = prices[denom]
+	current = prices[denom]
+}
+`, []int{10, 11}},
+		{"comma-ok lookup", `package p
+
+var prices = map[string]int{}
+
+type Pool struct{ Price int }
+
+func (p *Pool) Load(denom string) {
+	if price, ok := prices[denom]; ok {
+		p.Price = price
+	}
+}
+`, nil},
+		{"local variable", `package p
+
+var prices = map[string]int{}
+
+func Load(denom string) int {
+	price := prices[denom]
+	return price
+}
+`, nil},
+		{"slice index", `package p
+
+var prices = []int{1, 2}
+
+type Pool struct{ Price int }
+
+func (p *Pool) Load(i int) {
+	p.Price = prices[i]
+}
+`, nil},
+	})
+}
+
+func TestKeyCollision(t *testing.T) {
+	checkRule(t, "QLK-KEY-COLLISION", []ruleCase{
+		{"two variable-length parts", `package keeper
+
+type Store interface{ Set(key, value []byte) }
+
+func Save(store Store, owner, denom string, value []byte) {
+	store.Set(append([]byte(owner), denom...), value)
+}
+`, []int{6}},
+		{"built in a key function", `package keeper
+
+var BalancePrefix = []byte{0x01}
+
+func BalanceKey(addr []byte, denom string) []byte {
+	return append(append(BalancePrefix, addr...), denom...)
+}
+`, []int{6}},
+		{"separator between parts", `package keeper
+
+type Store interface{ Set(key, value []byte) }
+
+func Save(store Store, owner, denom string, value []byte) {
+	store.Set(append(append([]byte(owner), '/'), denom...), value)
+}
+`, nil},
+		{"length-prefixed part", `package keeper
+
+func LengthPrefix(b []byte) []byte { return b }
+
+var BalancePrefix = []byte{0x01}
+
+func BalanceKey(addr []byte, denom string) []byte {
+	return append(append(BalancePrefix, LengthPrefix(addr)...), denom...)
+}
+`, nil},
+	})
+}
+
+func TestStringStoreKey(t *testing.T) {
+	checkRule(t, "QLK-STRING-STORE-KEY", []ruleCase{
+		{"Sprintf key", `package keeper
+
+import "fmt"
+
+type Store interface{ Get(key []byte) []byte }
+
+func Load(store Store, owner, denom string) []byte {
+	return store.Get([]byte(fmt.Sprintf("%s%s", owner, denom)))
+}
+`, []int{8}},
+		{"concatenated key through a local", `package keeper
+
+type Store interface{ Delete(key []byte) }
+
+func Remove(store Store, owner, denom string) {
+	key := owner + denom
+	store.Delete([]byte(key))
+}
+`, []int{7}},
+		{"byte key", `package keeper
+
+type Store interface{ Get(key []byte) []byte }
+
+func Load(store Store, key []byte) []byte {
+	return store.Get(key)
+}
+`, nil},
+		{"not a store", `package keeper
+
+import "fmt"
+
+type Cache interface{ Get(key string) string }
+
+func Load(cache Cache, owner, denom string) string {
+	return cache.Get(fmt.Sprintf("%s%s", owner, denom))
+}
+`, nil},
+	})
+}
+
+func TestMissingMigration(t *testing.T) {
+	checkRule(t, "QLK-MISSING-MIGRATION", []ruleCase{
+		{"versioned types without a migration", `package types
+
+type ParamsV1 struct{ Fee int }
+
+type ParamsV2 struct {
+	Fee    int
+	MaxFee int
+}
+`, []int{3}},
+		{"legacy type", `package types
+
+type PoolLegacy struct{ Reserve int }
+`, []int{3}},
+		{"migration declared", `package types
+
+type ParamsV1 struct{ Fee int }
+
+type ParamsV2 struct{ Fee, MaxFee int }
+
+func Migrate1to2(old ParamsV1) ParamsV2 {
+	return ParamsV2{Fee: old.Fee}
+}
+`, nil},
+		{"upgrade handler registered", `package app
+
+type Keeper struct{}
+
+func (Keeper) SetUpgradeHandler(name string, fn func()) {}
+
+type StateV2 struct{}
+
+func Register(k Keeper) {
+	k.SetUpgradeHandler("v2", func() {})
+}
+`, nil},
+		{"unversioned names", `package types
+
+type V2 struct{}
+
+type Params struct{ Fee int }
+`, nil},
+	})
+}
+
+func TestMapZeroInsert(t *testing.T) {
+	checkRule(t, "QLK-MAP-ZERO-INSERT", []ruleCase{
+		{"field and package maps", `package bank
+
+var votes = map[string]int{}
+
+type Bank struct{ balances map[string]uint64 }
+
+func (b *Bank) Credit(addr string) {
+	b.balances[addr] += 1
+	votes[addr]++
+}
+`, []int{8, 9}},
+		{"reported as overflow instead", `package bank
+
+type Bank struct{ balances map[string]uint64 }
+
+func (b *Bank) Credit(addr string, amount uint64) {
+	b.balances[addr] += amount
+}
+`, nil},
+		{"checked with comma-ok", `package bank
+
+type Bank struct{ balances map[string]uint64 }
+
+func (b *Bank) Credit(addr string, amount uint64) {
+	if _, ok := b.balances[addr]; !ok {
+		return
+	}
+	b.balances[addr] += amount
+}
+`, nil},
+		{"checked in the if header", `package bank
+
+type Bank struct{ balances map[string]uint64 }
+
+func (b *Bank) Credit(addr string, amount uint64) {
+	if _, ok := b.balances[addr]; ok {
+		b.balances[addr] += amount
+	}
+}
+`, nil},
+		{"local counter", `package bank
+
+func Count(words []string) map[string]int {
+	counts := map[string]int{}
+	for _, w := range words {
+		counts[w]++
+	}
+	return counts
+}
+`, nil},
+	})
+}
+
+func TestRangeCopyMutation(t *testing.T) {
+	checkRule(t, "QLK-RANGE-COPY-MUTATION", []ruleCase{
+		{"field of a struct copy", `package p
+
+type Pool struct{ Reserve int }
+
+func Reset(pools []Pool) {
+	for _, pool := range pools {
+		pool.Reserve = 0
+	}
+}
+`, []int{7}},
+		{"element type not declared", `package p
+
+import "example.com/types"
+
+func Reset(pools []types.Pool) {
+	for _, pool := range pools {
+		pool.Reserve = 0
+	}
+}
+`, []int{7}},
+		{"pointer elements", `package p
+
+type Pool struct{ Reserve int }
+
+func Reset(pools []*Pool) {
+	for _, pool := range pools {
+		pool.Reserve = 0
+	}
+}
+`, nil},
+		{"copy written back", `package p
+
+type Pool struct{ Reserve int }
+
+func Reset(pools []Pool) {
+	for i, pool := range pools {
+		pool.Reserve = 0
+		pools[i] = pool
+	}
+}
+`, nil},
+		{"indexed write", `package p
+
+type Pool struct{ Reserve int }
+
+func Reset(pools []Pool) {
+	for i := range pools {
+		pools[i].Reserve = 0
+	}
+}
+`, nil},
+	})
+}
@@ -0,0 +1,141 @@
+package goparser
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// SARIFLog is the subset of the SARIF 2.1.0 schema needed to report
+// findings as code-scanning results.
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	EndLine     int           `json:"endLine,omitempty"`
+	EndColumn   int           `json:"endColumn,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+// sarifLevel maps a finding severity to a SARIF result level.
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// BuildSARIF converts the results of one or more files, keyed by the path
+// to report them under, into a single-run SARIF log.
+func BuildSARIF(files map[string]*ParseResult) *SARIFLog {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{Name: "contractquard-go", Rules: []sarifRule{}}},
+		// Finding columns count code points, not bytes or UTF-16 units.
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+	rules := map[string]bool{}
+	for _, path := range paths {
+		for _, finding := range files[path].Findings {
+			rules[finding.RuleID] = true
+			region := sarifRegion{
+				StartLine:   max(finding.Line, 1),
+				StartColumn: finding.Column,
+				EndLine:     finding.EndLine,
+				EndColumn:   finding.EndColumn,
+			}
+			if finding.Snippet != "" {
+				region.Snippet = &sarifMessage{Text: finding.Snippet}
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID:  finding.RuleID,
+				Level:   sarifLevel(finding.Severity),
+				Message: sarifMessage{Text: finding.Message},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: path},
+					Region:           region,
+				}}},
+			})
+		}
+	}
+	for id := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id})
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	return &SARIFLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
+
+// SARIFDirFiles rekeys a -dir result by path from the working directory, so
+// the artifact URIs resolve from where the tool was run.
+func SARIFDirFiles(root string, res *DirResult) map[string]*ParseResult {
+	files := map[string]*ParseResult{}
+	for rel, result := range res.Files {
+		files[path.Join(filepath.ToSlash(root), rel)] = result
+	}
+	return files
+}
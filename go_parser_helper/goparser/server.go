@@ -0,0 +1,103 @@
+package goparser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ParseRequest is the body of POST /parse.
+type ParseRequest struct {
+	Filename string `json:"filename"`
+	Source   string `json:"source"`
+}
+
+// HealthStatus is the body of GET /healthz.
+type HealthStatus struct {
+	Status  string      `json:"status"`
+	Version string      `json:"version"`
+	Cache   *CacheStats `json:"cache,omitempty"` // when opts.Cache is set
+}
+
+// NewServer returns the handler for the -serve mode: POST /parse analyzes
+// a ParseRequest under opts and cfg and answers with the ParseResult, and
+// GET /healthz answers a HealthStatus.
+//
+// A body that is not a ParseRequest is rejected with 400 and source over
+// opts.MaxBytes with 413. Source that does not parse is answered with 422
+// and the partial result. A request stopped by opts.Timeout still gets its
+// partial result, with timed_out set.
+func NewServer(opts ParseOptions, cfg Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSONError(w, http.StatusMethodNotAllowed, "use GET")
+			return
+		}
+		status := HealthStatus{Status: "ok", Version: Version}
+		if opts.Cache != nil {
+			stats := opts.Cache.Stats()
+			status.Cache = &stats
+		}
+		writeJSON(w, http.StatusOK, status)
+	})
+	mux.HandleFunc("/parse", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			writeJSONError(w, http.StatusMethodNotAllowed, "use POST")
+			return
+		}
+		body := io.Reader(r.Body)
+		if opts.MaxBytes > 0 {
+			// A JSON string escapes each byte to at most six, so this
+			// bounds the read without rejecting source under the limit.
+			body = http.MaxBytesReader(w, r.Body, 6*opts.MaxBytes+1024)
+		}
+		var req ParseRequest
+		decoder := json.NewDecoder(body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeJSONError(w, http.StatusRequestEntityTooLarge, (&sizeLimitError{limit: opts.MaxBytes}).Error())
+				return
+			}
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("malformed request: %v", err))
+			return
+		}
+		if decoder.More() {
+			writeJSONError(w, http.StatusBadRequest, "malformed request: data after the JSON object")
+			return
+		}
+		if req.Filename == "" {
+			writeJSONError(w, http.StatusBadRequest, "malformed request: filename is required")
+			return
+		}
+
+		result, err := ParseContext(r.Context(), req.Filename, []byte(req.Source), opts, cfg)
+		status := http.StatusOK
+		var tooLarge *sizeLimitError
+		switch {
+		case errors.As(err, &tooLarge):
+			status = http.StatusRequestEntityTooLarge
+		case err != nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled):
+			status = http.StatusUnprocessableEntity
+		}
+		writeJSON(w, status, result)
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
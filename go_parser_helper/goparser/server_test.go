@@ -0,0 +1,112 @@
+package goparser
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serve sends one request to a server under opts and returns the recorded
+// response.
+func serve(opts ParseOptions, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	NewServer(opts, DefaultConfig()).ServeHTTP(rec, req)
+	return rec
+}
+
+// parseRequest encodes a ParseRequest body.
+func parseRequest(t *testing.T, filename, source string) string {
+	t.Helper()
+	data, err := json.Marshal(ParseRequest{Filename: filename, Source: source})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestServerParse(t *testing.T) {
+	rec := serve(ParseOptions{}, http.MethodPost, "/parse", parseRequest(t, "a.go", "package a\n\nfunc F() { panic(1) }\n"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("got Content-Type %q", ct)
+	}
+	var result ParseResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.PackageName != "a" || len(findingsFor(&result, "QLK-PANIC")) != 1 {
+		t.Errorf("got package %q with findings %+v", result.PackageName, result.Findings)
+	}
+}
+
+func TestServerStatusCodes(t *testing.T) {
+	limited := ParseOptions{MaxBytes: 64}
+	for _, tc := range []struct {
+		name         string
+		opts         ParseOptions
+		method, path string
+		body         string
+		status       int
+		allow        string
+		errContains  string
+	}{
+		{"invalid JSON", ParseOptions{}, http.MethodPost, "/parse", "{", http.StatusBadRequest, "", "malformed request"},
+		{"unknown field", ParseOptions{}, http.MethodPost, "/parse", `{"filename": "a.go", "src": "package a"}`, http.StatusBadRequest, "", "unknown field"},
+		{"trailing data", ParseOptions{}, http.MethodPost, "/parse", `{"filename": "a.go", "source": "package a"} {}`, http.StatusBadRequest, "", "data after the JSON object"},
+		{"missing filename", ParseOptions{}, http.MethodPost, "/parse", `{"source": "package a"}`, http.StatusBadRequest, "", "filename is required"},
+		{"GET /parse", ParseOptions{}, http.MethodGet, "/parse", "", http.StatusMethodNotAllowed, "POST", "use POST"},
+		{"POST /healthz", ParseOptions{}, http.MethodPost, "/healthz", "", http.StatusMethodNotAllowed, "GET, HEAD", "use GET"},
+		{"source over MaxBytes", limited, http.MethodPost, "/parse", parseRequest(t, "a.go", "package a\n"+strings.Repeat("// padding\n", 10)), http.StatusRequestEntityTooLarge, "", ""},
+		{"body over the read limit", limited, http.MethodPost, "/parse", parseRequest(t, "a.go", strings.Repeat("x", 2000)), http.StatusRequestEntityTooLarge, "", "64 byte limit"},
+		{"source that does not parse", ParseOptions{}, http.MethodPost, "/parse", parseRequest(t, "a.go", "package a\n\nfunc {"), http.StatusUnprocessableEntity, "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(tc.opts, tc.method, tc.path, tc.body)
+			if rec.Code != tc.status {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tc.status, rec.Body)
+			}
+			if allow := rec.Header().Get("Allow"); allow != tc.allow {
+				t.Errorf("got Allow %q, want %q", allow, tc.allow)
+			}
+			if tc.errContains != "" {
+				var body map[string]string
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || !strings.Contains(body["error"], tc.errContains) {
+					t.Errorf("got body %s, want an error containing %q", rec.Body, tc.errContains)
+				}
+			}
+		})
+	}
+}
+
+func TestServerUnparsableSourceReturnsPartialResult(t *testing.T) {
+	rec := serve(ParseOptions{}, http.MethodPost, "/parse", parseRequest(t, "a.go", "package a\n\nfunc F() {}\n\nfunc {"))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d, want 422", rec.Code)
+	}
+	var result ParseResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) == 0 || len(result.Functions) == 0 || result.Functions[0].Name != "F" {
+		t.Errorf("got errors %q and functions %+v, want the parse error and F", result.Errors, result.Functions)
+	}
+}
+
+func TestServerHealth(t *testing.T) {
+	rec := serve(ParseOptions{Cache: NewResultCache(4)}, http.MethodGet, "/healthz", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", rec.Code)
+	}
+	var status HealthStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != "ok" || status.Version != Version || status.Cache == nil || status.Cache.Capacity != 4 {
+		t.Errorf("got %+v", status)
+	}
+}
@@ -0,0 +1,194 @@
+package goparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+)
+
+// AnalysisSession holds a parsed file so several configurations can be run
+// over it without re-reading or re-parsing the source.
+type AnalysisSession struct {
+	fset    *token.FileSet
+	file    *ast.File
+	source  []byte
+	wrapped string
+	// declsOnly skips the detectors; function bodies have been emptied.
+	declsOnly bool
+	// parseErr is set when the source could not be parsed. Run then reports
+	// it alongside whatever declarations the partial AST holds.
+	parseErr error
+}
+
+// NewAnalysisSession reads and parses filename. A parse failure is not an
+// error here: it is reported in the Errors of every Run result, matching
+// the one-shot CLI.
+func NewAnalysisSession(ctx context.Context, filename string, opts ParseOptions) (*AnalysisSession, error) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	return NewSourceSession(ctx, filename, source, opts), nil
+}
+
+// NewSourceSession parses source already in memory, such as an unsaved
+// editor buffer; filename is only used in positions. If ctx is done before
+// parsing finishes the session holds no file and every Run reports the
+// timeout; the parse itself cannot be interrupted and completes in the
+// background.
+func NewSourceSession(ctx context.Context, filename string, source []byte, opts ParseOptions) *AnalysisSession {
+	if opts.MaxBytes > 0 && int64(len(source)) > opts.MaxBytes {
+		return &AnalysisSession{parseErr: &sizeLimitError{limit: opts.MaxBytes}}
+	}
+	done := make(chan *AnalysisSession, 1)
+	go func() { done <- parseSource(filename, source, opts) }()
+	select {
+	case s := <-done:
+		return s
+	case <-ctx.Done():
+		return &AnalysisSession{parseErr: ctx.Err()}
+	}
+}
+
+func parseSource(filename string, source []byte, opts ParseOptions) *AnalysisSession {
+	// AllErrors keeps the parser going past ten errors; reportParseError
+	// deduplicates and caps what it finds.
+	mode := parser.ParseComments | parser.AllErrors
+	if opts.DeclsOnly {
+		mode = parser.SkipObjectResolution | parser.AllErrors
+	}
+	s := &AnalysisSession{fset: token.NewFileSet(), source: source, declsOnly: opts.DeclsOnly}
+	s.file, s.parseErr = parser.ParseFile(s.fset, filename, source, mode)
+	if s.parseErr != nil && opts.Wrap {
+		if snippet, ok := parseSnippet(filename, source); ok {
+			s.fset, s.file, s.source, s.wrapped, s.parseErr = snippet.fset, snippet.file, snippet.source, snippet.kind, nil
+		}
+	}
+	if s.parseErr == nil && s.declsOnly {
+		// Release the statement trees; only signatures are reported. The
+		// braces are kept so function line ranges stay accurate.
+		for _, decl := range s.file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				fn.Body = &ast.BlockStmt{Lbrace: fn.Body.Lbrace, Rbrace: fn.Body.Rbrace}
+			}
+		}
+		s.file.Comments = nil
+	}
+	return s
+}
+
+// Run walks the cached AST and applies the detectors under cfg. Each call
+// returns a fresh result. If ctx is done mid-walk, the walk stops at the
+// next top-level declaration and the result keeps the declarations seen so
+// far, with TimedOut set and no detectors run.
+func (s *AnalysisSession) Run(ctx context.Context, cfg Config) *ParseResult {
+	if s.file == nil {
+		return failedResult(s.parseErr)
+	}
+
+	visitor := NewGoVisitor(s.fset, string(s.source))
+	visitor.config = cfg
+	visitor.result.Wrapped = s.wrapped
+	walked := 0
+	if w := visitor.Visit(s.file); w != nil {
+		ast.Walk(w, s.file.Name)
+		for _, decl := range s.file.Decls {
+			if ctx.Err() != nil {
+				break
+			}
+			ast.Walk(w, decl)
+			walked++
+		}
+	}
+	visitor.correlateMethods()
+	visitor.checkSatisfactions()
+	visitor.detectContractType(s.file)
+	if err := ctx.Err(); err != nil {
+		visitor.result.TimedOut = true
+		visitor.result.Errors = append(visitor.result.Errors,
+			fmt.Sprintf("Timeout: %v after %d of %d declarations", err, walked, len(s.file.Decls)))
+	} else if s.parseErr != nil {
+		// A partial AST still lists the declarations that parsed, but the
+		// detectors are not run over the gaps the parser left.
+		visitor.result.reportParseError(s.parseErr, s.source)
+	} else if !s.declsOnly {
+		visitor.analyze(s.file)
+	}
+	stripScaffold(visitor.result)
+
+	return visitor.result
+}
+
+// sizeLimitError rejects input larger than ParseOptions.MaxBytes.
+type sizeLimitError struct {
+	limit int64
+}
+
+func (e *sizeLimitError) Error() string {
+	return fmt.Sprintf("input is larger than the %d byte limit", e.limit)
+}
+
+// failedResult reports input that produced no AST, telling a timeout and an
+// oversized input apart from a syntax error.
+func failedResult(err error) *ParseResult {
+	res := &ParseResult{PackageName: "unknown", ToolVersion: Version}
+	var tooLarge *sizeLimitError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		res.TimedOut = true
+		res.Errors = []string{fmt.Sprintf("Timeout: %v before parsing finished", err)}
+	case errors.As(err, &tooLarge):
+		res.Errors = []string{fmt.Sprintf("Input too large: %v", err)}
+	default:
+		res.reportParseError(err, nil)
+	}
+	return res
+}
+
+// ParseError is one syntax error. Column counts code points, as in Span.
+type ParseError struct {
+	Line    int    `json:"line" proto:"1"`
+	Column  int    `json:"column" proto:"2"`
+	Message string `json:"message" proto:"3"`
+}
+
+// maxParseErrors caps ParseErrors; a half-typed buffer can cascade into
+// hundreds of errors after the first.
+const maxParseErrors = 10
+
+// reportParseError fills ParseErrors and Errors from err. A scanner
+// error list is split into its entries, keeping the first of each distinct
+// message; any other error is reported as a single line. source, when
+// known, converts byte columns to code points.
+func (r *ParseResult) reportParseError(err error, source []byte) {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		r.Errors = append(r.Errors, fmt.Sprintf("Parse error: %v", err))
+		return
+	}
+	seen := map[string]bool{}
+	for _, e := range list {
+		if seen[e.Msg] {
+			continue
+		}
+		seen[e.Msg] = true
+		if len(r.ParseErrors) == maxParseErrors {
+			r.ParseErrorsOmitted++
+			continue
+		}
+		column := e.Pos.Column
+		if source != nil && e.Pos.Offset <= len(source) {
+			column = codePointColumn(string(source), e.Pos.Offset)
+		}
+		r.ParseErrors = append(r.ParseErrors, ParseError{Line: e.Pos.Line, Column: column, Message: e.Msg})
+		r.Errors = append(r.Errors, fmt.Sprintf("Parse error: %s:%d:%d: %s", e.Pos.Filename, e.Pos.Line, column, e.Msg))
+	}
+	if r.ParseErrorsOmitted > 0 {
+		r.Errors = append(r.Errors, fmt.Sprintf("Parse error: (+%d more)", r.ParseErrorsOmitted))
+	}
+}
@@ -0,0 +1,77 @@
+package goparser
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// Snippets longer than maxSnippetLines keep their first snippetHead and
+// last snippetTail lines around an elision marker.
+const (
+	maxSnippetLines = 20
+	snippetHead     = 12
+	snippetTail     = 4
+)
+
+// column returns the 1-based column of pos in Unicode code points. Without
+// the source it falls back to go/token's byte column.
+func (v *GoVisitor) column(pos token.Pos) int {
+	p := v.fset.Position(pos)
+	tf := v.tokFile
+	if tf == nil || p.Line < 1 || p.Line > tf.LineCount() {
+		return p.Column
+	}
+	if to := tf.Offset(pos); to <= len(v.source) {
+		return codePointColumn(v.source, to)
+	}
+	return p.Column
+}
+
+// codePointColumn returns the 1-based column, in code points, of the byte
+// offset into src.
+func codePointColumn(src string, offset int) int {
+	start := strings.LastIndexByte(src[:offset], '\n') + 1
+	return utf8.RuneCountInString(src[start:offset]) + 1
+}
+
+// snippet returns the source of lines start through end, with CRLF line
+// endings and tabs normalized. Line offsets come from the file set, so they
+// match the reported line numbers exactly.
+func (v *GoVisitor) snippet(start, end int) string {
+	tf := v.tokFile
+	if tf == nil || start < 1 || start > tf.LineCount() {
+		return ""
+	}
+	end = min(max(end, start), tf.LineCount())
+
+	line := func(n int) string {
+		from := tf.Offset(tf.LineStart(n))
+		to := len(v.source)
+		if n < tf.LineCount() {
+			to = tf.Offset(tf.LineStart(n + 1))
+		}
+		if from > to || to > len(v.source) {
+			return ""
+		}
+		text := strings.TrimRight(v.source[from:to], "\r\n")
+		return strings.ReplaceAll(text, "\t", "    ")
+	}
+
+	var lines []string
+	if count := end - start + 1; count > maxSnippetLines {
+		for n := start; n < start+snippetHead; n++ {
+			lines = append(lines, line(n))
+		}
+		lines = append(lines, fmt.Sprintf("... (%d lines elided)", count-snippetHead-snippetTail))
+		for n := end - snippetTail + 1; n <= end; n++ {
+			lines = append(lines, line(n))
+		}
+	} else {
+		for n := start; n <= end; n++ {
+			lines = append(lines, line(n))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
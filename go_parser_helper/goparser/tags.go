@@ -0,0 +1,224 @@
+package goparser
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// structTag converts a raw tag literal, backticks included, into a
+// reflect.StructTag so keys can be looked up with the standard semantics.
+func structTag(raw string) reflect.StructTag {
+	if unquoted, err := strconv.Unquote(raw); err == nil {
+		raw = unquoted
+	}
+	return reflect.StructTag(raw)
+}
+
+// parseTags splits a raw tag literal into its key:"value" pairs with the
+// same rules as reflect.StructTag.Lookup. Parsing stops at the first
+// malformed pair.
+func parseTags(raw string) map[string]string {
+	tags := map[string]string{}
+	tag := string(structTag(raw))
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			break
+		}
+		tags[key] = value
+		tag = tag[i+1:]
+	}
+	return tags
+}
+
+// TagConflict is a serialization key shared by more than one field of a
+// struct: a json name or a protobuf field number.
+type TagConflict struct {
+	Issue  `proto:"1"`
+	Struct string   `json:"struct" proto:"9"`
+	TagKey string   `json:"tag_key" proto:"10"`
+	Value  string   `json:"value" proto:"11"`
+	Fields []string `json:"fields" proto:"12"`
+}
+
+// detectTagConflicts flags structs in which two fields serialize under the
+// same json name or protobuf field number.
+func (v *GoVisitor) detectTagConflicts(file *ast.File) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok || st.Fields == nil {
+			return true
+		}
+
+		type occurrence struct {
+			field string
+			span  Span
+		}
+		seen := map[string]map[string][]occurrence{"json": {}, "protobuf": {}}
+		var order []string
+		for _, field := range st.Fields.List {
+			if field.Tag == nil || len(field.Names) == 0 {
+				continue
+			}
+			tag := structTag(field.Tag.Value)
+			keys := map[string]string{}
+			if name := strings.Split(tag.Get("json"), ",")[0]; name != "" && name != "-" {
+				keys["json"] = name
+			}
+			if parts := strings.Split(tag.Get("protobuf"), ","); len(parts) > 1 {
+				keys["protobuf"] = parts[1]
+			}
+			for _, tagKey := range []string{"json", "protobuf"} {
+				value, ok := keys[tagKey]
+				if !ok {
+					continue
+				}
+				if len(seen[tagKey][value]) == 0 {
+					order = append(order, tagKey+"\x00"+value)
+				}
+				for _, name := range field.Names {
+					seen[tagKey][value] = append(seen[tagKey][value], occurrence{field: name.Name, span: v.span(name)})
+				}
+			}
+		}
+
+		for _, key := range order {
+			parts := strings.SplitN(key, "\x00", 2)
+			tagKey, value := parts[0], parts[1]
+			occurrences := seen[tagKey][value]
+			if len(occurrences) < 2 {
+				continue
+			}
+			var fields []string
+			for _, occ := range occurrences {
+				fields = append(fields, occ.field)
+			}
+			label := fmt.Sprintf("json name %q", value)
+			if tagKey == "protobuf" {
+				label = "protobuf field number " + value
+			}
+			v.result.TagConflicts = append(v.result.TagConflicts, TagConflict{
+				Issue: Issue{
+					RuleID:   "QLK-TAG-CONFLICT",
+					Severity: SeverityHigh,
+					Message:  fmt.Sprintf("fields %s of %s share %s", strings.Join(fields, ", "), ts.Name.Name, label),
+					Span:     occurrences[1].span,
+				},
+				Struct: ts.Name.Name,
+				TagKey: tagKey,
+				Value:  value,
+				Fields: fields,
+			})
+		}
+		return true
+	})
+}
+
+// TagIssue is a field whose json tag loses or garbles its value on the wire.
+type TagIssue struct {
+	Issue  `proto:"1"`
+	Struct string `json:"struct" proto:"9"`
+	Field  string `json:"field" proto:"10"`
+}
+
+// amountNameParts mark a field as holding a token amount.
+var amountNameParts = []string{"amount", "balance", "supply", "fee", "price", "total"}
+
+// detectTagIssues flags json tags with no value, and 64-bit amounts that are
+// encoded as JSON numbers: clients decoding them as doubles, as JavaScript
+// does, silently lose precision above 2^53 unless the ",string" option is set.
+func (v *GoVisitor) detectTagIssues(file *ast.File) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok || st.Fields == nil {
+			return true
+		}
+		for _, field := range st.Fields.List {
+			if field.Tag == nil || len(field.Names) == 0 {
+				continue
+			}
+			value, ok := parseTags(field.Tag.Value)["json"]
+			if !ok {
+				continue
+			}
+			typ := v.typeToString(field.Type)
+			for _, name := range field.Names {
+				report := func(ruleID, severity, message string) {
+					v.result.TagIssues = append(v.result.TagIssues, TagIssue{
+						Issue: Issue{
+							RuleID:   ruleID,
+							Severity: severity,
+							Message:  message,
+							Span:     v.span(name),
+						},
+						Struct: ts.Name.Name,
+						Field:  name.Name,
+					})
+				}
+				options := strings.Split(value, ",")
+				switch {
+				case value == "":
+					report("QLK-EMPTY-JSON-TAG", SeverityLow,
+						fmt.Sprintf("field %s.%s has an empty json tag", ts.Name.Name, name.Name))
+				case options[0] == "-" && len(options) == 1:
+					// Never encoded.
+				case (typ == "uint64" || typ == "int64") && isAmountName(name.Name) && !hasOption(options[1:], "string"):
+					report("QLK-JSON-INT64-PRECISION", SeverityMedium,
+						fmt.Sprintf("%s amount %s.%s is encoded as a JSON number; add the \",string\" option", typ, ts.Name.Name, name.Name))
+				}
+			}
+		}
+		return true
+	})
+}
+
+func isAmountName(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range amountNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package goparser
+
+import (
+	"strings"
+	"testing"
+)
+
+// tagged writes a fixture with struct tags in single quotes, which a raw
+// string literal cannot hold as backquotes.
+func tagged(src string) string {
+	return strings.ReplaceAll(src, "'", "`")
+}
+
+func TestTagConflict(t *testing.T) {
+	checkRule(t, "QLK-TAG-CONFLICT", []ruleCase{
+		{"shared json name", tagged(`package types
+
+type Coin struct {
+	Denom  string 'json:"denom"'
+	Amount string 'json:"amount"'
+	Legacy string 'json:"denom,omitempty"'
+}
+`), []int{6}},
+		{"shared protobuf number", tagged(`package types
+
+type Coin struct {
+	Denom  string 'protobuf:"bytes,1,opt,name=denom"'
+	Amount string 'protobuf:"bytes,1,opt,name=amount"'
+}
+`), []int{5}},
+		{"distinct names and numbers", tagged(`package types
+
+type Coin struct {
+	Denom  string 'json:"denom" protobuf:"bytes,1,opt,name=denom"'
+	Amount string 'json:"amount" protobuf:"bytes,2,opt,name=amount"'
+}
+`), nil},
+		{"ignored fields", tagged(`package types
+
+type Coin struct {
+	Denom  string 'json:"-"'
+	Amount string 'json:"-"'
+}
+`), nil},
+	})
+}
+
+func TestEmptyJSONTag(t *testing.T) {
+	checkRule(t, "QLK-EMPTY-JSON-TAG", []ruleCase{
+		{"empty json tag", tagged(`package types
+
+type Coin struct {
+	Denom string 'json:""'
+}
+`), []int{4}},
+		{"named or skipped", tagged(`package types
+
+type Coin struct {
+	Denom  string 'json:"denom"'
+	Amount string 'json:"-"'
+	Note   string 'yaml:""'
+}
+`), nil},
+	})
+}
+
+func TestJSONInt64Precision(t *testing.T) {
+	checkRule(t, "QLK-JSON-INT64-PRECISION", []ruleCase{
+		{"64-bit amounts as numbers", tagged(`package types
+
+type Account struct {
+	Balance uint64 'json:"balance"'
+	Fee     int64  'json:"fee,omitempty"'
+}
+`), []int{4, 5}},
+		{"string option, narrow type or not an amount", tagged(`package types
+
+type Account struct {
+	Balance  uint64 'json:"balance,string"'
+	Price    uint32 'json:"price"'
+	Sequence uint64 'json:"sequence"'
+	Supply   uint64 'json:"-"'
+}
+`), nil},
+	})
+}
@@ -0,0 +1,79 @@
+package goparser
+
+import (
+	"go/ast"
+)
+
+// TypeReference is one use of a package-qualified type, with the qualifier
+// resolved through the file's imports so that sdk.Msg and types.Msg can be
+// matched to the same package.
+type TypeReference struct {
+	Selector        string `json:"selector" proto:"1"` // as written, "sdk.Context"
+	Package         string `json:"package" proto:"2"`  // the local name, "sdk"
+	ResolvedPackage string `json:"resolved_package" proto:"3"`
+	LineStart       int    `json:"line_start" proto:"4"`
+}
+
+// resolveTypeReferences records every qualified type in a type position:
+// fields, parameters and results, variable and type declarations, composite
+// literals and type assertions. Qualifiers that are not imports, including
+// names brought in by dot imports, are skipped.
+func (v *GoVisitor) resolveTypeReferences(file *ast.File) {
+	imports := importLocalNames(file)
+	// record descends through type constructors but not into struct,
+	// interface or func types, whose fields the walk below visits itself.
+	var record func(expr ast.Expr)
+	record = func(expr ast.Expr) {
+		switch t := expr.(type) {
+		case *ast.SelectorExpr:
+			pkg, ok := t.X.(*ast.Ident)
+			if !ok || imports[pkg.Name] == "" {
+				return
+			}
+			v.result.TypeReferences = append(v.result.TypeReferences, TypeReference{
+				Selector:        pkg.Name + "." + t.Sel.Name,
+				Package:         pkg.Name,
+				ResolvedPackage: imports[pkg.Name],
+				LineStart:       v.line(t),
+			})
+		case *ast.StarExpr:
+			record(t.X)
+		case *ast.ParenExpr:
+			record(t.X)
+		case *ast.Ellipsis:
+			record(t.Elt)
+		case *ast.ArrayType:
+			record(t.Elt)
+		case *ast.MapType:
+			record(t.Key)
+			record(t.Value)
+		case *ast.ChanType:
+			record(t.Value)
+		case *ast.IndexExpr:
+			record(t.X)
+			record(t.Index)
+		case *ast.IndexListExpr:
+			record(t.X)
+			for _, index := range t.Indices {
+				record(index)
+			}
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.ImportSpec:
+			return false
+		case *ast.Field:
+			record(e.Type)
+		case *ast.ValueSpec:
+			record(e.Type)
+		case *ast.TypeSpec:
+			record(e.Type)
+		case *ast.CompositeLit:
+			record(e.Type)
+		case *ast.TypeAssertExpr:
+			record(e.Type)
+		}
+		return true
+	})
+}
@@ -0,0 +1,1280 @@
+package goparser
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type ParsedFunction struct {
+	Name        string            `json:"name" proto:"1"`
+	TypeParams  []ParsedTypeParam `json:"type_params,omitempty" proto:"2"`
+	Parameters  []ParsedParameter `json:"parameters" proto:"3"`
+	ReturnTypes []string          `json:"return_types" proto:"4"`
+	IsExported  bool              `json:"is_exported" proto:"5"`
+	Receiver    *ParsedReceiver   `json:"receiver,omitempty" proto:"6"`
+	LineStart   int               `json:"line_start" proto:"7"`
+	LineEnd     int               `json:"line_end" proto:"8"`
+	// ColStart and ColEnd count code points, as in Span.
+	ColStart   int    `json:"col_start" proto:"9"`
+	ColEnd     int    `json:"col_end" proto:"10"`
+	Doc        string `json:"doc,omitempty" proto:"11"`
+	Complexity int    `json:"complexity" proto:"12"` // cyclomatic; 0 without a body
+	// MaxNestingDepth is the deepest chain of nested blocks: 1 for a body
+	// with a single if, 2 for a loop inside it.
+	MaxNestingDepth int `json:"max_nesting_depth" proto:"13"`
+	// MutatedFields lists, in order of first write, the receiver fields the
+	// method assigns, increments or deletes from.
+	MutatesState  bool     `json:"mutates_state" proto:"14"`
+	MutatedFields []string `json:"mutated_fields" proto:"15"`
+	Snippet       string   `json:"snippet,omitempty" proto:"16"`
+	// PanicSurface is set on exported functions only.
+	PanicSurface []PanicSource `json:"panic_surface,omitempty" proto:"17"`
+}
+
+// receiverType returns the receiver's type name without pointer or type
+// arguments, or "" for a plain function.
+func (f ParsedFunction) receiverType() string {
+	if f.Receiver == nil {
+		return ""
+	}
+	typ := strings.TrimPrefix(f.Receiver.Type, "*")
+	if i := strings.Index(typ, "["); i >= 0 {
+		typ = typ[:i]
+	}
+	return typ
+}
+
+// displayName returns "Type.Method" for methods and the plain name for
+// functions, as funcDisplayName does for declarations.
+func (f ParsedFunction) displayName() string {
+	if typ := f.receiverType(); typ != "" {
+		return typ + "." + f.Name
+	}
+	return f.Name
+}
+
+type ParsedParameter struct {
+	Name       string `json:"name" proto:"1"`
+	Type       string `json:"type" proto:"2"`
+	IsVariadic bool   `json:"is_variadic,omitempty" proto:"3"`
+}
+
+type ParsedTypeParam struct {
+	Name       string `json:"name" proto:"1"`
+	Constraint string `json:"constraint" proto:"2"`
+}
+
+type ParsedReceiver struct {
+	Name string `json:"name" proto:"1"`
+	Type string `json:"type" proto:"2"`
+}
+
+type ParsedStruct struct {
+	Name       string            `json:"name" proto:"1"`
+	TypeParams []ParsedTypeParam `json:"type_params,omitempty" proto:"2"`
+	Fields     []ParsedField     `json:"fields" proto:"3"`
+	Methods    []string          `json:"methods" proto:"4"`
+	IsExported bool              `json:"is_exported" proto:"5"`
+	LineStart  int               `json:"line_start" proto:"6"`
+	LineEnd    int               `json:"line_end" proto:"7"`
+	ColStart   int               `json:"col_start" proto:"8"`
+	ColEnd     int               `json:"col_end" proto:"9"`
+	Doc        string            `json:"doc,omitempty" proto:"10"`
+	Snippet    string            `json:"snippet,omitempty" proto:"11"`
+}
+
+type ParsedField struct {
+	Name       string `json:"name" proto:"1"`
+	Type       string `json:"type" proto:"2"`
+	IsExported bool   `json:"is_exported" proto:"3"`
+	Tag        string `json:"tag,omitempty" proto:"4"`
+	// ParsedTags holds Tag's key:"value" pairs, unquoted.
+	ParsedTags map[string]string `json:"parsed_tags,omitempty" proto:"5"`
+	// EmbeddedType is the final identifier of an embedded field's type,
+	// "Keeper" for both *Keeper and banktypes.Keeper.
+	IsEmbedded   bool   `json:"is_embedded,omitempty" proto:"6"`
+	EmbeddedType string `json:"embedded_type,omitempty" proto:"7"`
+}
+
+type ParsedInterface struct {
+	Name       string            `json:"name" proto:"1"`
+	TypeParams []ParsedTypeParam `json:"type_params,omitempty" proto:"2"`
+	Methods    []string          `json:"methods" proto:"3"`
+	IsExported bool              `json:"is_exported" proto:"4"`
+	LineStart  int               `json:"line_start" proto:"5"`
+	LineEnd    int               `json:"line_end" proto:"6"`
+	ColStart   int               `json:"col_start" proto:"7"`
+	ColEnd     int               `json:"col_end" proto:"8"`
+	Doc        string            `json:"doc,omitempty" proto:"9"`
+	Snippet    string            `json:"snippet,omitempty" proto:"10"`
+}
+
+// ParsedTypeDef is a type declaration other than a struct or interface: a
+// named type such as "type Denom string" or an alias such as
+// "type AccAddress = []byte".
+type ParsedTypeDef struct {
+	Name       string            `json:"name" proto:"1"`
+	TypeParams []ParsedTypeParam `json:"type_params,omitempty" proto:"2"`
+	Underlying string            `json:"underlying" proto:"3"`
+	IsAlias    bool              `json:"is_alias" proto:"4"`
+	Methods    []string          `json:"methods" proto:"5"`
+	IsExported bool              `json:"is_exported" proto:"6"`
+	LineStart  int               `json:"line_start" proto:"7"`
+	Doc        string            `json:"doc,omitempty" proto:"8"`
+}
+
+type ParsedConstant struct {
+	Name string `json:"name" proto:"1"`
+	Type string `json:"type" proto:"2"`
+	// Value is the literal text of a basic-literal value, or the decimal
+	// value of an integer expression using iota, such as 3 for the fourth
+	// name of an iota run.
+	Value      string `json:"value,omitempty" proto:"3"`
+	IsExported bool   `json:"is_exported" proto:"4"`
+	LineStart  int    `json:"line_start" proto:"5"`
+}
+
+type ParsedVariable struct {
+	Name       string `json:"name" proto:"1"`
+	Type       string `json:"type" proto:"2"`
+	Value      string `json:"value,omitempty" proto:"3"` // set when the value is a basic literal
+	IsExported bool   `json:"is_exported" proto:"4"`
+	LineStart  int    `json:"line_start" proto:"5"`
+}
+
+type ParsedImport struct {
+	Path  string `json:"path" proto:"1"`
+	Name  string `json:"name,omitempty" proto:"2"`
+	Alias string `json:"alias,omitempty" proto:"3"`
+}
+
+type ParsedGoroutine struct {
+	FunctionCall      string `json:"function_call" proto:"1"` // "<func-literal>" for go func() {...}()
+	Span              `proto:"2"`
+	Context           string `json:"context" proto:"6"`
+	EnclosingFunction string `json:"enclosing_function" proto:"7"`
+	// The cancellation signals the goroutine is given or refers to.
+	HasContext     bool `json:"has_context,omitempty" proto:"8"`
+	HasStopChannel bool `json:"has_stop_channel,omitempty" proto:"9"`
+	HasWaitGroup   bool `json:"has_wait_group,omitempty" proto:"10"`
+	// LeakSuspect marks a func literal that loops forever with none of
+	// those signals, so nothing can stop it.
+	LeakSuspect bool `json:"leak_suspect,omitempty" proto:"11"`
+}
+
+type ParsedChannel struct {
+	Name              string `json:"name" proto:"1"`
+	Type              string `json:"type" proto:"2"`
+	Direction         string `json:"direction" proto:"3"`   // "send", "receive", "bidirectional"
+	BufferSize        int    `json:"buffer_size" proto:"4"` // -1 when not a constant
+	LineStart         int    `json:"line_start" proto:"5"`
+	EnclosingFunction string `json:"enclosing_function" proto:"6"`
+}
+
+type ParsedSelect struct {
+	LineStart         int                `json:"line_start" proto:"1"`
+	CaseCount         int                `json:"case_count" proto:"2"`
+	HasDefault        bool               `json:"has_default" proto:"3"`
+	Cases             []ParsedSelectCase `json:"cases" proto:"4"`
+	EnclosingFunction string             `json:"enclosing_function" proto:"5"`
+}
+
+// ParsedSelectCase is one communication clause of a select.
+type ParsedSelectCase struct {
+	Direction string `json:"direction" proto:"1"` // "send" or "receive"
+	Channel   string `json:"channel" proto:"2"`
+}
+
+type ParsedDefer struct {
+	Call              string `json:"call" proto:"1"`
+	LineStart         int    `json:"line_start" proto:"2"`
+	EnclosingFunction string `json:"enclosing_function" proto:"3"`
+	CallsRecover      bool   `json:"calls_recover" proto:"4"`
+}
+
+type ParsedPanic struct {
+	Argument          string `json:"argument" proto:"1"`
+	EnclosingFunction string `json:"enclosing_function" proto:"2"` // "" at package scope
+	Span              `proto:"3"`
+	// CallChain is the shortest path of in-file calls from an exported
+	// function to the one declaring the panic, entry point first.
+	ReachableFromExported bool     `json:"reachable_from_exported" proto:"7"`
+	CallChain             []string `json:"call_chain,omitempty" proto:"8"`
+}
+
+type IgnoredError struct {
+	Call              string `json:"call" proto:"1"`
+	DiscardedIndex    int    `json:"discarded_index" proto:"2"`
+	EnclosingFunction string `json:"enclosing_function" proto:"3"`
+	Span              `proto:"4"`
+}
+
+type ParseResult struct {
+	PackageName              string                     `json:"package_name" proto:"1"`
+	Functions                []ParsedFunction           `json:"functions" proto:"2"`
+	Structs                  []ParsedStruct             `json:"structs" proto:"3"`
+	Interfaces               []ParsedInterface          `json:"interfaces" proto:"4"`
+	OrphanMethods            []string                   `json:"orphan_methods" proto:"5"` // "Type.Method" on non-struct types
+	TypeDefs                 []ParsedTypeDef            `json:"type_defs" proto:"6"`
+	Satisfactions            []InterfaceSatisfaction    `json:"satisfactions" proto:"7"`
+	Constants                []ParsedConstant           `json:"constants" proto:"8"`
+	Variables                []ParsedVariable           `json:"variables" proto:"9"`
+	Imports                  []ParsedImport             `json:"imports" proto:"10"`
+	TypeReferences           []TypeReference            `json:"type_references" proto:"11"`
+	CommentMarkers           []CommentMarker            `json:"comment_markers" proto:"12"`
+	ImportFindings           []ImportFinding            `json:"import_findings" proto:"13"`
+	Goroutines               []ParsedGoroutine          `json:"goroutines" proto:"14"`
+	Channels                 []ParsedChannel            `json:"channels" proto:"15"`
+	Selects                  []ParsedSelect             `json:"selects" proto:"16"`
+	Panics                   []ParsedPanic              `json:"panics" proto:"17"`
+	Defers                   []ParsedDefer              `json:"defers" proto:"18"`
+	IgnoredErrors            []IgnoredError             `json:"ignored_errors" proto:"19"`
+	ContractType             string                     `json:"contract_type" proto:"20"`
+	ContractTypes            []ContractTypeScore        `json:"contract_types" proto:"21"`
+	Wrapped                  string                     `json:"wrapped,omitempty" proto:"22"` // "package" or "function" when -wrap rescued a snippet
+	FeaturesUsed             FeaturesUsed               `json:"features_used" proto:"23"`
+	EventInjection           []Issue                    `json:"event_injection" proto:"24"`
+	NamedErrorNotSet         []Issue                    `json:"named_error_not_set" proto:"25"`
+	UnsafeUsage              []Issue                    `json:"unsafe_usage" proto:"26"`
+	MessageValidation        []MessageValidationFinding `json:"message_validation" proto:"27"`
+	UncancellableLoop        []Issue                    `json:"uncancellable_loop" proto:"28"`
+	DuplicateLiterals        []DuplicateLiteral         `json:"duplicate_literals" proto:"29"`
+	Dispatch                 []DispatchRoute            `json:"dispatch" proto:"30"`
+	UnusedMessageFields      []UnusedMessageField       `json:"unused_message_fields" proto:"31"`
+	UncheckedMapLookup       []Issue                    `json:"unchecked_map_lookup" proto:"32"`
+	TagConflicts             []TagConflict              `json:"tag_conflicts" proto:"33"`
+	TagIssues                []TagIssue                 `json:"tag_issues" proto:"34"`
+	KeeperCoupling           []Issue                    `json:"keeper_coupling" proto:"35"`
+	SecretFindings           []SecretFinding            `json:"secret_findings" proto:"36"`
+	SensitiveLogging         []Issue                    `json:"sensitive_logging" proto:"37"`
+	InvariantIssues          []Issue                    `json:"invariant_issues" proto:"38"`
+	UnusedFields             []Issue                    `json:"unused_fields" proto:"39"`
+	InconsistentErrorReturns []Issue                    `json:"inconsistent_error_returns" proto:"40"`
+	ShadowFindings           []ShadowFinding            `json:"shadow_findings" proto:"41"`
+	NilCollectionReturn      []Issue                    `json:"nil_collection_return" proto:"42"`
+	KeyCollisionRisk         []Issue                    `json:"key_collision_risk" proto:"43"`
+	StoreKeyFindings         []StoreKeyFinding          `json:"store_key_findings" proto:"44"`
+	ShouldBeMethod           []Issue                    `json:"should_be_method" proto:"45"`
+	ConcurrentContextUse     []Issue                    `json:"concurrent_context_use" proto:"46"`
+	ConcurrencyFindings      []ConcurrencyFinding       `json:"concurrency_findings" proto:"47"`
+	ValidationOrdering       []Issue                    `json:"validation_ordering" proto:"48"`
+	GenesisValidationIssues  []Issue                    `json:"genesis_validation_issues" proto:"49"`
+	StubValidations          []StubValidationFinding    `json:"stub_validations" proto:"50"`
+	ShouldBeConst            []Issue                    `json:"should_be_const" proto:"51"`
+	UnregisteredHandlers     []Issue                    `json:"unregistered_handlers" proto:"52"`
+	RedundantConditions      []Issue                    `json:"redundant_conditions" proto:"53"`
+	MigrationIssues          []Issue                    `json:"migration_issues" proto:"54"`
+	AuthorizationFindings    []AuthFinding              `json:"authorization_findings" proto:"55"`
+	UnusedContextFindings    []ContextFinding           `json:"unused_context_findings" proto:"56"`
+	GasRisks                 []GasRisk                  `json:"gas_risks" proto:"57"`
+	BoundsRisks              []BoundsRisk               `json:"bounds_risks" proto:"58"`
+	ReentrancyRisks          []ReentrancyRisk           `json:"reentrancy_risks" proto:"59"`
+	MapAccessRisks           []MapAccessRisk            `json:"map_access_risks" proto:"60"`
+	RangeCopyFindings        []RangeCopyFinding         `json:"range_copy_findings" proto:"61"`
+	DeterminismFindings      []DeterminismFinding       `json:"determinism_findings" proto:"62"`
+	OverflowRisks            []OverflowRisk             `json:"overflow_risks" proto:"63"`
+	Calls                    []CallEdge                 `json:"calls" proto:"64"`
+	Findings                 []Finding                  `json:"findings" proto:"65"` // every issue above, flattened
+	RiskScore                int                        `json:"risk_score" proto:"66"`
+	// TimedOut is set when -timeout stopped the parse or walk; the result
+	// then holds only what was reached.
+	TimedOut bool `json:"timed_out,omitempty" proto:"67"`
+	// Suppressed counts the findings -baseline removed from Findings.
+	Suppressed int `json:"suppressed,omitempty" proto:"68"`
+	// ParseErrors are the syntax errors, one per distinct message, capped
+	// at maxParseErrors; ParseErrorsOmitted counts the rest.
+	ParseErrors        []ParseError `json:"parse_errors,omitempty" proto:"69"`
+	ParseErrorsOmitted int          `json:"parse_errors_omitted,omitempty" proto:"70"`
+	// Errors holds the human-readable form of every problem, parse errors
+	// included.
+	Errors      []string `json:"errors" proto:"71"`
+	ToolVersion string   `json:"tool_version" proto:"72"`
+}
+
+type GoVisitor struct {
+	fset   *token.FileSet
+	result *ParseResult
+	source string
+	config Config
+	funcs  *funcIndex
+	// scope is the function the walk is inside, nil at package level.
+	scope *funcScope
+	// bound names the variable each make(chan ...) call is assigned to.
+	bound map[*ast.CallExpr]string
+	// tokFile locates line offsets in source for snippets.
+	tokFile *token.File
+	// redactions maps the source text of each literal reported as a secret
+	// to its redacted preview, so finding snippets do not repeat it.
+	redactions map[string]string
+	// qualifiers holds every identifier used as the X of a selector, which
+	// is how an imported package is referenced.
+	qualifiers map[string]bool
+	// loops are the for and range loops enclosing the node being walked,
+	// innermost last.
+	loops []loopScope
+}
+
+// funcScope names the function being walked. Closures are named the way
+// the runtime names them in stack traces: Foo.func1, Foo.func1.1.
+type funcScope struct {
+	name     string
+	closure  bool
+	closures int
+}
+
+// enter returns a copy of the visitor for walking the body of the function
+// named name.
+func (v *GoVisitor) enter(name string, closure bool) *GoVisitor {
+	child := *v
+	child.scope = &funcScope{name: name, closure: closure}
+	return &child
+}
+
+func NewGoVisitor(fset *token.FileSet, source string) *GoVisitor {
+	return &GoVisitor{
+		fset:       fset,
+		source:     source,
+		bound:      map[*ast.CallExpr]string{},
+		redactions: map[string]string{},
+		qualifiers: map[string]bool{},
+		result: &ParseResult{
+			ToolVersion:              Version,
+			Functions:                []ParsedFunction{},
+			Structs:                  []ParsedStruct{},
+			Interfaces:               []ParsedInterface{},
+			OrphanMethods:            []string{},
+			TypeDefs:                 []ParsedTypeDef{},
+			Satisfactions:            []InterfaceSatisfaction{},
+			Constants:                []ParsedConstant{},
+			Variables:                []ParsedVariable{},
+			Imports:                  []ParsedImport{},
+			TypeReferences:           []TypeReference{},
+			CommentMarkers:           []CommentMarker{},
+			ImportFindings:           []ImportFinding{},
+			Goroutines:               []ParsedGoroutine{},
+			Channels:                 []ParsedChannel{},
+			ContractTypes:            []ContractTypeScore{},
+			Selects:                  []ParsedSelect{},
+			Panics:                   []ParsedPanic{},
+			Defers:                   []ParsedDefer{},
+			IgnoredErrors:            []IgnoredError{},
+			Errors:                   []string{},
+			EventInjection:           []Issue{},
+			NamedErrorNotSet:         []Issue{},
+			UnsafeUsage:              []Issue{},
+			MessageValidation:        []MessageValidationFinding{},
+			UncancellableLoop:        []Issue{},
+			DuplicateLiterals:        []DuplicateLiteral{},
+			Dispatch:                 []DispatchRoute{},
+			UnusedMessageFields:      []UnusedMessageField{},
+			UncheckedMapLookup:       []Issue{},
+			TagConflicts:             []TagConflict{},
+			TagIssues:                []TagIssue{},
+			KeeperCoupling:           []Issue{},
+			SecretFindings:           []SecretFinding{},
+			SensitiveLogging:         []Issue{},
+			InvariantIssues:          []Issue{},
+			UnusedFields:             []Issue{},
+			InconsistentErrorReturns: []Issue{},
+			ShadowFindings:           []ShadowFinding{},
+			NilCollectionReturn:      []Issue{},
+			KeyCollisionRisk:         []Issue{},
+			StoreKeyFindings:         []StoreKeyFinding{},
+			ShouldBeMethod:           []Issue{},
+			ConcurrentContextUse:     []Issue{},
+			ConcurrencyFindings:      []ConcurrencyFinding{},
+			ValidationOrdering:       []Issue{},
+			GenesisValidationIssues:  []Issue{},
+			StubValidations:          []StubValidationFinding{},
+			ShouldBeConst:            []Issue{},
+			UnregisteredHandlers:     []Issue{},
+			RedundantConditions:      []Issue{},
+			MigrationIssues:          []Issue{},
+			AuthorizationFindings:    []AuthFinding{},
+			UnusedContextFindings:    []ContextFinding{},
+			GasRisks:                 []GasRisk{},
+			BoundsRisks:              []BoundsRisk{},
+			ReentrancyRisks:          []ReentrancyRisk{},
+			MapAccessRisks:           []MapAccessRisk{},
+			RangeCopyFindings:        []RangeCopyFinding{},
+			DeterminismFindings:      []DeterminismFinding{},
+			OverflowRisks:            []OverflowRisk{},
+			Calls:                    []CallEdge{},
+			Findings:                 []Finding{},
+		},
+	}
+}
+
+func (v *GoVisitor) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		return nil
+	}
+
+	switch n := node.(type) {
+	case *ast.File:
+		v.result.PackageName = n.Name.Name
+		v.tokFile = v.fset.File(n.Pos())
+		for _, decl := range n.Decls {
+			if gen, ok := decl.(*ast.GenDecl); ok {
+				v.visitValueDecl(gen)
+			}
+		}
+
+	case *ast.ImportSpec:
+		v.visitImport(n)
+
+	case *ast.FuncDecl:
+		v.visitFunction(n)
+		return v.enter(funcDisplayName(n), false)
+
+	case *ast.FuncLit:
+		if v.scope == nil {
+			return v
+		}
+		v.scope.closures++
+		if v.scope.closure {
+			return v.enter(fmt.Sprintf("%s.%d", v.scope.name, v.scope.closures), true)
+		}
+		return v.enter(fmt.Sprintf("%s.func%d", v.scope.name, v.scope.closures), true)
+
+	case *ast.GenDecl:
+		v.visitGenDecl(n)
+
+	case *ast.AssignStmt:
+		v.visitAssign(n)
+		v.bindNames(n.Lhs, n.Rhs)
+
+	case *ast.ValueSpec:
+		names := make([]ast.Expr, len(n.Names))
+		for i, name := range n.Names {
+			names[i] = name
+		}
+		v.bindNames(names, n.Values)
+
+	case *ast.ForStmt:
+		if assign, ok := n.Init.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+			return v.enterLoop(n, definedIdents(assign.Lhs))
+		}
+
+	case *ast.RangeStmt:
+		if n.Tok == token.DEFINE {
+			return v.enterLoop(n, definedIdents([]ast.Expr{n.Key, n.Value}))
+		}
+
+	case *ast.GoStmt:
+		v.visitGoroutine(n)
+
+	case *ast.SelectStmt:
+		v.visitSelect(n)
+
+	case *ast.DeferStmt:
+		v.visitDefer(n)
+
+	case *ast.CallExpr:
+		v.visitCallExpr(n)
+
+	case *ast.SelectorExpr:
+		if x, ok := n.X.(*ast.Ident); ok {
+			v.qualifiers[x.Name] = true
+		}
+	}
+
+	return v
+}
+
+func (v *GoVisitor) visitImport(imp *ast.ImportSpec) {
+	parsed := ParsedImport{
+		Path: strings.Trim(imp.Path.Value, `"`),
+	}
+
+	if imp.Name != nil {
+		parsed.Name = imp.Name.Name
+		if imp.Name.Name != "." && imp.Name.Name != "_" {
+			parsed.Alias = imp.Name.Name
+		}
+	}
+
+	v.result.Imports = append(v.result.Imports, parsed)
+}
+
+func (v *GoVisitor) visitFunction(fn *ast.FuncDecl) {
+	pos := v.fset.Position(fn.Pos())
+	end := v.fset.Position(fn.End())
+
+	parsed := ParsedFunction{
+		Name:            fn.Name.Name,
+		TypeParams:      v.typeParams(fn.Type.TypeParams),
+		Parameters:      []ParsedParameter{},
+		ReturnTypes:     []string{},
+		IsExported:      ast.IsExported(fn.Name.Name),
+		LineStart:       pos.Line,
+		LineEnd:         end.Line,
+		ColStart:        v.column(fn.Pos()),
+		ColEnd:          v.column(fn.End()),
+		Doc:             docText(fn.Doc),
+		Complexity:      cyclomaticComplexity(fn.Body),
+		MaxNestingDepth: nestingDepth(fn.Body),
+		Snippet:         v.snippet(pos.Line, end.Line),
+		MutatedFields:   []string{},
+	}
+
+	// Parse receiver (for methods)
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		recv := fn.Recv.List[0]
+		parsed.Receiver = &ParsedReceiver{
+			Type: v.typeToString(recv.Type),
+		}
+		if len(recv.Names) > 0 {
+			parsed.Receiver.Name = recv.Names[0].Name
+		}
+		parsed.MutatedFields = mutatedFields(fn.Body, parsed.Receiver.Name)
+		parsed.MutatesState = len(parsed.MutatedFields) > 0
+	}
+
+	// Parse parameters
+	if fn.Type.Params != nil {
+		for i, param := range fn.Type.Params.List {
+			paramType := v.typeToString(param.Type)
+			// Only a final, single parameter may be variadic.
+			_, ellipsis := param.Type.(*ast.Ellipsis)
+			variadic := ellipsis && i == len(fn.Type.Params.List)-1 && len(param.Names) <= 1
+			if len(param.Names) > 0 {
+				for _, name := range param.Names {
+					parsed.Parameters = append(parsed.Parameters, ParsedParameter{
+						Name:       name.Name,
+						Type:       paramType,
+						IsVariadic: variadic,
+					})
+				}
+			} else {
+				parsed.Parameters = append(parsed.Parameters, ParsedParameter{
+					Name:       "",
+					Type:       paramType,
+					IsVariadic: variadic,
+				})
+			}
+		}
+	}
+
+	// Parse return types
+	if fn.Type.Results != nil {
+		for _, result := range fn.Type.Results.List {
+			parsed.ReturnTypes = append(parsed.ReturnTypes, v.typeToString(result.Type))
+		}
+	}
+
+	v.result.Functions = append(v.result.Functions, parsed)
+}
+
+// receiverField returns the receiver field expr writes to, "balances" for
+// vc.balances, vc.balances[k] and vc.balances.total, or "".
+func receiverField(expr ast.Expr, recv string) string {
+	if recv == "" || recv == "_" {
+		return ""
+	}
+	for {
+		switch e := expr.(type) {
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			if id, ok := e.X.(*ast.Ident); ok && id.Name == recv {
+				return e.Sel.Name
+			}
+			expr = e.X
+		default:
+			return ""
+		}
+	}
+}
+
+// mutatedFields lists the receiver fields body assigns, increments or
+// deletes map entries from, closures included.
+func mutatedFields(body *ast.BlockStmt, recv string) []string {
+	fields := []string{}
+	if body == nil {
+		return fields
+	}
+	seen := map[string]bool{}
+	add := func(expr ast.Expr) {
+		if field := receiverField(expr, recv); field != "" && !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			if s.Tok != token.DEFINE {
+				for _, lhs := range s.Lhs {
+					add(lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			add(s.X)
+		case *ast.CallExpr:
+			if id, ok := s.Fun.(*ast.Ident); ok && id.Name == "delete" && len(s.Args) == 2 {
+				add(s.Args[0])
+			}
+		}
+		return true
+	})
+	return fields
+}
+
+func (v *GoVisitor) visitGenDecl(gen *ast.GenDecl) {
+	for _, spec := range gen.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			doc := s.Doc
+			if doc == nil && len(gen.Specs) == 1 {
+				// "// X ...\ntype X struct" attaches the comment to the
+				// declaration rather than the spec.
+				doc = gen.Doc
+			}
+			v.visitTypeSpec(s, docText(doc))
+		}
+	}
+}
+
+// visitValueDecl records the package-level constants and variables of a
+// const or var block, one entry per name. Within a const group a spec with
+// neither type nor values repeats the previous spec's, as in an iota run.
+func (v *GoVisitor) visitValueDecl(gen *ast.GenDecl) {
+	if gen.Tok != token.CONST && gen.Tok != token.VAR {
+		return
+	}
+	var typ ast.Expr
+	var values []ast.Expr
+	// known holds the integer constants of the group evaluated so far, for
+	// expressions such as iota + Base.
+	known := map[string]int64{}
+	for iota, spec := range gen.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if gen.Tok == token.VAR || vs.Type != nil || len(vs.Values) > 0 {
+			typ, values = vs.Type, vs.Values
+		}
+		for i, name := range vs.Names {
+			if name.Name == "_" {
+				continue
+			}
+			typeName, value := "", ""
+			if typ != nil {
+				typeName = v.typeToString(typ)
+			}
+			if i < len(values) {
+				if lit, ok := values[i].(*ast.BasicLit); ok {
+					value = lit.Value
+				}
+				if gen.Tok == token.CONST {
+					if n, ok := constInt(values[i], int64(iota), known); ok {
+						known[name.Name] = n
+						if usesIota(values[i]) {
+							value = strconv.FormatInt(n, 10)
+						}
+					}
+				}
+			}
+			line := v.fset.Position(name.Pos()).Line
+			if gen.Tok == token.CONST {
+				v.result.Constants = append(v.result.Constants, ParsedConstant{
+					Name: name.Name, Type: typeName, Value: value, IsExported: name.IsExported(), LineStart: line,
+				})
+			} else {
+				v.result.Variables = append(v.result.Variables, ParsedVariable{
+					Name: name.Name, Type: typeName, Value: value, IsExported: name.IsExported(), LineStart: line,
+				})
+			}
+		}
+	}
+}
+
+// usesIota reports whether a constant expression refers to iota.
+func usesIota(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// constInt evaluates an integer constant expression: literals, iota, the
+// known constants, conversions such as Kind(iota), and unary and binary
+// integer operators. It fails on anything else, including division by zero.
+func constInt(expr ast.Expr, iota int64, known map[string]int64) (int64, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.INT {
+			return 0, false
+		}
+		n, err := strconv.ParseInt(e.Value, 0, 64)
+		return n, err == nil
+	case *ast.Ident:
+		if e.Name == "iota" {
+			return iota, true
+		}
+		n, ok := known[e.Name]
+		return n, ok
+	case *ast.ParenExpr:
+		return constInt(e.X, iota, known)
+	case *ast.CallExpr:
+		if len(e.Args) != 1 {
+			return 0, false
+		}
+		if _, ok := e.Fun.(*ast.Ident); !ok {
+			if _, ok := e.Fun.(*ast.SelectorExpr); !ok {
+				return 0, false
+			}
+		}
+		return constInt(e.Args[0], iota, known)
+	case *ast.UnaryExpr:
+		x, ok := constInt(e.X, iota, known)
+		if !ok {
+			return 0, false
+		}
+		switch e.Op {
+		case token.ADD:
+			return x, true
+		case token.SUB:
+			return -x, true
+		case token.XOR:
+			return ^x, true
+		}
+	case *ast.BinaryExpr:
+		x, okX := constInt(e.X, iota, known)
+		y, okY := constInt(e.Y, iota, known)
+		if !okX || !okY {
+			return 0, false
+		}
+		if y == 0 && (e.Op == token.QUO || e.Op == token.REM) {
+			return 0, false
+		}
+		switch e.Op {
+		case token.ADD:
+			return x + y, true
+		case token.SUB:
+			return x - y, true
+		case token.MUL:
+			return x * y, true
+		case token.QUO:
+			return x / y, true
+		case token.REM:
+			return x % y, true
+		case token.SHL:
+			return x << uint64(y), y >= 0 && y < 64
+		case token.SHR:
+			return x >> uint64(y), y >= 0 && y < 64
+		case token.AND:
+			return x & y, true
+		case token.OR:
+			return x | y, true
+		case token.XOR:
+			return x ^ y, true
+		case token.AND_NOT:
+			return x &^ y, true
+		}
+	}
+	return 0, false
+}
+
+// typeParams lists a type parameter list one entry per name, or nil for a
+// non-generic declaration.
+func (v *GoVisitor) typeParams(list *ast.FieldList) []ParsedTypeParam {
+	if list == nil {
+		return nil
+	}
+	var params []ParsedTypeParam
+	for _, field := range list.List {
+		constraint := v.typeToString(field.Type)
+		for _, name := range field.Names {
+			params = append(params, ParsedTypeParam{Name: name.Name, Constraint: constraint})
+		}
+	}
+	return params
+}
+
+// docText returns a doc comment's text with the comment markers removed.
+func docText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.Text())
+}
+
+func (v *GoVisitor) visitTypeSpec(ts *ast.TypeSpec, doc string) {
+	pos := v.fset.Position(ts.Pos())
+
+	switch t := ts.Type.(type) {
+	case *ast.StructType:
+		v.visitStruct(ts.Name.Name, t, v.typeParams(ts.TypeParams), doc, ts)
+	case *ast.InterfaceType:
+		v.visitInterface(ts.Name.Name, t, v.typeParams(ts.TypeParams), doc, ts)
+	default:
+		v.result.TypeDefs = append(v.result.TypeDefs, ParsedTypeDef{
+			Name:       ts.Name.Name,
+			TypeParams: v.typeParams(ts.TypeParams),
+			Underlying: v.typeToString(ts.Type),
+			IsAlias:    ts.Assign != token.NoPos,
+			Methods:    []string{},
+			IsExported: ast.IsExported(ts.Name.Name),
+			LineStart:  pos.Line,
+			Doc:        doc,
+		})
+	}
+}
+
+func (v *GoVisitor) visitStruct(name string, st *ast.StructType, typeParams []ParsedTypeParam, doc string, spec ast.Node) {
+	lineStart, lineEnd := v.line(spec), v.fset.Position(spec.End()).Line
+	parsed := ParsedStruct{
+		Name:       name,
+		TypeParams: typeParams,
+		Fields:     []ParsedField{},
+		Methods:    []string{},
+		IsExported: ast.IsExported(name),
+		LineStart:  lineStart,
+		LineEnd:    lineEnd,
+		ColStart:   v.column(spec.Pos()),
+		ColEnd:     v.column(spec.End()),
+		Doc:        doc,
+		Snippet:    v.snippet(lineStart, lineEnd),
+	}
+
+	if st.Fields != nil {
+		for _, field := range st.Fields.List {
+			fieldType := v.typeToString(field.Type)
+			tag := ""
+			if field.Tag != nil {
+				tag = field.Tag.Value
+			}
+
+			if len(field.Names) > 0 {
+				for _, fieldName := range field.Names {
+					parsed.Fields = append(parsed.Fields, ParsedField{
+						Name:       fieldName.Name,
+						Type:       fieldType,
+						IsExported: ast.IsExported(fieldName.Name),
+						Tag:        tag,
+						ParsedTags: parseTags(tag),
+					})
+				}
+			} else {
+				// Anonymous field
+				parsed.Fields = append(parsed.Fields, ParsedField{
+					Name:         "",
+					Type:         fieldType,
+					IsExported:   false,
+					Tag:          tag,
+					ParsedTags:   parseTags(tag),
+					IsEmbedded:   true,
+					EmbeddedType: embeddedTypeName(field.Type),
+				})
+			}
+		}
+	}
+
+	v.result.Structs = append(v.result.Structs, parsed)
+}
+
+// embeddedTypeName returns the identifier an embedded field is named by,
+// dropping any pointer, package qualifier and type arguments.
+func embeddedTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			return e.Sel.Name
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+func (v *GoVisitor) visitInterface(name string, it *ast.InterfaceType, typeParams []ParsedTypeParam, doc string, spec ast.Node) {
+	lineStart, lineEnd := v.line(spec), v.fset.Position(spec.End()).Line
+	parsed := ParsedInterface{
+		Name:       name,
+		TypeParams: typeParams,
+		Methods:    []string{},
+		IsExported: ast.IsExported(name),
+		LineStart:  lineStart,
+		LineEnd:    lineEnd,
+		ColStart:   v.column(spec.Pos()),
+		ColEnd:     v.column(spec.End()),
+		Doc:        doc,
+		Snippet:    v.snippet(lineStart, lineEnd),
+	}
+
+	if it.Methods != nil {
+		for _, method := range it.Methods.List {
+			if len(method.Names) > 0 {
+				for _, methodName := range method.Names {
+					parsed.Methods = append(parsed.Methods, methodName.Name)
+				}
+			}
+		}
+	}
+
+	v.result.Interfaces = append(v.result.Interfaces, parsed)
+}
+
+// correlateMethods attaches each method to the struct its receiver names,
+// whether by value or pointer. Methods on other types go to OrphanMethods.
+func (v *GoVisitor) correlateMethods() {
+	structs := map[string]*ParsedStruct{}
+	for i := range v.result.Structs {
+		structs[v.result.Structs[i].Name] = &v.result.Structs[i]
+	}
+	typeDefs := map[string]*ParsedTypeDef{}
+	for i := range v.result.TypeDefs {
+		typeDefs[v.result.TypeDefs[i].Name] = &v.result.TypeDefs[i]
+	}
+	for _, fn := range v.result.Functions {
+		if fn.Receiver == nil {
+			continue
+		}
+		typ := fn.receiverType()
+		if st := structs[typ]; st != nil {
+			st.Methods = append(st.Methods, fn.Name)
+			continue
+		}
+		if def := typeDefs[typ]; def != nil {
+			def.Methods = append(def.Methods, fn.Name)
+		}
+		v.result.OrphanMethods = append(v.result.OrphanMethods, typ+"."+fn.Name)
+	}
+}
+
+func (v *GoVisitor) visitGoroutine(gs *ast.GoStmt) {
+	functionCall := ""
+	if call, ok := gs.Call.Fun.(*ast.Ident); ok {
+		functionCall = call.Name
+	} else if sel, ok := gs.Call.Fun.(*ast.SelectorExpr); ok {
+		functionCall = sel.Sel.Name
+	} else if _, ok := gs.Call.Fun.(*ast.FuncLit); ok {
+		functionCall = "<func-literal>"
+	}
+
+	parsed := ParsedGoroutine{
+		FunctionCall: functionCall,
+		Span:         v.span(gs),
+		Context:      "goroutine",
+	}
+	if v.scope != nil {
+		parsed.EnclosingFunction = v.scope.name
+	}
+	v.classifyGoroutine(gs.Call, &parsed)
+	v.checkLoopCapture(gs)
+
+	v.result.Goroutines = append(v.result.Goroutines, parsed)
+}
+
+// visitAssign records results of a call discarded into the blank identifier
+// where they are likely errors: at a position whose declared type is error
+// when the callee is declared in the file, otherwise in the last position.
+func (v *GoVisitor) visitAssign(as *ast.AssignStmt) {
+	if len(as.Rhs) != 1 {
+		return
+	}
+	call, ok := as.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	results := v.localResultTypes(call)
+	for i, lhs := range as.Lhs {
+		if id, ok := lhs.(*ast.Ident); !ok || id.Name != "_" {
+			continue
+		}
+		if results != nil {
+			if i >= len(results) || results[i] != "error" {
+				continue
+			}
+		} else if i != len(as.Lhs)-1 {
+			continue
+		}
+		parsed := IgnoredError{
+			Call:           v.nodeText(call.Fun),
+			DiscardedIndex: i,
+			Span:           v.span(as),
+		}
+		if v.scope != nil {
+			parsed.EnclosingFunction = v.scope.name
+		}
+		v.result.IgnoredErrors = append(v.result.IgnoredErrors, parsed)
+	}
+}
+
+// localResultTypes returns the result types of a call to a function declared
+// in the file, one per result, or nil when the callee cannot be resolved.
+func (v *GoVisitor) localResultTypes(call *ast.CallExpr) []string {
+	id, ok := call.Fun.(*ast.Ident)
+	if !ok || id.Obj == nil {
+		return nil
+	}
+	fn, ok := id.Obj.Decl.(*ast.FuncDecl)
+	if !ok {
+		return nil
+	}
+	return v.fieldTypes(fn.Type.Results)
+}
+
+func (v *GoVisitor) visitDefer(ds *ast.DeferStmt) {
+	parsed := ParsedDefer{LineStart: v.fset.Position(ds.Pos()).Line}
+	if v.scope != nil {
+		parsed.EnclosingFunction = v.scope.name
+	}
+	// recover only stops a panic when called directly by the deferred
+	// function, so a bare "defer recover()" does not count.
+	var body *ast.BlockStmt
+	switch fun := ds.Call.Fun.(type) {
+	case *ast.FuncLit:
+		parsed.Call = v.typeToString(fun.Type) + " {...}()"
+		body = fun.Body
+	case *ast.Ident:
+		parsed.Call = v.nodeText(ds.Call)
+		if fun.Obj != nil {
+			if fn, ok := fun.Obj.Decl.(*ast.FuncDecl); ok {
+				body = fn.Body
+			}
+		}
+	default:
+		parsed.Call = v.nodeText(ds.Call)
+	}
+	inspectBody(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if id, ok := call.Fun.(*ast.Ident); ok && id.Name == "recover" {
+				parsed.CallsRecover = true
+			}
+		}
+		return !parsed.CallsRecover
+	})
+	v.result.Defers = append(v.result.Defers, parsed)
+}
+
+func (v *GoVisitor) visitSelect(ss *ast.SelectStmt) {
+	parsed := ParsedSelect{
+		LineStart: v.fset.Position(ss.Pos()).Line,
+		CaseCount: len(ss.Body.List),
+		Cases:     []ParsedSelectCase{},
+	}
+	if v.scope != nil {
+		parsed.EnclosingFunction = v.scope.name
+	}
+	for _, stmt := range ss.Body.List {
+		clause, ok := stmt.(*ast.CommClause)
+		if !ok {
+			continue
+		}
+		var recv ast.Expr
+		switch comm := clause.Comm.(type) {
+		case nil:
+			parsed.HasDefault = true
+		case *ast.SendStmt:
+			parsed.Cases = append(parsed.Cases, ParsedSelectCase{Direction: "send", Channel: v.nodeText(comm.Chan)})
+		case *ast.ExprStmt:
+			recv = comm.X
+		case *ast.AssignStmt:
+			recv = comm.Rhs[0]
+		}
+		if u, ok := recv.(*ast.UnaryExpr); ok && u.Op == token.ARROW {
+			parsed.Cases = append(parsed.Cases, ParsedSelectCase{Direction: "receive", Channel: v.nodeText(u.X)})
+		}
+	}
+	v.result.Selects = append(v.result.Selects, parsed)
+}
+
+// bindNames remembers the target of each call assigned one to one, so the
+// call's visit can name what it creates.
+func (v *GoVisitor) bindNames(lhs, rhs []ast.Expr) {
+	if len(lhs) != len(rhs) {
+		return
+	}
+	for i, value := range rhs {
+		if call, ok := value.(*ast.CallExpr); ok {
+			if id, ok := lhs[i].(*ast.Ident); !ok || id.Name != "_" {
+				v.bound[call] = v.nodeText(lhs[i])
+			}
+		}
+	}
+}
+
+func (v *GoVisitor) visitCallExpr(ce *ast.CallExpr) {
+	if ident, ok := ce.Fun.(*ast.Ident); ok && ident.Name == "panic" {
+		parsed := ParsedPanic{Span: v.span(ce)}
+		if len(ce.Args) > 0 {
+			parsed.Argument = v.nodeText(ce.Args[0])
+		}
+		if v.scope != nil {
+			parsed.EnclosingFunction = v.scope.name
+		}
+		v.result.Panics = append(v.result.Panics, parsed)
+	}
+
+	// Check for channel operations
+	if ident, ok := ce.Fun.(*ast.Ident); ok {
+		if ident.Name == "make" && len(ce.Args) > 0 {
+			if chanType, ok := ce.Args[0].(*ast.ChanType); ok {
+				pos := v.fset.Position(ce.Pos())
+
+				direction := "bidirectional"
+				if chanType.Dir == ast.SEND {
+					direction = "send"
+				} else if chanType.Dir == ast.RECV {
+					direction = "receive"
+				}
+
+				parsed := ParsedChannel{
+					Name:      "anonymous",
+					Type:      v.typeToString(chanType.Value),
+					Direction: direction,
+					LineStart: pos.Line,
+				}
+				if name, ok := v.bound[ce]; ok {
+					parsed.Name = name
+				}
+				if len(ce.Args) > 1 {
+					parsed.BufferSize = -1
+					if lit, ok := ce.Args[1].(*ast.BasicLit); ok && lit.Kind == token.INT {
+						if size, err := strconv.ParseInt(lit.Value, 0, 0); err == nil {
+							parsed.BufferSize = int(size)
+						}
+					}
+				}
+				if v.scope != nil {
+					parsed.EnclosingFunction = v.scope.name
+				}
+
+				v.result.Channels = append(v.result.Channels, parsed)
+			}
+		}
+	}
+}
+
+func (v *GoVisitor) typeToString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return v.typeToString(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + v.typeToString(t.X)
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + v.typeToString(t.Elt)
+		}
+		return "[...]" + v.typeToString(t.Elt)
+	case *ast.ChanType:
+		dir := ""
+		if t.Dir == ast.SEND {
+			dir = "chan<- "
+		} else if t.Dir == ast.RECV {
+			dir = "<-chan "
+		} else {
+			dir = "chan "
+		}
+		return dir + v.typeToString(t.Value)
+	case *ast.MapType:
+		return "map[" + v.typeToString(t.Key) + "]" + v.typeToString(t.Value)
+	case *ast.IndexExpr:
+		return v.typeToString(t.X) + "[" + v.typeToString(t.Index) + "]"
+	case *ast.IndexListExpr:
+		args := make([]string, len(t.Indices))
+		for i, index := range t.Indices {
+			args[i] = v.typeToString(index)
+		}
+		return v.typeToString(t.X) + "[" + strings.Join(args, ", ") + "]"
+	case *ast.UnaryExpr:
+		// ~T in a constraint
+		return t.Op.String() + v.typeToString(t.X)
+	case *ast.BinaryExpr:
+		// A | B in a constraint
+		return v.typeToString(t.X) + " " + t.Op.String() + " " + v.typeToString(t.Y)
+	case *ast.InterfaceType:
+		return "interface{}"
+	case *ast.StructType:
+		return "struct{}"
+	case *ast.FuncType:
+		sig := "func(" + strings.Join(v.fieldTypes(t.Params), ", ") + ")"
+		results := v.fieldTypes(t.Results)
+		switch len(results) {
+		case 0:
+			return sig
+		case 1:
+			return sig + " " + results[0]
+		default:
+			return sig + " (" + strings.Join(results, ", ") + ")"
+		}
+	case *ast.Ellipsis:
+		return "..." + v.typeToString(t.Elt)
+	default:
+		return "unknown"
+	}
+}
+
+// fieldTypes renders the types of a parameter or result list, once per
+// name, dropping the names.
+func (v *GoVisitor) fieldTypes(list *ast.FieldList) []string {
+	types := []string{}
+	if list == nil {
+		return types
+	}
+	for _, field := range list.List {
+		typ := v.typeToString(field.Type)
+		for n := max(len(field.Names), 1); n > 0; n-- {
+			types = append(types, typ)
+		}
+	}
+	return types
+}
+
+// ParseFile analyzes a file on disk under opts and cfg. Only a file that
+// cannot be read is an error; syntax errors, timeouts and oversized input
+// are reported in the result's Errors.
+func ParseFile(filename string, opts ParseOptions, cfg Config) (*ParseResult, error) {
+	// Check the size before reading so an enormous file is never loaded.
+	if opts.MaxBytes > 0 {
+		if info, err := os.Stat(filename); err == nil && info.Size() > opts.MaxBytes {
+			return failedResult(&sizeLimitError{limit: opts.MaxBytes}), nil
+		}
+	}
+	ctx, cancel := opts.context()
+	defer cancel()
+	session, err := NewAnalysisSession(ctx, filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	return session.Run(ctx, cfg), nil
+}
+
+// ParseSource analyzes source already in memory under opts and cfg, as
+// ParseFile does for a file on disk.
+func ParseSource(filename string, source []byte, opts ParseOptions, cfg Config) *ParseResult {
+	if source == nil {
+		// ParseContext would read the file for nil.
+		source = []byte{}
+	}
+	result, _ := ParseContext(context.Background(), filename, source, opts, cfg)
+	return result
+}
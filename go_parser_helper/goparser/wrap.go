@@ -0,0 +1,86 @@
+package goparser
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"time"
+)
+
+// ParseOptions controls how source is turned into an AST before analysis.
+type ParseOptions struct {
+	// Wrap retries input that does not parse as a file as a code snippet.
+	Wrap bool
+	// DeclsOnly keeps only top-level declarations: function bodies are
+	// emptied after parsing and no detectors run.
+	DeclsOnly bool
+	// MaxBytes rejects larger input before it is parsed; 0 means no limit.
+	MaxBytes int64
+	// Timeout bounds the parse and walk of each file; 0 means no limit.
+	Timeout time.Duration
+	// Concurrency is the number of files ParseDir parses at once; 0 means
+	// one per CPU.
+	Concurrency int
+	// Cache, when set, serves ParseContext and ParseSource from earlier
+	// results for the same file name, source, options and config. See
+	// ResultCache.
+	Cache *ResultCache
+}
+
+// context returns the context a file is parsed and walked under.
+func (opts ParseOptions) context() (context.Context, context.CancelFunc) {
+	if opts.Timeout > 0 {
+		return context.WithTimeout(context.Background(), opts.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// snippetWrappers are tried in order when a fragment fails to parse as a
+// complete file: first as top-level declarations, then as statements.
+var snippetWrappers = []struct {
+	kind   string
+	prefix string
+	suffix string
+}{
+	{kind: "package", prefix: "package main\n"},
+	{kind: "function", prefix: "package main\nfunc _() {\n", suffix: "\n}\n"},
+}
+
+type wrappedSnippet struct {
+	fset   *token.FileSet
+	file   *ast.File
+	source []byte
+	kind   string
+}
+
+// parseSnippet wraps a fragment in a synthetic package (and, failing that, a
+// function scaffold). A //line directive after the scaffold makes every
+// reported position refer to the snippet's own lines.
+func parseSnippet(filename string, source []byte) (*wrappedSnippet, bool) {
+	for _, wrapper := range snippetWrappers {
+		wrapped := []byte(fmt.Sprintf("%s//line %s:1\n%s%s", wrapper.prefix, filename, source, wrapper.suffix))
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, filename, wrapped, parser.ParseComments)
+		if err == nil {
+			return &wrappedSnippet{fset: fset, file: file, source: wrapped, kind: wrapper.kind}, true
+		}
+	}
+	return nil, false
+}
+
+// stripScaffold drops the synthetic function added by the "function" wrapper
+// so it is not reported as a declaration of the snippet.
+func stripScaffold(result *ParseResult) {
+	if result.Wrapped != "function" {
+		return
+	}
+	functions := result.Functions[:0]
+	for _, fn := range result.Functions {
+		if fn.Name != "_" {
+			functions = append(functions, fn)
+		}
+	}
+	result.Functions = functions
+}
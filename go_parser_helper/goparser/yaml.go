@@ -0,0 +1,362 @@
+package goparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is one non-blank, comment-stripped line of a YAML document.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// parseYAML decodes the subset of YAML a config file needs into the values
+// encoding/json would produce: block mappings and sequences, flow [a, b]
+// and {k: v} collections, quoted and plain scalars, booleans, numbers and
+// null. Anchors, tags, block scalars and multiple documents are rejected
+// rather than misread.
+func parseYAML(data []byte) (interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(stripYAMLComment(strings.TrimSuffix(raw, "\r")), " \t")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || len(lines) == 0 && trimmed == "---" {
+			continue
+		}
+		if trimmed == "---" || trimmed == "..." {
+			return nil, fmt.Errorf("line %d: multiple documents are not supported", i+1)
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", i+1)
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	p := &yamlParser{lines: lines}
+	value, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return value, nil
+}
+
+// stripYAMLComment drops a # comment that starts the line or follows
+// whitespace outside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// block parses the mapping or sequence whose entries start at indent.
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if line := p.lines[p.pos]; line.text == "-" || strings.HasPrefix(line.text, "- ") {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		key, rest, err := splitYAMLKey(line)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+		if m[key], err = p.value(line, rest, indent); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	list := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if line.text != "-" && !strings.HasPrefix(line.text, "- ") {
+			return nil, fmt.Errorf("line %d: expected a list item", line.num)
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if opensYAMLMapping(rest) {
+			// "- key: value" opens a mapping whose keys line up with key.
+			p.lines[p.pos] = yamlLine{num: line.num, indent: line.indent + len(line.text) - len(rest), text: rest}
+			item, err := p.mapping(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+			continue
+		}
+		p.pos++
+		item, err := p.value(line, rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+// value parses what follows a key or list marker on line: an inline scalar
+// or flow collection, or else the nested block on the following lines.
+func (p *yamlParser) value(line yamlLine, rest string, indent int) (interface{}, error) {
+	if rest != "" {
+		return parseYAMLInline(line.num, rest)
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return p.block(p.lines[p.pos].indent)
+	}
+	// A sequence may sit at its parent key's indentation.
+	if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && strings.HasPrefix(p.lines[p.pos].text, "- ") && !strings.HasPrefix(line.text, "-") {
+		return p.sequence(indent)
+	}
+	return nil, nil
+}
+
+// opensYAMLMapping reports whether the text after a list marker is the
+// first entry of a block mapping rather than a scalar or flow collection.
+func opensYAMLMapping(text string) bool {
+	if text == "" || strings.ContainsRune("[{\"'", rune(text[0])) {
+		return false
+	}
+	return strings.Contains(text, ": ") || strings.HasSuffix(text, ":")
+}
+
+// splitYAMLKey splits "key: rest" into the decoded key and the trimmed rest.
+func splitYAMLKey(line yamlLine) (string, string, error) {
+	text := line.text
+	if text != "" && (text[0] == '"' || text[0] == '\'') {
+		end := closingQuote(text)
+		if end < 0 {
+			return "", "", fmt.Errorf("line %d: unterminated quoted key", line.num)
+		}
+		key, err := yamlQuoted(line.num, text[:end+1])
+		if err != nil {
+			return "", "", err
+		}
+		after := text[end+1:]
+		if after != ":" && !strings.HasPrefix(after, ": ") {
+			return "", "", fmt.Errorf("line %d: expected ':' after key", line.num)
+		}
+		return key, strings.TrimSpace(after[1:]), nil
+	}
+	i := strings.Index(text, ": ")
+	if i < 0 && strings.HasSuffix(text, ":") {
+		i = len(text) - 1
+	}
+	if i <= 0 {
+		return "", "", fmt.Errorf("line %d: expected 'key: value', got %q", line.num, text)
+	}
+	return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), nil
+}
+
+// closingQuote returns the index of the quote closing the string text
+// opens, or -1.
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quote == '"':
+			i++
+		case text[i] == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func yamlQuoted(num int, text string) (string, error) {
+	if text[0] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	s, err := strconv.Unquote(text)
+	if err != nil {
+		return "", fmt.Errorf("line %d: invalid quoted string %s", num, text)
+	}
+	return s, nil
+}
+
+// parseYAMLInline parses a scalar or flow collection that fills the rest
+// of a line.
+func parseYAMLInline(num int, text string) (interface{}, error) {
+	switch text[0] {
+	case '&', '*', '!', '|', '>':
+		return nil, fmt.Errorf("line %d: anchors, tags and block scalars are not supported", num)
+	}
+	f := &yamlFlow{num: num, text: text}
+	value, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	if f.skipSpace(); f.pos < len(f.text) {
+		return nil, fmt.Errorf("line %d: unexpected %q after value", num, f.text[f.pos:])
+	}
+	return value, nil
+}
+
+// yamlFlow scans the flow collections and scalars of a single line.
+type yamlFlow struct {
+	num  int
+	text string
+	pos  int
+	// depth counts the flow collections open at pos.
+	depth int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.pos < len(f.text) && f.text[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) value() (interface{}, error) {
+	f.skipSpace()
+	if f.pos >= len(f.text) {
+		return nil, fmt.Errorf("line %d: missing value", f.num)
+	}
+	switch f.text[f.pos] {
+	case '[':
+		list := []interface{}{}
+		err := f.items(']', func() error {
+			item, err := f.value()
+			list = append(list, item)
+			return err
+		})
+		return list, err
+	case '{':
+		m := map[string]interface{}{}
+		err := f.items('}', func() error {
+			key, err := f.scalar(true)
+			if err != nil {
+				return err
+			}
+			if f.skipSpace(); f.pos >= len(f.text) || f.text[f.pos] != ':' {
+				return fmt.Errorf("line %d: expected ':' after key %v", f.num, key)
+			}
+			f.pos++
+			m[fmt.Sprint(key)], err = f.value()
+			return err
+		})
+		return m, err
+	}
+	return f.scalar(false)
+}
+
+// items parses the comma-separated entries of the collection at f.pos up
+// to its closing bracket.
+func (f *yamlFlow) items(closing byte, item func() error) error {
+	f.pos++
+	f.depth++
+	for {
+		if f.skipSpace(); f.pos < len(f.text) && f.text[f.pos] == closing {
+			f.pos++
+			f.depth--
+			return nil
+		}
+		if f.pos >= len(f.text) {
+			return fmt.Errorf("line %d: missing %q", f.num, closing)
+		}
+		if err := item(); err != nil {
+			return err
+		}
+		f.skipSpace()
+		if f.pos >= len(f.text) {
+			return fmt.Errorf("line %d: missing %q", f.num, closing)
+		}
+		switch f.text[f.pos] {
+		case ',':
+			f.pos++
+		case closing:
+		default:
+			return fmt.Errorf("line %d: expected ',' or %q", f.num, closing)
+		}
+	}
+}
+
+// scalar parses a quoted or plain scalar. Inside a flow collection a plain
+// scalar ends at a comma, bracket or brace; a key also ends at a colon.
+func (f *yamlFlow) scalar(key bool) (interface{}, error) {
+	f.skipSpace()
+	rest := f.text[f.pos:]
+	if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+		end := closingQuote(rest)
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unterminated string", f.num)
+		}
+		f.pos += end + 1
+		return yamlQuoted(f.num, rest[:end+1])
+	}
+	end := len(rest)
+	if f.depth > 0 {
+		end = strings.IndexAny(rest, ",]}")
+		if end < 0 {
+			end = len(rest)
+		}
+		if key {
+			if i := strings.IndexByte(rest[:end], ':'); i >= 0 {
+				end = i
+			}
+		}
+	}
+	f.pos += end
+	plain := strings.TrimSpace(rest[:end])
+	if key {
+		return plain, nil
+	}
+	return yamlPlain(plain), nil
+}
+
+// yamlPlain resolves an unquoted scalar to a boolean, null, number or
+// string.
+func yamlPlain(s string) interface{} {
+	switch s {
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return n
+	}
+	return s
+}
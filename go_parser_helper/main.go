@@ -1,423 +1,268 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"go/ast"
-	"go/parser"
-	"go/token"
+	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
-)
-
-type ParsedFunction struct {
-	Name        string            `json:"name"`
-	Parameters  []ParsedParameter `json:"parameters"`
-	ReturnTypes []string          `json:"return_types"`
-	IsExported  bool              `json:"is_exported"`
-	Receiver    *ParsedReceiver   `json:"receiver,omitempty"`
-	LineStart   int               `json:"line_start"`
-	LineEnd     int               `json:"line_end"`
-}
-
-type ParsedParameter struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
-}
-
-type ParsedReceiver struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
-}
-
-type ParsedStruct struct {
-	Name       string        `json:"name"`
-	Fields     []ParsedField `json:"fields"`
-	IsExported bool          `json:"is_exported"`
-	LineStart  int           `json:"line_start"`
-	LineEnd    int           `json:"line_end"`
-}
-
-type ParsedField struct {
-	Name       string `json:"name"`
-	Type       string `json:"type"`
-	IsExported bool   `json:"is_exported"`
-	Tag        string `json:"tag,omitempty"`
-}
-
-type ParsedInterface struct {
-	Name       string   `json:"name"`
-	Methods    []string `json:"methods"`
-	IsExported bool     `json:"is_exported"`
-	LineStart  int      `json:"line_start"`
-	LineEnd    int      `json:"line_end"`
-}
-
-type ParsedImport struct {
-	Path  string `json:"path"`
-	Name  string `json:"name,omitempty"`
-	Alias string `json:"alias,omitempty"`
-}
-
-type ParsedGoroutine struct {
-	FunctionCall string `json:"function_call"`
-	LineStart    int    `json:"line_start"`
-	Context      string `json:"context"`
-}
+	"syscall"
+	"time"
 
-type ParsedChannel struct {
-	Name      string `json:"name"`
-	Type      string `json:"type"`
-	Direction string `json:"direction"` // "send", "receive", "bidirectional"
-	LineStart int    `json:"line_start"`
-}
-
-type ParseResult struct {
-	PackageName  string            `json:"package_name"`
-	Functions    []ParsedFunction  `json:"functions"`
-	Structs      []ParsedStruct    `json:"structs"`
-	Interfaces   []ParsedInterface `json:"interfaces"`
-	Imports      []ParsedImport    `json:"imports"`
-	Goroutines   []ParsedGoroutine `json:"goroutines"`
-	Channels     []ParsedChannel   `json:"channels"`
-	ContractType string            `json:"contract_type"`
-	Errors       []string          `json:"errors"`
-}
+	"go_parser_helper/goparser"
+)
 
-type GoVisitor struct {
-	fset   *token.FileSet
-	result *ParseResult
-	source string
-}
+// version and commit identify the build and are set with -ldflags, as in
+//
+//	go build -ldflags "-X main.version=0.2.0 -X main.commit=$(git rev-parse --short HEAD)"
+var (
+	version = "0.1.0-dev"
+	commit  = "unknown"
+)
 
-func NewGoVisitor(fset *token.FileSet, source string) *GoVisitor {
-	return &GoVisitor{
-		fset:   fset,
-		source: source,
-		result: &ParseResult{
-			Functions:  []ParsedFunction{},
-			Structs:    []ParsedStruct{},
-			Interfaces: []ParsedInterface{},
-			Imports:    []ParsedImport{},
-			Goroutines: []ParsedGoroutine{},
-			Channels:   []ParsedChannel{},
-			Errors:     []string{},
-		},
+// readStdin reads standard input, stopping one byte past maxBytes so that
+// goparser.ParseSource can reject it without buffering the rest.
+func readStdin(maxBytes int64) ([]byte, error) {
+	if maxBytes <= 0 {
+		return io.ReadAll(os.Stdin)
 	}
+	return io.ReadAll(io.LimitReader(os.Stdin, maxBytes+1))
 }
 
-func (v *GoVisitor) Visit(node ast.Node) ast.Visitor {
-	if node == nil {
-		return nil
-	}
-
-	switch n := node.(type) {
-	case *ast.File:
-		v.result.PackageName = n.Name.Name
-		v.detectContractType()
-
-	case *ast.ImportSpec:
-		v.visitImport(n)
+// exitTimeout is the exit status when -timeout stopped any file, after the
+// partial results have been written. Syntax errors and oversized input are
+// reported in Errors with a zero status.
+const exitTimeout = 3
 
-	case *ast.FuncDecl:
-		v.visitFunction(n)
-
-	case *ast.GenDecl:
-		v.visitGenDecl(n)
-
-	case *ast.GoStmt:
-		v.visitGoroutine(n)
-
-	case *ast.CallExpr:
-		v.visitCallExpr(n)
-	}
-
-	return v
-}
+func main() {
+	var filenames fileList
+	flag.Var(&filenames, "file", "Go file to parse, or - to read from stdin; repeat the flag or separate names with commas to parse several")
+	var stdinName = flag.String("filename", "<stdin>", "Name reported in positions when reading from stdin")
+	var dir = flag.String("dir", "", "Directory of Go files to parse recursively (instead of -file)")
+	var manifest = flag.String("manifest", "", "JSON array of files to parse, as paths or {file, tags} objects (instead of -file)")
+	var output = flag.String("output", "", "Output file for JSON result")
+	var wrap = flag.Bool("wrap", false, "Retry input that is not a complete file as a snippet wrapped in a synthetic package")
+	var merge = flag.Bool("merge", false, "Add the union of all files' imports and functions to a multi-file result")
+	var concurrency = flag.Int("concurrency", runtime.NumCPU(), "Number of files to parse at once in -dir mode")
+	var includeTests = flag.Bool("include-tests", false, "Also parse _test.go files in -dir mode")
+	var declsOnly = flag.Bool("decls-only", false, "Report only the package, imports and top-level declarations, without running any detectors")
+	var configPath = flag.String("config", "", "JSON or YAML (.yaml, .yml) file overriding the default analysis config, including per-rule enabled and severity settings")
+	var secretPatterns = flag.String("secret-patterns", "", "JSON array of {category, pattern, severity} regexps added to the built-in secret patterns")
+	var format = flag.String("format", "json", "Output format: json, sarif, protobuf, dot (call graph), or import-graph (with -dir)")
+	var schemaOnly = flag.Bool("schema", false, "Print the JSON Schema of the JSON output and exit")
+	var protoSchemaOnly = flag.Bool("proto-schema", false, "Print the .proto schema of the protobuf output and exit")
+	var showVersion = flag.Bool("version", false, "Print the version, commit and Go version and exit")
+	var maxBytes = flag.Int64("max-bytes", 4<<20, "Reject input files larger than this many bytes; 0 disables the limit")
+	var timeout = flag.Duration("timeout", 0, "Stop parsing and analyzing a file after this long and report partial results, exiting with status 3; 0 disables the limit")
+	var quiet = flag.Bool("quiet", false, "Emit only each file's package name, findings and errors instead of the full result")
+	var failOn = flag.String("fail-on", "", "Exit with -fail-exit-code after writing the output if any finding is at least this severe: info, low, medium, high or critical")
+	var failExitCode = flag.Int("fail-exit-code", 1, "Exit status used by -fail-on")
+	var baselinePath = flag.String("baseline", "", "JSON file of accepted findings to leave out of the output, counted in suppressed")
+	var writeBaseline = flag.String("write-baseline", "", "Write a baseline accepting every finding of this run to the given file")
+	var sortOutput = flag.Bool("sort", true, "Sort declarations, imports and findings by line, then name, so equivalent runs diff cleanly; -sort=false keeps each list in source order")
+	var nondeterministic = flag.String("nondeterministic", "", "Comma-separated calls (time.Now) and import paths (math/rand) to report in contract logic, replacing the built-in set; include map-iteration to also report output built in map order")
+	var cacheSize = flag.Int("cache-size", 256, "Number of results -serve keeps for re-parsing identical source; 0 disables the cache")
+	var serveAddr = flag.String("serve", "", "Serve POST /parse and GET /healthz over HTTP on this address, such as :8080, instead of parsing files")
+	flag.Parse()
+	goparser.Version = version
 
-func (v *GoVisitor) visitImport(imp *ast.ImportSpec) {
-	parsed := ParsedImport{
-		Path: strings.Trim(imp.Path.Value, `"`),
+	if *showVersion {
+		fmt.Printf("go_parser_helper %s (commit %s, %s)\n", version, commit, runtime.Version())
+		return
 	}
 
-	if imp.Name != nil {
-		parsed.Name = imp.Name.Name
-		if imp.Name.Name != "." && imp.Name.Name != "_" {
-			parsed.Alias = imp.Name.Name
+	if *schemaOnly {
+		schema, err := json.MarshalIndent(goparser.JSONSchema(reflect.TypeOf(goparser.ParseResult{}), reflect.TypeOf(goparser.DirResult{}), reflect.TypeOf(goparser.QuietResult{}), reflect.TypeOf(goparser.QuietDirResult{})), "", "  ")
+		if err != nil {
+			log.Fatalf("Error marshaling JSON schema: %v", err)
 		}
+		fmt.Println(string(schema))
+		return
 	}
 
-	v.result.Imports = append(v.result.Imports, parsed)
-}
-
-func (v *GoVisitor) visitFunction(fn *ast.FuncDecl) {
-	pos := v.fset.Position(fn.Pos())
-	end := v.fset.Position(fn.End())
-
-	parsed := ParsedFunction{
-		Name:        fn.Name.Name,
-		Parameters:  []ParsedParameter{},
-		ReturnTypes: []string{},
-		IsExported:  ast.IsExported(fn.Name.Name),
-		LineStart:   pos.Line,
-		LineEnd:     end.Line,
-	}
-
-	// Parse receiver (for methods)
-	if fn.Recv != nil && len(fn.Recv.List) > 0 {
-		recv := fn.Recv.List[0]
-		parsed.Receiver = &ParsedReceiver{
-			Type: v.typeToString(recv.Type),
-		}
-		if len(recv.Names) > 0 {
-			parsed.Receiver.Name = recv.Names[0].Name
+	if *protoSchemaOnly {
+		schema, err := goparser.ProtoSchema(reflect.TypeOf(goparser.ParseResult{}), reflect.TypeOf(goparser.DirResult{}), reflect.TypeOf(goparser.QuietResult{}), reflect.TypeOf(goparser.QuietDirResult{}))
+		if err != nil {
+			log.Fatalf("Error generating .proto schema: %v", err)
 		}
+		fmt.Print(schema)
+		return
 	}
 
-	// Parse parameters
-	if fn.Type.Params != nil {
-		for _, param := range fn.Type.Params.List {
-			paramType := v.typeToString(param.Type)
-			if len(param.Names) > 0 {
-				for _, name := range param.Names {
-					parsed.Parameters = append(parsed.Parameters, ParsedParameter{
-						Name: name.Name,
-						Type: paramType,
-					})
-				}
-			} else {
-				parsed.Parameters = append(parsed.Parameters, ParsedParameter{
-					Name: "",
-					Type: paramType,
-				})
-			}
+	inputs := 0
+	for _, input := range []string{filenames.String(), *dir, *manifest} {
+		if input != "" {
+			inputs++
 		}
 	}
-
-	// Parse return types
-	if fn.Type.Results != nil {
-		for _, result := range fn.Type.Results.List {
-			parsed.ReturnTypes = append(parsed.ReturnTypes, v.typeToString(result.Type))
-		}
+	if inputs > 1 {
+		log.Fatal("The -file, -dir and -manifest flags are mutually exclusive")
 	}
-
-	v.result.Functions = append(v.result.Functions, parsed)
-}
-
-func (v *GoVisitor) visitGenDecl(gen *ast.GenDecl) {
-	for _, spec := range gen.Specs {
-		switch s := spec.(type) {
-		case *ast.TypeSpec:
-			v.visitTypeSpec(s)
+	if inputs > 0 && *serveAddr != "" {
+		log.Fatal("The -serve flag takes no -file, -dir or -manifest")
+	}
+	if inputs == 0 && *serveAddr == "" {
+		log.Fatal("Please provide a Go file to parse using -file flag, a directory using -dir flag or a file list using -manifest flag")
+	}
+	switch *format {
+	case "json", "sarif", "protobuf", "dot":
+	case "import-graph":
+		if *dir == "" {
+			log.Fatal("The import-graph format requires the -dir flag")
 		}
+	default:
+		log.Fatalf("Unknown output format %q", *format)
 	}
-}
-
-func (v *GoVisitor) visitTypeSpec(ts *ast.TypeSpec) {
-	pos := v.fset.Position(ts.Pos())
-	end := v.fset.Position(ts.End())
-
-	switch t := ts.Type.(type) {
-	case *ast.StructType:
-		v.visitStruct(ts.Name.Name, t, pos.Line, end.Line)
-	case *ast.InterfaceType:
-		v.visitInterface(ts.Name.Name, t, pos.Line, end.Line)
+	if *failOn != "" && goparser.SeverityRank(*failOn) < 0 {
+		log.Fatalf("Unknown -fail-on severity %q; want one of %s", *failOn, strings.Join(goparser.SeverityLevels, ", "))
 	}
-}
 
-func (v *GoVisitor) visitStruct(name string, st *ast.StructType, lineStart, lineEnd int) {
-	parsed := ParsedStruct{
-		Name:       name,
-		Fields:     []ParsedField{},
-		IsExported: ast.IsExported(name),
-		LineStart:  lineStart,
-		LineEnd:    lineEnd,
+	cfg, err := goparser.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
 	}
-
-	if st.Fields != nil {
-		for _, field := range st.Fields.List {
-			fieldType := v.typeToString(field.Type)
-			tag := ""
-			if field.Tag != nil {
-				tag = field.Tag.Value
-			}
-
-			if len(field.Names) > 0 {
-				for _, fieldName := range field.Names {
-					parsed.Fields = append(parsed.Fields, ParsedField{
-						Name:       fieldName.Name,
-						Type:       fieldType,
-						IsExported: ast.IsExported(fieldName.Name),
-						Tag:        tag,
-					})
-				}
-			} else {
-				// Anonymous field
-				parsed.Fields = append(parsed.Fields, ParsedField{
-					Name:       "",
-					Type:       fieldType,
-					IsExported: false,
-					Tag:        tag,
-				})
-			}
+	if *secretPatterns != "" {
+		cfg.SecretPatterns, err = goparser.LoadSecretPatterns(*secretPatterns)
+		if err != nil {
+			log.Fatalf("Error loading secret patterns: %v", err)
 		}
 	}
-
-	v.result.Structs = append(v.result.Structs, parsed)
-}
-
-func (v *GoVisitor) visitInterface(name string, it *ast.InterfaceType, lineStart, lineEnd int) {
-	parsed := ParsedInterface{
-		Name:       name,
-		Methods:    []string{},
-		IsExported: ast.IsExported(name),
-		LineStart:  lineStart,
-		LineEnd:    lineEnd,
+	if *nondeterministic != "" {
+		var entries fileList
+		entries.Set(*nondeterministic)
+		cfg.Nondeterministic = entries
 	}
-
-	if it.Methods != nil {
-		for _, method := range it.Methods.List {
-			if len(method.Names) > 0 {
-				for _, methodName := range method.Names {
-					parsed.Methods = append(parsed.Methods, methodName.Name)
-				}
+	opts := goparser.ParseOptions{Wrap: *wrap, DeclsOnly: *declsOnly, MaxBytes: *maxBytes, Timeout: *timeout, Concurrency: *concurrency}
+	if *serveAddr != "" {
+		if *cacheSize > 0 {
+			opts.Cache = goparser.NewResultCache(*cacheSize)
+		}
+		if err := serve(*serveAddr, goparser.NewServer(opts, cfg)); err != nil {
+			log.Fatalf("Error serving: %v", err)
+		}
+		return
+	}
+	// Multi-file runs fill multi; a single -file fills single. files holds
+	// the per-file results of either, keyed as they are reported.
+	var multi *goparser.DirResult
+	var single *goparser.ParseResult
+	var files map[string]*goparser.ParseResult
+	if *dir != "" {
+		multi, err = goparser.ParseDir(*dir, opts, cfg, *includeTests)
+	} else if *manifest != "" || len(filenames) > 1 {
+		if *manifest != "" {
+			var entries []goparser.ManifestEntry
+			entries, err = goparser.LoadManifest(*manifest)
+			if err == nil {
+				multi, err = goparser.ParseManifest(entries, opts, cfg)
 			}
+		} else {
+			multi, err = goparser.ParseFiles(filenames, opts, cfg)
 		}
+	} else {
+		name := filenames[0]
+		if name == "-" {
+			name = *stdinName
+			var source []byte
+			source, err = readStdin(opts.MaxBytes)
+			if err == nil {
+				single = goparser.ParseSource(name, source, opts, cfg)
+			}
+		} else {
+			single, err = goparser.ParseFile(name, opts, cfg)
+		}
+		files = map[string]*goparser.ParseResult{filepath.ToSlash(name): single}
 	}
-
-	v.result.Interfaces = append(v.result.Interfaces, parsed)
-}
-
-func (v *GoVisitor) visitGoroutine(gs *ast.GoStmt) {
-	pos := v.fset.Position(gs.Pos())
-
-	functionCall := ""
-	if call, ok := gs.Call.Fun.(*ast.Ident); ok {
-		functionCall = call.Name
-	} else if sel, ok := gs.Call.Fun.(*ast.SelectorExpr); ok {
-		functionCall = sel.Sel.Name
+	if err != nil {
+		log.Fatalf("Error parsing file: %v", err)
 	}
-
-	parsed := ParsedGoroutine{
-		FunctionCall: functionCall,
-		LineStart:    pos.Line,
-		Context:      "goroutine",
+	if multi != nil {
+		files = multi.Files
 	}
-
-	v.result.Goroutines = append(v.result.Goroutines, parsed)
-}
-
-func (v *GoVisitor) visitCallExpr(ce *ast.CallExpr) {
-	// Check for channel operations
-	if ident, ok := ce.Fun.(*ast.Ident); ok {
-		if ident.Name == "make" && len(ce.Args) > 0 {
-			if chanType, ok := ce.Args[0].(*ast.ChanType); ok {
-				pos := v.fset.Position(ce.Pos())
-
-				direction := "bidirectional"
-				if chanType.Dir == ast.SEND {
-					direction = "send"
-				} else if chanType.Dir == ast.RECV {
-					direction = "receive"
-				}
-
-				parsed := ParsedChannel{
-					Name:      "anonymous",
-					Type:      v.typeToString(chanType.Value),
-					Direction: direction,
-					LineStart: pos.Line,
-				}
-
-				v.result.Channels = append(v.result.Channels, parsed)
-			}
+	if *sortOutput {
+		for _, res := range files {
+			res.SortByPosition()
 		}
 	}
-}
 
-func (v *GoVisitor) typeToString(expr ast.Expr) string {
-	switch t := expr.(type) {
-	case *ast.Ident:
-		return t.Name
-	case *ast.SelectorExpr:
-		return v.typeToString(t.X) + "." + t.Sel.Name
-	case *ast.StarExpr:
-		return "*" + v.typeToString(t.X)
-	case *ast.ArrayType:
-		if t.Len == nil {
-			return "[]" + v.typeToString(t.Elt)
+	if *writeBaseline != "" {
+		if err := goparser.NewBaseline(files).Write(*writeBaseline); err != nil {
+			log.Fatalf("Error writing baseline: %v", err)
 		}
-		return "[...]" + v.typeToString(t.Elt)
-	case *ast.ChanType:
-		dir := ""
-		if t.Dir == ast.SEND {
-			dir = "chan<- "
-		} else if t.Dir == ast.RECV {
-			dir = "<-chan "
-		} else {
-			dir = "chan "
+	}
+	if *baselinePath != "" {
+		baseline, err := goparser.LoadBaseline(*baselinePath)
+		if err != nil {
+			log.Fatalf("Error loading baseline: %v", err)
 		}
-		return dir + v.typeToString(t.Value)
-	case *ast.MapType:
-		return "map[" + v.typeToString(t.Key) + "]" + v.typeToString(t.Value)
-	case *ast.InterfaceType:
-		return "interface{}"
-	case *ast.FuncType:
-		return "func"
-	default:
-		return "unknown"
+		baseline.Apply(files)
 	}
-}
 
-func (v *GoVisitor) detectContractType() {
-	source := strings.ToLower(v.source)
-
-	if strings.Contains(source, "cosmos-sdk") || strings.Contains(source, "sdk.msg") {
-		v.result.ContractType = "cosmos_sdk"
-	} else if strings.Contains(source, "ethereum") || strings.Contains(source, "ethclient") {
-		v.result.ContractType = "ethereum"
-	} else if strings.Contains(source, "blockchain") || strings.Contains(source, "smart contract") {
-		v.result.ContractType = "blockchain"
-	} else {
-		v.result.ContractType = "generic"
+	timedOut := single != nil && single.TimedOut
+	if multi != nil {
+		timedOut = multi.TimedOut()
 	}
-}
-
-func parseGoFile(filename string) (*ParseResult, error) {
-	source, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %v", err)
+	// failing counts the findings at or above -fail-on.
+	failing := 0
+	if *failOn != "" && multi != nil {
+		failing = multi.FindingsAtOrAbove(*failOn)
+	} else if *failOn != "" {
+		failing = single.FindingsAtOrAbove(*failOn)
 	}
-
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, filename, source, parser.ParseComments)
-	if err != nil {
-		result := &ParseResult{
-			PackageName: "unknown",
-			Errors:      []string{fmt.Sprintf("Parse error: %v", err)},
-		}
-		return result, nil
+	if multi != nil && *merge {
+		multi.Merge()
 	}
 
-	visitor := NewGoVisitor(fset, string(source))
-	ast.Walk(visitor, file)
-
-	return visitor.result, nil
-}
-
-func main() {
-	var filename = flag.String("file", "", "Go file to parse")
-	var output = flag.String("output", "", "Output file for JSON result")
-	flag.Parse()
-
-	if *filename == "" {
-		log.Fatal("Please provide a Go file to parse using -file flag")
+	var result interface{} = single
+	if multi != nil {
+		result = multi
+	}
+	if *quiet && multi != nil {
+		result = multi.Quiet()
+	} else if *quiet {
+		result = single.Quiet()
+	}
+	switch {
+	case *format == "import-graph":
+		result = goparser.BuildImportGraph(*dir, multi)
+	case *format == "sarif" && *dir != "":
+		result = goparser.BuildSARIF(goparser.SARIFDirFiles(*dir, multi))
+	case *format == "sarif":
+		result = goparser.BuildSARIF(files)
 	}
 
-	result, err := parseGoFile(*filename)
-	if err != nil {
-		log.Fatalf("Error parsing file: %v", err)
+	if *format == "protobuf" || *format == "dot" {
+		var rawOutput []byte
+		if *format == "dot" {
+			var buf bytes.Buffer
+			err = goparser.WriteDOT(&buf, files)
+			rawOutput = buf.Bytes()
+		} else {
+			rawOutput, err = goparser.MarshalProto(result)
+		}
+		if err != nil {
+			log.Fatalf("Error marshaling %s: %v", *format, err)
+		}
+		if *output != "" {
+			err = os.WriteFile(*output, rawOutput, 0644)
+		} else {
+			_, err = os.Stdout.Write(rawOutput)
+		}
+		if err != nil {
+			log.Fatalf("Error writing output: %v", err)
+		}
+		exit(timedOut, failing, *failExitCode)
+		return
 	}
 
 	jsonOutput, err := json.MarshalIndent(result, "", "  ")
@@ -433,4 +278,65 @@ func main() {
 	} else {
 		fmt.Println(string(jsonOutput))
 	}
+	exit(timedOut, failing, *failExitCode)
+}
+
+// exit ends a run whose output has been written: with failExitCode when
+// -fail-on matched findings, otherwise with exitTimeout when -timeout
+// stopped any file.
+func exit(timedOut bool, failing, failExitCode int) {
+	if failing > 0 {
+		os.Exit(failExitCode)
+	}
+	if timedOut {
+		os.Exit(exitTimeout)
+	}
+}
+
+// shutdownGrace is how long in-flight requests may run after SIGINT or
+// SIGTERM before the server closes them.
+const shutdownGrace = 10 * time.Second
+
+// serve runs handler on addr until SIGINT or SIGTERM, then shuts down
+// gracefully.
+func serve(addr string, handler http.Handler) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() { errc <- server.ListenAndServe() }()
+	log.Printf("Serving on %s", addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+	log.Print("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// fileList collects -file values given as a comma-separated list, repeated
+// flags, or both.
+type fileList []string
+
+func (f *fileList) String() string { return strings.Join(*f, ",") }
+
+func (f *fileList) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			*f = append(*f, name)
+		}
+	}
+	return nil
 }